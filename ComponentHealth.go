@@ -0,0 +1,225 @@
+package main
+
+import (
+	"github.com/segmentio/kafka-go"
+	"sync"
+	"time"
+)
+
+// componentHealth
+/*
+ * Tracks whether component is failing: failingSince is set by the first error after success
+ * and reset by the next success. Methods are safe to call on nil receiver, so tracking is optional.
+ */
+type componentHealth struct {
+	mutex        sync.Mutex
+	lastError    error
+	lastErrorAt  time.Time
+	failingSince time.Time
+}
+
+type componentHealthSnapshot struct {
+	LastError    string     `json:"lastError,omitempty"`
+	LastErrorAt  *time.Time `json:"lastErrorAt,omitempty"`
+	FailingSince *time.Time `json:"failingSince,omitempty"`
+}
+
+func (health *componentHealth) failed(err error) {
+	if health == nil || err == nil {
+		return
+	}
+
+	now := time.Now()
+	health.mutex.Lock()
+	health.lastError = err
+	health.lastErrorAt = now
+	if health.failingSince.IsZero() {
+		health.failingSince = now
+	}
+	health.mutex.Unlock()
+}
+
+func (health *componentHealth) succeeded() {
+	if health == nil {
+		return
+	}
+
+	health.mutex.Lock()
+	health.failingSince = time.Time{}
+	health.mutex.Unlock()
+}
+
+// isFailingLongerThan must be called with locked mutex
+func (health *componentHealth) isFailingLongerThan(now time.Time, threshold time.Duration) bool {
+	return !health.failingSince.IsZero() && now.Sub(health.failingSince) > threshold
+}
+
+func (health *componentHealth) snapshot(now time.Time, threshold time.Duration) (componentHealthSnapshot, bool) {
+	health.mutex.Lock()
+	defer health.mutex.Unlock()
+
+	return health.makeSnapshot(), !health.isFailingLongerThan(now, threshold)
+}
+
+// makeSnapshot must be called with locked mutex
+func (health *componentHealth) makeSnapshot() componentHealthSnapshot {
+	snapshot := componentHealthSnapshot{}
+	if health.lastError != nil {
+		snapshot.LastError = health.lastError.Error()
+		snapshot.LastErrorAt = timePointer(health.lastErrorAt)
+	}
+	if !health.failingSince.IsZero() {
+		snapshot.FailingSince = timePointer(health.failingSince)
+	}
+
+	return snapshot
+}
+
+// ConnectorStatus
+/*
+ * Connector progress for readiness check and /status endpoint.
+ * Connector is ready when it waits for new messages or handled messages recently, and it is not failing too long.
+ */
+type ConnectorStatus struct {
+	componentHealth
+	Name          string
+	Topic         string
+	isStarted     bool
+	isFetching    bool
+	lastActiveAt  time.Time
+	hasFetched    bool
+	lastPartition int
+	lastOffset    int64
+	pendingCommit int
+	lag           *ConsumerLagTracker
+}
+
+type ConnectorStatusSnapshot struct {
+	componentHealthSnapshot
+	Name          string        `json:"name"`
+	Topic         string        `json:"topic"`
+	Ready         bool          `json:"ready"`
+	Started       bool          `json:"started"`
+	Fetching      bool          `json:"fetching"`
+	LastActiveAt  *time.Time    `json:"lastActiveAt,omitempty"`
+	LastPartition *int          `json:"lastPartition,omitempty"`
+	LastOffset    *int64        `json:"lastOffset,omitempty"`
+	PendingCommit int           `json:"pendingCommit"`
+	Lag           map[int]int64 `json:"lag,omitempty"`
+}
+
+func newConnectorStatus(name string, topic string, lag *ConsumerLagTracker) *ConnectorStatus {
+	return &ConnectorStatus{
+		Name:  name,
+		Topic: topic,
+		lag:   lag,
+	}
+}
+
+// failed shadows promoted componentHealth.failed to keep nil *ConnectorStatus safe
+func (status *ConnectorStatus) failed(err error) {
+	if status != nil {
+		status.componentHealth.failed(err)
+	}
+}
+
+func (status *ConnectorStatus) succeeded() {
+	if status != nil {
+		status.componentHealth.succeeded()
+	}
+}
+
+func (status *ConnectorStatus) started() {
+	if status == nil {
+		return
+	}
+
+	status.mutex.Lock()
+	status.isStarted = true
+	status.lastActiveAt = time.Now()
+	status.mutex.Unlock()
+}
+
+func (status *ConnectorStatus) fetchStarted() {
+	if status == nil {
+		return
+	}
+
+	status.mutex.Lock()
+	status.isFetching = true
+	status.lastActiveAt = time.Now()
+	status.mutex.Unlock()
+}
+
+// fetched marks that fetch is finished; messages are empty when fetch failed
+func (status *ConnectorStatus) fetched(messages []kafka.Message) {
+	if status == nil {
+		return
+	}
+
+	status.mutex.Lock()
+	status.isFetching = false
+	status.lastActiveAt = time.Now()
+	if len(messages) != 0 {
+		status.hasFetched = true
+		status.lastPartition = messages[len(messages)-1].Partition
+		status.lastOffset = messages[len(messages)-1].Offset
+	}
+	status.mutex.Unlock()
+
+	status.lag.observe(messages)
+}
+
+func (status *ConnectorStatus) setPendingCommit(pendingCommit int) {
+	if status == nil {
+		return
+	}
+
+	status.mutex.Lock()
+	status.pendingCommit = pendingCommit
+	status.mutex.Unlock()
+}
+
+func (status *ConnectorStatus) stopped() {
+	if status == nil {
+		return
+	}
+
+	status.mutex.Lock()
+	status.isStarted = false
+	status.isFetching = false
+	status.mutex.Unlock()
+}
+
+func (status *ConnectorStatus) snapshot(now time.Time, threshold time.Duration) ConnectorStatusSnapshot {
+	status.mutex.Lock()
+	defer status.mutex.Unlock()
+
+	snapshot := ConnectorStatusSnapshot{
+		componentHealthSnapshot: status.makeSnapshot(),
+		Name:                    status.Name,
+		Topic:                   status.Topic,
+		Started:                 status.isStarted,
+		Fetching:                status.isFetching,
+		PendingCommit:           status.pendingCommit,
+		Lag:                     status.lag.snapshot(),
+	}
+	if !status.lastActiveAt.IsZero() {
+		snapshot.LastActiveAt = timePointer(status.lastActiveAt)
+	}
+	if status.hasFetched {
+		lastPartition, lastOffset := status.lastPartition, status.lastOffset
+		snapshot.LastPartition = &lastPartition
+		snapshot.LastOffset = &lastOffset
+	}
+
+	snapshot.Ready = status.isStarted &&
+		(status.isFetching || now.Sub(status.lastActiveAt) <= threshold) &&
+		!status.isFailingLongerThan(now, threshold)
+
+	return snapshot
+}
+
+func timePointer(value time.Time) *time.Time {
+	return &value
+}
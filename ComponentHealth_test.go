@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestComponentHealth(t *testing.T) {
+	t.Run("failed and succeeded", func(t *testing.T) {
+		health := &componentHealth{}
+		expectedError := errors.New("expected error")
+
+		snapshot, ready := health.snapshot(time.Now(), time.Minute)
+		assert.True(t, ready)
+		assert.Empty(t, snapshot.LastError)
+		assert.Nil(t, snapshot.FailingSince)
+
+		health.failed(expectedError)
+		firstFailedAt := health.failingSince
+		health.failed(expectedError)
+		assert.Equal(t, firstFailedAt, health.failingSince)
+
+		snapshot, ready = health.snapshot(time.Now(), time.Minute)
+		assert.True(t, ready)
+		assert.Equal(t, expectedError.Error(), snapshot.LastError)
+		assert.NotNil(t, snapshot.LastErrorAt)
+		assert.Equal(t, firstFailedAt, *snapshot.FailingSince)
+
+		snapshot, ready = health.snapshot(time.Now().Add(time.Minute*2), time.Minute)
+		assert.False(t, ready)
+
+		health.succeeded()
+		snapshot, ready = health.snapshot(time.Now().Add(time.Minute*2), time.Minute)
+		assert.True(t, ready)
+		assert.Equal(t, expectedError.Error(), snapshot.LastError)
+		assert.Nil(t, snapshot.FailingSince)
+	})
+
+	t.Run("nil receiver", func(t *testing.T) {
+		var health *componentHealth
+		var status *ConnectorStatus
+
+		assert.NotPanics(t, func() {
+			health.failed(errors.New("expected error"))
+			health.succeeded()
+
+			status.started()
+			status.fetchStarted()
+			status.fetched(nil)
+			status.failed(errors.New("expected error"))
+			status.succeeded()
+			status.setPendingCommit(1)
+			status.stopped()
+		})
+	})
+}
+
+func TestConnectorStatus(t *testing.T) {
+	threshold := time.Minute
+
+	status := newConnectorStatus("scores#0", "scores", nil)
+
+	snapshot := status.snapshot(time.Now(), threshold)
+	assert.False(t, snapshot.Ready)
+	assert.False(t, snapshot.Started)
+	assert.Nil(t, snapshot.LastActiveAt)
+	assert.Nil(t, snapshot.LastOffset)
+
+	status.started()
+	status.fetchStarted()
+	snapshot = status.snapshot(time.Now(), threshold)
+	assert.True(t, snapshot.Ready)
+	assert.True(t, snapshot.Fetching)
+
+	// waiting for new messages for a long time is ok
+	snapshot = status.snapshot(time.Now().Add(threshold*2), threshold)
+	assert.True(t, snapshot.Ready)
+
+	status.fetched([]kafka.Message{{Partition: 2, Offset: 42}})
+	status.setPendingCommit(3)
+	snapshot = status.snapshot(time.Now(), threshold)
+	assert.True(t, snapshot.Ready)
+	assert.False(t, snapshot.Fetching)
+	assert.Equal(t, "scores#0", snapshot.Name)
+	assert.Equal(t, "scores", snapshot.Topic)
+	assert.Equal(t, 2, *snapshot.LastPartition)
+	assert.Equal(t, int64(42), *snapshot.LastOffset)
+	assert.Equal(t, 3, snapshot.PendingCommit)
+
+	// stuck on handling fetched messages
+	snapshot = status.snapshot(time.Now().Add(threshold*2), threshold)
+	assert.False(t, snapshot.Ready)
+
+	assert.Nil(t, snapshot.Lag)
+
+	lagStatus := newConnectorStatus("scores#1", "scores", newConsumerLagTracker(nil, "scores#1", "scores", 100, time.Minute, nil))
+	lagStatus.fetched([]kafka.Message{{Partition: 1, Offset: 42, HighWaterMark: 50}})
+	assert.Equal(t, map[int]int64{1: 7}, lagStatus.snapshot(time.Now(), threshold).Lag)
+
+	status.failed(errors.New("redis error"))
+	status.fetched(nil)
+	snapshot = status.snapshot(time.Now().Add(threshold/2), threshold)
+	assert.True(t, snapshot.Ready)
+	assert.Equal(t, "redis error", snapshot.LastError)
+	assert.Equal(t, int64(42), *snapshot.LastOffset)
+
+	status.stopped()
+	snapshot = status.snapshot(time.Now(), threshold)
+	assert.False(t, snapshot.Ready)
+	assert.False(t, snapshot.Started)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kneu-messenger-pigeon/events"
+	"os"
+	"time"
+)
+
+const (
+	TopologyScoresWriter      = "scores"
+	TopologyLessonsWriter     = "lessons"
+	TopologyDisciplinesWriter = "disciplines"
+	TopologyMetaEventsWriter  = "meta-events"
+)
+
+const DefaultTopologyGroupId = "storage-writer"
+
+const DefaultTopologyMinBytes = 10
+
+const DefaultTopologyMaxBytes = 10e3
+
+const DefaultTopologyMaxWait = time.Second
+
+type ConnectorTopology []ConnectorTopologyEntry
+
+// ConnectorTopologyEntry
+/*
+ * Describes connectors for one topic: which writer handles its messages and how many parallel readers to run.
+ */
+type ConnectorTopologyEntry struct {
+	Topic    string           `json:"topic"`
+	Writer   string           `json:"writer"`
+	Readers  int              `json:"readers"`
+	GroupId  string           `json:"groupId"`
+	MinBytes int              `json:"minBytes"`
+	MaxBytes int              `json:"maxBytes"`
+	MaxWait  topologyDuration `json:"maxWait"`
+}
+
+// topologyDuration accepts both duration string ("1s", "500ms") and number of nanoseconds.
+type topologyDuration time.Duration
+
+func (duration *topologyDuration) UnmarshalJSON(b []byte) error {
+	var value any
+	err := json.Unmarshal(b, &value)
+	if err != nil {
+		return err
+	}
+
+	switch typedValue := value.(type) {
+	case float64:
+		*duration = topologyDuration(typedValue)
+	case string:
+		var parsed time.Duration
+		parsed, err = time.ParseDuration(typedValue)
+		*duration = topologyDuration(parsed)
+	default:
+		err = fmt.Errorf("invalid duration: %s", string(b))
+	}
+
+	return err
+}
+
+func (duration topologyDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(duration).String())
+}
+
+func getDefaultConnectorTopology() ConnectorTopology {
+	return ConnectorTopology{
+		newConnectorTopologyEntry(events.RawScoresTopic, TopologyScoresWriter, 2),
+		newConnectorTopologyEntry(events.RawLessonsTopic, TopologyLessonsWriter, 2),
+		newConnectorTopologyEntry(events.DisciplinesTopic, TopologyDisciplinesWriter, 1),
+		newConnectorTopologyEntry(events.MetaEventsTopic, TopologyMetaEventsWriter, 1),
+	}
+}
+
+func newConnectorTopologyEntry(topic string, writer string, readers int) ConnectorTopologyEntry {
+	return ConnectorTopologyEntry{
+		Topic:    topic,
+		Writer:   writer,
+		Readers:  readers,
+		GroupId:  DefaultTopologyGroupId,
+		MinBytes: DefaultTopologyMinBytes,
+		MaxBytes: DefaultTopologyMaxBytes,
+		MaxWait:  topologyDuration(DefaultTopologyMaxWait),
+	}
+}
+
+func loadConnectorTopology(filename string) (ConnectorTopology, error) {
+	if filename == "" {
+		return getDefaultConnectorTopology(), nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading connectors topology %s: %w", filename, err)
+	}
+
+	var topology ConnectorTopology
+	err = json.Unmarshal(content, &topology)
+	if err == nil {
+		topology.fillDefaults()
+		err = topology.validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid connectors topology %s: %w", filename, err)
+	}
+
+	return topology, nil
+}
+
+func (topology ConnectorTopology) fillDefaults() {
+	for i := range topology {
+		if topology[i].Readers == 0 {
+			topology[i].Readers = 1
+		}
+		if topology[i].GroupId == "" {
+			topology[i].GroupId = DefaultTopologyGroupId
+		}
+		if topology[i].MinBytes == 0 {
+			topology[i].MinBytes = DefaultTopologyMinBytes
+		}
+		if topology[i].MaxBytes == 0 {
+			topology[i].MaxBytes = DefaultTopologyMaxBytes
+		}
+		if topology[i].MaxWait == 0 {
+			topology[i].MaxWait = topologyDuration(DefaultTopologyMaxWait)
+		}
+	}
+}
+
+func (topology ConnectorTopology) validate() error {
+	if len(topology) == 0 {
+		return errors.New("empty topology")
+	}
+
+	for i, entry := range topology {
+		if entry.Topic == "" {
+			return fmt.Errorf("entry %d: empty topic", i)
+		}
+
+		switch entry.Writer {
+		case TopologyScoresWriter, TopologyLessonsWriter, TopologyDisciplinesWriter, TopologyMetaEventsWriter:
+		default:
+			return fmt.Errorf("entry %d: unknown writer %q", i, entry.Writer)
+		}
+
+		if entry.Readers < 0 {
+			return fmt.Errorf("entry %d: negative readers count", i)
+		}
+		if entry.MinBytes > entry.MaxBytes {
+			return fmt.Errorf("entry %d: minBytes is greater than maxBytes", i)
+		}
+	}
+
+	return nil
+}
+
+func (topology ConnectorTopology) getReadersCount() (count int) {
+	for _, entry := range topology {
+		count += entry.Readers
+	}
+
+	return count
+}
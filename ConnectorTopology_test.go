@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/kneu-messenger-pigeon/events"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLoadConnectorTopology(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		topology, err := loadConnectorTopology("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, getDefaultConnectorTopology(), topology)
+		assert.Equal(t, 6, topology.getReadersCount())
+	})
+
+	t.Run("from file", func(t *testing.T) {
+		topology, err := loadConnectorTopology("connectors-topology.example.json")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, len(topology))
+		assert.Equal(t, 10, topology.getReadersCount())
+
+		assert.Equal(t, ConnectorTopologyEntry{
+			Topic:    events.RawScoresTopic,
+			Writer:   TopologyScoresWriter,
+			Readers:  6,
+			GroupId:  DefaultTopologyGroupId,
+			MinBytes: 10,
+			MaxBytes: 10000,
+			MaxWait:  topologyDuration(time.Second),
+		}, topology[0])
+
+		assert.Equal(t, newConnectorTopologyEntry(events.DisciplinesTopic, TopologyDisciplinesWriter, 1), topology[2])
+	})
+
+	t.Run("not exists file", func(t *testing.T) {
+		topology, err := loadConnectorTopology("not-exists-topology.json")
+
+		assert.Error(t, err)
+		assert.Nil(t, topology)
+	})
+
+	invalidTopologies := map[string]string{
+		"broken json":    `[{"topic": `,
+		"empty":          `[]`,
+		"empty topic":    `[{"writer": "scores"}]`,
+		"unknown writer": `[{"topic": "raw-scores", "writer": "unknown"}]`,
+		"negative":       `[{"topic": "raw-scores", "writer": "scores", "readers": -1}]`,
+		"min bytes":      `[{"topic": "raw-scores", "writer": "scores", "minBytes": 100, "maxBytes": 10}]`,
+		"wrong duration": `[{"topic": "raw-scores", "writer": "scores", "maxWait": "second"}]`,
+		"bool duration":  `[{"topic": "raw-scores", "writer": "scores", "maxWait": true}]`,
+	}
+
+	for name, content := range invalidTopologies {
+		t.Run("invalid "+name, func(t *testing.T) {
+			filename := "TestLoadConnectorTopology.json"
+			_ = os.WriteFile(filename, []byte(content), 0644)
+			defer os.Remove(filename)
+
+			topology, err := loadConnectorTopology(filename)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "Invalid connectors topology")
+			assert.Nil(t, topology)
+		})
+	}
+
+	t.Run("duration", func(t *testing.T) {
+		var duration topologyDuration
+
+		assert.NoError(t, duration.UnmarshalJSON([]byte(`500000000`)))
+		assert.Equal(t, topologyDuration(time.Millisecond*500), duration)
+
+		payload, err := duration.MarshalJSON()
+		assert.NoError(t, err)
+		assert.Equal(t, `"500ms"`, string(payload))
+	})
+}
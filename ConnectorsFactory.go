@@ -0,0 +1,83 @@
+package main
+
+import (
+	"github.com/kneu-messenger-pigeon/events"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+)
+
+// ConnectorsFactory
+/*
+ * Creates connectors of topology entries with shared writers, so the service and the rebuild command
+ * write into Redis the same way.
+ */
+type ConnectorsFactory struct {
+	logger                *slog.Logger
+	redis                 redis.UniversalClient
+	writers               map[string]WriterInterface
+	currentYearWriter     WriterInterface
+	lessonTypesListWriter WriterInterface
+	deadLetterWriter      DeadLetterWriterInterface
+}
+
+func newConnectorsFactory(
+	logger *slog.Logger, redis redis.UniversalClient,
+	scoresChangesFeedWriter ScoresChangesFeedWriterInterface,
+	lessonExistChecker LessonExistCheckerInterface, lessonWrittenNotifier LessonWrittenNotifierInterface,
+	deadLetterWriter DeadLetterWriterInterface, deletedLessonScoresFeed bool,
+) *ConnectorsFactory {
+	lessonWriter := &LessonWriter{
+		logger:                logger,
+		lessonExistChecker:    lessonExistChecker,
+		lessonWrittenNotifier: lessonWrittenNotifier,
+	}
+	if deletedLessonScoresFeed {
+		lessonWriter.scoresChangesFeedWriter = scoresChangesFeedWriter
+	}
+
+	return &ConnectorsFactory{
+		logger: logger,
+		redis:  redis,
+		writers: map[string]WriterInterface{
+			TopologyScoresWriter: &ScoreWriter{
+				logger:                  logger,
+				scoresChangesFeedWriter: scoresChangesFeedWriter,
+			},
+			TopologyLessonsWriter:     lessonWriter,
+			TopologyDisciplinesWriter: &DisciplineWriter{},
+		},
+		currentYearWriter: &YearChangeWriter{
+			logger:               logger,
+			isValidEducationYear: isValidEducationYear,
+		},
+		lessonTypesListWriter: &LessonTypesListWriter{logger: logger},
+		deadLetterWriter:      deadLetterWriter,
+	}
+}
+
+func (factory *ConnectorsFactory) newConnector(
+	entry ConnectorTopologyEntry, reader events.ReaderInterface, status *ConnectorStatus, metrics *ConnectorMetrics,
+) ConnectorInterface {
+	if entry.Writer == TopologyMetaEventsWriter {
+		return &KafkaToRedisMetaEventsConnector{
+			logger:                factory.logger,
+			redis:                 factory.redis,
+			currentYearWriter:     factory.currentYearWriter,
+			lessonTypesListWriter: factory.lessonTypesListWriter,
+			deadLetterWriter:      factory.deadLetterWriter,
+			reader:                reader,
+			status:                status,
+			metrics:               metrics,
+		}
+	}
+
+	return &KafkaToRedisConnector{
+		logger:           factory.logger,
+		redis:            factory.redis,
+		writer:           factory.writers[entry.Writer],
+		deadLetterWriter: factory.deadLetterWriter,
+		reader:           reader,
+		status:           status,
+		metrics:          metrics,
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestConnectorsFactory(t *testing.T) {
+	redis, _ := redismock.NewClientMock()
+	feedWriter := &SuppressedScoresChangesFeedWriter{}
+	factory := newConnectorsFactory(
+		newTestLogger(&bytes.Buffer{}), redis, feedWriter, NewMockLessonExistCheckerInterface(t), feedWriter, nil, false,
+	)
+
+	metaConnector := factory.newConnector(newConnectorTopologyEntry("meta", TopologyMetaEventsWriter, 1), nil, nil, nil)
+	assert.IsType(t, &KafkaToRedisMetaEventsConnector{}, metaConnector)
+
+	scoresStatus := newConnectorStatus("scores#0", "scores", nil)
+	scoresConnector := factory.newConnector(newConnectorTopologyEntry("scores", TopologyScoresWriter, 1), nil, scoresStatus, nil)
+	if assert.IsType(t, &KafkaToRedisConnector{}, scoresConnector) {
+		assert.IsType(t, &ScoreWriter{}, scoresConnector.(*KafkaToRedisConnector).writer)
+		assert.Same(t, scoresStatus, scoresConnector.(*KafkaToRedisConnector).status)
+		assert.Same(t, redis, scoresConnector.(*KafkaToRedisConnector).redis)
+	}
+
+	lessonsConnector := factory.newConnector(newConnectorTopologyEntry("lessons", TopologyLessonsWriter, 1), nil, nil, nil)
+	assert.IsType(t, &LessonWriter{}, lessonsConnector.(*KafkaToRedisConnector).writer)
+}
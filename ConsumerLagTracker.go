@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/segmentio/kafka-go"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const DefaultConsumerLagWarnThreshold = int64(10000)
+
+const DefaultConsumerLagWarnDuration = time.Minute * 5
+
+const DefaultConsumerLagRefreshInterval = time.Second * 30
+
+// ConsumerLagStaleRefreshIntervals is count of refresh intervals after which not observed partition is dropped
+const ConsumerLagStaleRefreshIntervals = 3
+
+type ConsumerLagStatsReaderInterface interface {
+	Stats() kafka.ReaderStats
+}
+
+// ConsumerLagTracker
+/*
+ * Tracks consumer lag of one connector per topic partition: lag = high-water mark - offset - 1 of fetched message.
+ * Works with consumer group readers, where reader stats don't have a lag.
+ * Logs a warning once lag of partition exceeds warnThreshold longer than warnDuration, and info when it recovers.
+ * Lag is refreshed by execute on timer too, so stalled connector, which doesn't fetch messages, is warned as well.
+ * Partition, which is not observed longer than getStaleTimeout() (e.g. revoked on rebalance), is dropped
+ * with its gauge and status value.
+ * Methods are safe to call on nil receiver.
+ */
+type ConsumerLagTracker struct {
+	logger          *slog.Logger
+	connector       string
+	topic           string
+	warnThreshold   int64
+	warnDuration    time.Duration
+	reader          ConsumerLagStatsReaderInterface
+	refreshInterval time.Duration
+	mutex           sync.Mutex
+	partitions      map[int]*partitionLag
+}
+
+type partitionLag struct {
+	lag           int64
+	observedAt    time.Time
+	exceededSince time.Time
+	isWarned      bool
+	gaugeName     string
+	gauge         *metrics.Gauge
+}
+
+func newConsumerLagTracker(
+	logger *slog.Logger, connector string, topic string, warnThreshold int64, warnDuration time.Duration,
+	reader ConsumerLagStatsReaderInterface,
+) *ConsumerLagTracker {
+	return &ConsumerLagTracker{
+		logger:          logger,
+		connector:       connector,
+		topic:           topic,
+		warnThreshold:   warnThreshold,
+		warnDuration:    warnDuration,
+		reader:          reader,
+		refreshInterval: DefaultConsumerLagRefreshInterval,
+		partitions:      make(map[int]*partitionLag),
+	}
+}
+
+func (tracker *ConsumerLagTracker) execute(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(tracker.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			tracker.refresh(tracker.reader.Stats(), time.Now())
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (tracker *ConsumerLagTracker) observe(messages []kafka.Message) {
+	if tracker == nil || len(messages) == 0 {
+		return
+	}
+
+	// only the last fetched message of each partition matters
+	lastOffsets := make(map[int]kafka.Message)
+	for _, message := range messages {
+		if message.HighWaterMark > 0 {
+			lastOffsets[message.Partition] = message
+		}
+	}
+
+	now := time.Now()
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	for partition, message := range lastOffsets {
+		lag := message.HighWaterMark - message.Offset - 1
+		if lag < 0 {
+			lag = 0
+		}
+		tracker.update(partition, lag, now)
+	}
+}
+
+// refresh
+/*
+ * Updates lag from reader stats and re-evaluates lag of all partitions, so warning is logged after warnDuration
+ * even when no messages are fetched. Stats of reader of one partition have lag of the last message fetched
+ * by the reader in background; stats of consumer group reader have no partition, so the last observed lag is kept.
+ */
+func (tracker *ConsumerLagTracker) refresh(stats kafka.ReaderStats, now time.Time) {
+	if tracker == nil {
+		return
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	statsPartition, err := strconv.Atoi(stats.Partition)
+	if err == nil && statsPartition >= 0 && stats.Lag >= 0 {
+		tracker.update(statsPartition, stats.Lag, now)
+	}
+
+	staleTimeout := tracker.getStaleTimeout()
+	for partition, state := range tracker.partitions {
+		if now.Sub(state.observedAt) > staleTimeout {
+			tracker.drop(partition, state)
+		} else {
+			tracker.evaluate(partition, state, now)
+		}
+	}
+}
+
+// getStaleTimeout
+/*
+ * Partition is kept a few refresh intervals after the last observation, but not less than warnDuration,
+ * so lag of stalled connector is warned before its partitions are dropped.
+ */
+func (tracker *ConsumerLagTracker) getStaleTimeout() time.Duration {
+	return max(tracker.refreshInterval*ConsumerLagStaleRefreshIntervals, tracker.warnDuration+tracker.refreshInterval)
+}
+
+// update must be called with locked mutex
+func (tracker *ConsumerLagTracker) update(partition int, lag int64, now time.Time) {
+	state, exists := tracker.partitions[partition]
+	if !exists {
+		state = &partitionLag{
+			gaugeName: fmt.Sprintf(
+				`connector__consumer_lag{connector=%q,topic=%q,partition="%d"}`,
+				tracker.connector, tracker.topic, partition,
+			),
+		}
+		state.gauge = metrics.GetOrCreateGauge(state.gaugeName, nil)
+		tracker.partitions[partition] = state
+	}
+
+	state.lag = lag
+	state.observedAt = now
+	state.gauge.Set(float64(lag))
+
+	tracker.evaluate(partition, state, now)
+}
+
+// drop must be called with locked mutex
+func (tracker *ConsumerLagTracker) drop(partition int, state *partitionLag) {
+	delete(tracker.partitions, partition)
+	metrics.UnregisterMetric(state.gaugeName)
+
+	tracker.logger.Info(
+		"consumer lag of partition is not observed, drop it",
+		"connector", tracker.connector, "topic", tracker.topic, "partition", partition,
+		"lag", state.lag, "observedAt", state.observedAt,
+	)
+}
+
+// evaluate logs warning when lag exceeds threshold longer than warnDuration; must be called with locked mutex
+func (tracker *ConsumerLagTracker) evaluate(partition int, state *partitionLag, now time.Time) {
+	lag := state.lag
+	if lag <= tracker.warnThreshold {
+		if state.isWarned {
+			tracker.logger.Info(
+				"consumer lag recovered",
+				"connector", tracker.connector, "topic", tracker.topic, "partition", partition, "lag", lag,
+			)
+		}
+		state.exceededSince = time.Time{}
+		state.isWarned = false
+		return
+	}
+
+	if state.exceededSince.IsZero() {
+		state.exceededSince = now
+	}
+	if !state.isWarned && now.Sub(state.exceededSince) >= tracker.warnDuration {
+		state.isWarned = true
+		tracker.logger.Warn(
+			"consumer lag exceeds threshold",
+			"connector", tracker.connector, "topic", tracker.topic, "partition", partition,
+			"lag", lag, "threshold", tracker.warnThreshold, "since", state.exceededSince,
+		)
+	}
+}
+
+func (tracker *ConsumerLagTracker) snapshot() map[int]int64 {
+	if tracker == nil {
+		return nil
+	}
+
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	if len(tracker.partitions) == 0 {
+		return nil
+	}
+
+	lags := make(map[int]int64, len(tracker.partitions))
+	for partition, state := range tracker.partitions {
+		lags[partition] = state.lag
+	}
+
+	return lags
+}
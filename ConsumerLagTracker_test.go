@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsumerLagTracker(t *testing.T) {
+	t.Run("lag per partition", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		tracker := newConsumerLagTracker(newTestLogger(out), "test-lag#0", "raw_scores", 100, time.Minute, nil)
+
+		tracker.observe([]kafka.Message{
+			{Partition: 0, Offset: 10, HighWaterMark: 50},
+			{Partition: 1, Offset: 5, HighWaterMark: 6},
+			{Partition: 0, Offset: 11, HighWaterMark: 50},
+			// high-water mark is unknown
+			{Partition: 2, Offset: 3},
+		})
+
+		assert.Equal(t, map[int]int64{0: 38, 1: 0}, tracker.snapshot())
+		assert.Equal(t, float64(38), tracker.partitions[0].gauge.Get())
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("warn when lag exceeds threshold longer than duration", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		tracker := newConsumerLagTracker(newTestLogger(out), "test-lag-warn#0", "raw_scores", 100, time.Minute, nil)
+		now := time.Now()
+
+		tracker.update(0, 500, now)
+		assert.Empty(t, out.String())
+
+		tracker.update(0, 400, now.Add(time.Second*30))
+		assert.Empty(t, out.String())
+
+		tracker.update(0, 300, now.Add(time.Minute))
+		tracker.update(0, 300, now.Add(time.Minute*2))
+		assert.Equal(t, 1, strings.Count(out.String(), `msg="consumer lag exceeds threshold"`))
+		assert.Contains(t, out.String(), `partition=0 lag=300 threshold=100`)
+
+		tracker.update(0, 50, now.Add(time.Minute*3))
+		assert.Contains(t, out.String(), `msg="consumer lag recovered" connector=test-lag-warn#0 topic=raw_scores partition=0 lag=50`)
+
+		out.Reset()
+		tracker.update(0, 500, now.Add(time.Minute*4))
+		tracker.update(0, 50, now.Add(time.Minute*4+time.Second))
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("refresh lag of stalled connector", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		tracker := newConsumerLagTracker(newTestLogger(out), "test-lag-stalled#0", "raw_scores", 100, time.Minute, nil)
+		now := time.Now()
+
+		tracker.update(0, 500, now)
+		// consumer group reader stats have no partition
+		tracker.refresh(kafka.ReaderStats{Partition: "-1", Lag: 900}, now.Add(time.Second*30))
+		assert.Empty(t, out.String())
+
+		tracker.refresh(kafka.ReaderStats{Partition: "-1", Lag: 900}, now.Add(time.Minute))
+		assert.Contains(t, out.String(), `msg="consumer lag exceeds threshold" connector=test-lag-stalled#0`)
+		assert.Contains(t, out.String(), `partition=0 lag=500 threshold=100`)
+		assert.Equal(t, map[int]int64{0: 500}, tracker.snapshot())
+	})
+
+	t.Run("drop partition which is not observed", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		tracker := newConsumerLagTracker(newTestLogger(out), "test-lag-stale#0", "raw_scores", 100, time.Minute, nil)
+		tracker.refreshInterval = time.Second * 30
+		now := time.Now()
+
+		tracker.update(0, 500, now)
+		tracker.update(1, 20, now)
+		staleGaugeName := tracker.partitions[0].gaugeName
+		assert.Contains(t, metrics.ListMetricNames(), staleGaugeName)
+
+		// partition 0 is revoked, only partition 1 is still observed
+		tracker.update(1, 10, now.Add(time.Minute))
+		tracker.refresh(kafka.ReaderStats{Partition: "-1"}, now.Add(time.Minute+time.Second*30))
+		assert.Equal(t, map[int]int64{0: 500, 1: 10}, tracker.snapshot())
+
+		tracker.refresh(kafka.ReaderStats{Partition: "-1"}, now.Add(time.Minute+time.Second*31))
+		assert.Equal(t, map[int]int64{1: 10}, tracker.snapshot())
+		assert.NotContains(t, metrics.ListMetricNames(), staleGaugeName)
+		assert.Contains(t, out.String(), `msg="consumer lag of partition is not observed, drop it" connector=test-lag-stale#0 topic=raw_scores partition=0 lag=500`)
+
+		// partition is tracked again when it is observed after drop
+		tracker.observe([]kafka.Message{{Partition: 0, Offset: 10, HighWaterMark: 12}})
+		assert.Equal(t, map[int]int64{0: 1, 1: 10}, tracker.snapshot())
+		assert.Contains(t, metrics.ListMetricNames(), staleGaugeName)
+	})
+
+	t.Run("refresh lag from partition reader stats", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		tracker := newConsumerLagTracker(newTestLogger(out), "test-lag-refresh#0", "raw_scores", 100, time.Minute, nil)
+
+		tracker.refresh(kafka.ReaderStats{Partition: "2", Lag: 40}, time.Now())
+
+		assert.Equal(t, map[int]int64{2: 40}, tracker.snapshot())
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("execute", func(t *testing.T) {
+		reader := NewMockConsumerLagStatsReaderInterface(t)
+		reader.On("Stats").Return(kafka.ReaderStats{Partition: "1", Lag: 70})
+
+		tracker := newConsumerLagTracker(
+			newTestLogger(&bytes.Buffer{}), "test-lag-execute#0", "raw_scores", 100, time.Minute, reader,
+		)
+		tracker.refreshInterval = time.Millisecond * 10
+
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go tracker.execute(ctx, wg)
+
+		assert.Eventually(t, func() bool {
+			return tracker.snapshot()[1] == 70
+		}, time.Second, time.Millisecond*10)
+
+		cancel()
+		wg.Wait()
+	})
+
+	t.Run("nil receiver", func(t *testing.T) {
+		var tracker *ConsumerLagTracker
+
+		assert.NotPanics(t, func() {
+			tracker.observe([]kafka.Message{{Partition: 0, Offset: 10, HighWaterMark: 50}})
+			tracker.refresh(kafka.ReaderStats{Partition: "0", Lag: 40}, time.Now())
+		})
+		assert.Nil(t, tracker.snapshot())
+	})
+}
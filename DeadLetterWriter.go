@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/kneu-messenger-pigeon/events"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultDeadLetterAttempts = 3
+
+const DeadLetterRetryDelay = time.Millisecond * 500
+
+// TransientErrorMaxRetryDelay limits backoff of retries after network and infrastructure errors
+const TransientErrorMaxRetryDelay = time.Second * 10
+
+// transientRedisErrorPrefixes are prefixes of Redis replies, which are caused by server state and not by message
+var transientRedisErrorPrefixes = []string{"LOADING", "BUSY", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN", "READONLY", "MISCONF"}
+
+// transientRedisClientErrors are messages of Redis client pool errors, which are not exported by go-redis
+var transientRedisClientErrors = []string{
+	"redis: connection pool timeout", "redis: connection pool exhausted", "ERR max number of clients reached",
+}
+
+const (
+	DeadLetterHeaderKey       = "dead-letter-original-key"
+	DeadLetterHeaderTopic     = "dead-letter-original-topic"
+	DeadLetterHeaderPartition = "dead-letter-original-partition"
+	DeadLetterHeaderOffset    = "dead-letter-original-offset"
+	DeadLetterHeaderError     = "dead-letter-error"
+	DeadLetterHeaderAttempts  = "dead-letter-attempts"
+)
+
+type DeadLetterWriterInterface interface {
+	getMaxAttempts() int
+	write(message kafka.Message, attempts int, err error) error
+}
+
+type DeadLetterWriter struct {
+	writer      events.WriterInterface
+	maxAttempts int
+}
+
+func (deadLetterWriter *DeadLetterWriter) getMaxAttempts() int {
+	if deadLetterWriter.maxAttempts < 1 {
+		return 1
+	}
+
+	return deadLetterWriter.maxAttempts
+}
+
+func (deadLetterWriter *DeadLetterWriter) write(message kafka.Message, attempts int, err error) error {
+	headers := make([]kafka.Header, len(message.Headers), len(message.Headers)+6)
+	copy(headers, message.Headers)
+
+	return deadLetterWriter.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   message.Key,
+		Value: message.Value,
+		Headers: append(
+			headers,
+			kafka.Header{Key: DeadLetterHeaderKey, Value: message.Key},
+			kafka.Header{Key: DeadLetterHeaderTopic, Value: []byte(message.Topic)},
+			kafka.Header{Key: DeadLetterHeaderPartition, Value: []byte(strconv.Itoa(message.Partition))},
+			kafka.Header{Key: DeadLetterHeaderOffset, Value: []byte(strconv.FormatInt(message.Offset, 10))},
+			kafka.Header{Key: DeadLetterHeaderError, Value: []byte(err.Error())},
+			kafka.Header{Key: DeadLetterHeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		),
+	})
+}
+
+// writeWithDeadLetter
+/*
+ * Calls handle up to deadLetterWriter.getMaxAttempts() times and, if every attempt failed,
+ * publishes the message into dead-letter topic. Returned err is nil when message was handled or dead-lettered,
+ * so caller can commit it; handleErr is the last handle error of dead-lettered message.
+ * Only errors caused by message are dead-lettered (e.g. decode, validation or Redis script errors):
+ * after network and infrastructure errors handle is retried with backoff until success or ctx is done,
+ * as such errors would dead-letter every message during short Redis outage.
+ * Without deadLetterWriter the message is handled only once.
+ */
+func writeWithDeadLetter(
+	ctx context.Context, logger *slog.Logger, deadLetterWriter DeadLetterWriterInterface,
+	message kafka.Message, handle func(message kafka.Message) error,
+) (handleErr error, err error) {
+	if deadLetterWriter == nil {
+		return nil, handle(message)
+	}
+
+	attempts := 0
+	maxAttempts := deadLetterWriter.getMaxAttempts()
+	transientErrorDelay := DeadLetterRetryDelay
+	for {
+		handleErr = handle(message)
+		if handleErr == nil {
+			return nil, nil
+		}
+
+		delay := DeadLetterRetryDelay
+		if isTransientError(handleErr) {
+			delay = transientErrorDelay
+			transientErrorDelay = min(transientErrorDelay*2, TransientErrorMaxRetryDelay)
+			logger.Warn(
+				"transient write error, retry", append(messageLogArgs(message), "delay", delay, "error", handleErr)...,
+			)
+		} else if attempts++; attempts >= maxAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, handleErr
+		case <-time.After(delay):
+		}
+	}
+
+	return handleErr, deadLetterWriter.write(message, attempts, handleErr)
+}
+
+// isTransientError returns true for network and infrastructure errors, which are not caused by handled message
+func isTransientError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, redis.ErrClosed) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+
+	for _, prefix := range transientRedisErrorPrefixes {
+		if redis.HasErrorPrefix(err, prefix) {
+			return true
+		}
+	}
+	for _, message := range transientRedisClientErrors {
+		if strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kneu-messenger-pigeon/events"
+	"github.com/kneu-messenger-pigeon/events/mocks"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestDeadLetterWriter(t *testing.T) {
+	matchContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+
+	t.Run("write", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		message := kafka.Message{
+			Topic:     events.RawScoresTopic,
+			Partition: 1,
+			Offset:    1234,
+			Key:       []byte(events.ScoreEventName),
+			Value:     []byte("{broken"),
+		}
+
+		expectedMessage := kafka.Message{
+			Key:   message.Key,
+			Value: message.Value,
+			Headers: []kafka.Header{
+				{Key: DeadLetterHeaderKey, Value: []byte(events.ScoreEventName)},
+				{Key: DeadLetterHeaderTopic, Value: []byte(events.RawScoresTopic)},
+				{Key: DeadLetterHeaderPartition, Value: []byte("1")},
+				{Key: DeadLetterHeaderOffset, Value: []byte("1234")},
+				{Key: DeadLetterHeaderError, Value: []byte(expectedError.Error())},
+				{Key: DeadLetterHeaderAttempts, Value: []byte("3")},
+			},
+		}
+
+		writer := mocks.NewWriterInterface(t)
+		writer.On("WriteMessages", matchContext, expectedMessage).Once().Return(nil)
+
+		deadLetterWriter := DeadLetterWriter{
+			writer:      writer,
+			maxAttempts: 3,
+		}
+
+		assert.Equal(t, 3, deadLetterWriter.getMaxAttempts())
+		assert.NoError(t, deadLetterWriter.write(message, 3, expectedError))
+	})
+
+	t.Run("min attempts", func(t *testing.T) {
+		deadLetterWriter := DeadLetterWriter{}
+
+		assert.Equal(t, 1, deadLetterWriter.getMaxAttempts())
+	})
+}
+
+func TestWriteWithDeadLetter(t *testing.T) {
+	message := kafka.Message{
+		Key:   []byte(events.ScoreEventName),
+		Value: []byte("{}"),
+	}
+	logger := newTestLogger(&bytes.Buffer{})
+
+	t.Run("without dead-letter writer", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		calls := 0
+		handleErr, err := writeWithDeadLetter(context.Background(), logger, nil, message, func(_ kafka.Message) error {
+			calls++
+			return expectedError
+		})
+
+		assert.NoError(t, handleErr)
+		assert.Equal(t, expectedError, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("success after retry", func(t *testing.T) {
+		deadLetterWriter := NewMockDeadLetterWriterInterface(t)
+		deadLetterWriter.On("getMaxAttempts").Return(3)
+
+		calls := 0
+		handleErr, err := writeWithDeadLetter(context.Background(), logger, deadLetterWriter, message, func(_ kafka.Message) error {
+			calls++
+			if calls == 1 {
+				return errors.New("expected error")
+			}
+			return nil
+		})
+
+		assert.NoError(t, handleErr)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		deadLetterWriter.AssertNotCalled(t, "write")
+	})
+
+	t.Run("moved to dead-letter", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		deadLetterWriter := NewMockDeadLetterWriterInterface(t)
+		deadLetterWriter.On("getMaxAttempts").Return(2)
+		deadLetterWriter.On("write", message, 2, expectedError).Once().Return(nil)
+
+		calls := 0
+		handleErr, err := writeWithDeadLetter(context.Background(), logger, deadLetterWriter, message, func(_ kafka.Message) error {
+			calls++
+			return expectedError
+		})
+
+		assert.Equal(t, expectedError, handleErr)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("context cancelled between attempts", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		deadLetterWriter := NewMockDeadLetterWriterInterface(t)
+		deadLetterWriter.On("getMaxAttempts").Return(3)
+
+		handleErr, err := writeWithDeadLetter(ctx, logger, deadLetterWriter, message, func(_ kafka.Message) error {
+			cancel()
+			return expectedError
+		})
+
+		assert.NoError(t, handleErr)
+		assert.Equal(t, expectedError, err)
+		deadLetterWriter.AssertNotCalled(t, "write")
+	})
+
+	t.Run("redis connection error is not dead-lettered", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		server, client := newMiniRedisClient(t)
+		server.Close()
+
+		deadLetterWriter := NewMockDeadLetterWriterInterface(t)
+		deadLetterWriter.On("getMaxAttempts").Return(1)
+
+		calls := 0
+		handleErr, err := writeWithDeadLetter(context.Background(), newTestLogger(out), deadLetterWriter, message, func(_ kafka.Message) error {
+			calls++
+			if calls == 2 {
+				assert.NoError(t, server.Restart())
+			}
+			return client.Set(context.Background(), "key", "value", 0).Err()
+		})
+
+		assert.NoError(t, handleErr)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, "value", client.Get(context.Background(), "key").Val())
+		assert.Contains(t, out.String(), `msg="transient write error, retry"`)
+		deadLetterWriter.AssertNotCalled(t, "write")
+	})
+
+	t.Run("transient error until context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		deadLetterWriter := NewMockDeadLetterWriterInterface(t)
+		deadLetterWriter.On("getMaxAttempts").Return(1)
+
+		calls := 0
+		handleErr, err := writeWithDeadLetter(ctx, logger, deadLetterWriter, message, func(_ kafka.Message) error {
+			calls++
+			if calls == 3 {
+				cancel()
+			}
+			return io.EOF
+		})
+
+		assert.NoError(t, handleErr)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, 3, calls)
+		deadLetterWriter.AssertNotCalled(t, "write")
+	})
+}
+
+// redisReplyError emulates error reply of Redis server
+type redisReplyError string
+
+func (err redisReplyError) Error() string { return string(err) }
+
+func (err redisReplyError) RedisError() {}
+
+func TestIsTransientError(t *testing.T) {
+	transientErrors := []error{
+		io.EOF,
+		fmt.Errorf("read: %w", io.ErrUnexpectedEOF),
+		&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+		redis.ErrClosed,
+		context.DeadlineExceeded,
+		errors.New("redis: connection pool timeout"),
+		redisReplyError("LOADING Redis is loading the dataset in memory"),
+		redisReplyError("READONLY You can't write against a read only replica."),
+		redisReplyError("BUSY Redis is busy running a script."),
+	}
+	for _, err := range transientErrors {
+		assert.True(t, isTransientError(err), err.Error())
+	}
+
+	permanentErrors := []error{
+		&json.SyntaxError{},
+		errors.New("invalid score event"),
+		redisReplyError("ERR Error running script (call to f_1234): user_script:1: bad argument"),
+		redisReplyError("WRONGTYPE Operation against a key holding the wrong kind of value"),
+	}
+	for _, err := range permanentErrors {
+		assert.False(t, isTransientError(err), err.Error())
+	}
+}
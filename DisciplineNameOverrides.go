@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type DisciplineNameOverride struct {
+	id       uint
+	name     string
+	origName string
+}
+
+// DisciplineNameOverrides
+/*
+ * Manages manually set discipline names, which can't be fixed by discipline name rules.
+ * Overrides are stored in "{year}:discipline_name_overrides" hash by discipline id and written into discipline name.
+ * DisciplineWriter and DisciplineNamesRenormalizer don't touch name of discipline with override.
+ */
+type DisciplineNameOverrides struct {
+	redis redis.UniversalClient
+}
+
+// set writes override, discipline name and search index of name in one transaction
+func (overrides *DisciplineNameOverrides) set(ctx context.Context, year int, id uint, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("empty discipline name")
+	}
+
+	_, err := overrides.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, getDisciplineNameOverridesKey(year), strconv.FormatUint(uint64(id), 10), name)
+		pipe.HSet(ctx, getDisciplineInfoKey(year, id), getDisciplineNameFields(name)...)
+		updateDisciplineSearchIndex(ctx, pipe, year, id, name)
+		return nil
+	})
+
+	return err
+}
+
+// remove deletes override and restores name normalized from origName with its search index in one transaction;
+// returns false when override doesn't exist
+func (overrides *DisciplineNameOverrides) remove(ctx context.Context, year int, id uint) (bool, error) {
+	key := getDisciplineInfoKey(year, id)
+	overridesKey := getDisciplineNameOverridesKey(year)
+	isRemoved := false
+
+	// origName could be changed by DisciplineWriter between read and write, so transaction fails in such case
+	err := overrides.redis.Watch(ctx, func(tx *redis.Tx) error {
+		origName, _ := tx.HMGet(ctx, key, "origName").Val()[0].(string)
+
+		cmds, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HDel(ctx, overridesKey, strconv.FormatUint(uint64(id), 10))
+			if origName != "" {
+				rules := getDisciplineNameRules()
+				restoredName := rules.clear(origName)
+				fields := append(getDisciplineNameFields(restoredName), "nameRulesVersion", rules.version)
+				pipe.HSet(ctx, key, fields...)
+				updateDisciplineSearchIndex(ctx, pipe, year, id, restoredName)
+			}
+			return nil
+		})
+		if err == nil {
+			isRemoved = cmds[0].(*redis.IntCmd).Val() == 1
+		}
+
+		return err
+	}, key)
+
+	return isRemoved, err
+}
+
+// list returns overrides of year sorted by discipline id with current origName of discipline
+func (overrides *DisciplineNameOverrides) list(ctx context.Context, year int) ([]DisciplineNameOverride, error) {
+	names, err := overrides.redis.HGetAll(ctx, getDisciplineNameOverridesKey(year)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]DisciplineNameOverride, 0, len(names))
+	for field, name := range names {
+		id, err := strconv.ParseUint(field, 10, 0)
+		if err == nil {
+			list = append(list, DisciplineNameOverride{id: uint(id), name: name})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].id < list[j].id
+	})
+
+	cmds := make([]*redis.SliceCmd, len(list))
+	_, err = overrides.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, override := range list {
+			cmds[i] = pipe.HMGet(ctx, getDisciplineInfoKey(year, override.id), "origName")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		list[i].origName, _ = cmds[i].Val()[0].(string)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDisciplineNameOverrides(t *testing.T) {
+	ctx := context.Background()
+	version := getDisciplineNameRules().version
+
+	expectRemove := func(redisMock redismock.ClientMock, removed int64) {
+		redisMock.ExpectWatch("2030:discipline:200")
+		redisMock.ExpectHMGet("2030:discipline:200", "origName").SetVal([]interface{}{"Фінанси, 5 сем."})
+		redisMock.ExpectTxPipeline()
+		redisMock.ExpectHDel("2030:discipline_name_overrides", "200").SetVal(removed)
+		redisMock.ExpectHSet(
+			"2030:discipline:200", "name", "Фінанси", "shortName", "Фінанси", "latinName", "Finansy",
+			"nameRulesVersion", version,
+		).SetVal(0)
+		redisMock.ExpectEval(
+			updateDisciplineSearchIndexScriptSource, []string{"2030:discipline:200"},
+			"2030:discipline_search:", "200", "фі фін фіна фінан фінанс фінанси",
+		).SetVal(int64(1))
+		redisMock.ExpectTxPipelineExec()
+	}
+
+	t.Run("set", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectTxPipeline()
+		redisMock.ExpectHSet("2030:discipline_name_overrides", "200", "Фінанси та кредит").SetVal(1)
+		redisMock.ExpectHSet(
+			"2030:discipline:200",
+			"name", "Фінанси та кредит", "shortName", "Фінанси та кредит", "latinName", "Finansy ta kredyt",
+		).SetVal(0)
+		redisMock.ExpectEval(
+			updateDisciplineSearchIndexScriptSource, []string{"2030:discipline:200"},
+			"2030:discipline_search:", "200", "фі фін фіна фінан фінанс фінанси та кр кре кред креди кредит",
+		).SetVal(int64(1))
+		redisMock.ExpectTxPipelineExec()
+
+		out := &bytes.Buffer{}
+		err := executeNameOverride(
+			ctx, out, &DisciplineNameOverrides{redis: redisClient},
+			NameOverrideOptions{action: NameOverrideActionSet, year: 2030, id: 200, name: "Фінанси та кредит"},
+		)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, "2030:200: name is set to \"Фінанси та кредит\"\n", out.String())
+
+		overrides := &DisciplineNameOverrides{redis: redisClient}
+		assert.EqualError(t, overrides.set(ctx, 2030, 200, "  "), "empty discipline name")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		expectRemove(redisMock, 1)
+
+		out := &bytes.Buffer{}
+		err := executeNameOverride(
+			ctx, out, &DisciplineNameOverrides{redis: redisClient},
+			NameOverrideOptions{action: NameOverrideActionRemove, year: 2030, id: 200},
+		)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, "2030:200: name override is removed\n", out.String())
+	})
+
+	t.Run("remove not existing", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		expectRemove(redisMock, 0)
+
+		err := executeNameOverride(
+			ctx, &bytes.Buffer{}, &DisciplineNameOverrides{redis: redisClient},
+			NameOverrideOptions{action: NameOverrideActionRemove, year: 2030, id: 200},
+		)
+
+		assert.EqualError(t, err, "2030:200: name override not found")
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+}
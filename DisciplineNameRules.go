@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+	"log/slog"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"unicode/utf8"
+)
+
+const (
+	DisciplineNameCapitalizeFirst = "first"
+	DisciplineNameCapitalizeNone  = "none"
+)
+
+//go:embed discipline-name-rules.yaml
+var defaultDisciplineNameRulesSource []byte
+
+// DisciplineNameRulesSource
+/*
+ * Discipline name normalization rules as written in YAML or JSON file.
+ * Steps are applied in order: regexp rewrites, literal replacements, regexp removals, trim,
+ * collapse of repeated whitespaces and capitalization.
+ */
+type DisciplineNameRulesSource struct {
+	Rewrite []struct {
+		Pattern     string `yaml:"pattern"`
+		Replacement string `yaml:"replacement"`
+	} `yaml:"rewrite"`
+	Replace []struct {
+		From string `yaml:"from"`
+		To   string `yaml:"to"`
+	} `yaml:"replace"`
+	Remove []string `yaml:"remove"`
+	Trim   struct {
+		Right string `yaml:"right"`
+		Left  string `yaml:"left"`
+	} `yaml:"trim"`
+	CollapseSpaces bool   `yaml:"collapseSpaces"`
+	Capitalize     string `yaml:"capitalize"`
+}
+
+type disciplineNameRewrite struct {
+	regexp      *regexp.Regexp
+	replacement string
+}
+
+// DisciplineNameRules are validated and compiled rules, safe for concurrent use
+type DisciplineNameRules struct {
+	version           string
+	rewrites          []disciplineNameRewrite
+	replacers         []*strings.Replacer
+	removals          []*regexp.Regexp
+	trimRight         string
+	trimLeft          string
+	collapseSpaces    bool
+	isCapitalizeFirst bool
+}
+
+var removeDuplicateSpaces = regexp.MustCompile(`\s+`)
+
+var ukrainianToUpper = cases.Upper(language.Ukrainian)
+
+var currentDisciplineNameRules atomic.Pointer[DisciplineNameRules]
+
+func init() {
+	rules, err := parseDisciplineNameRules(defaultDisciplineNameRulesSource)
+	if err != nil {
+		panic("invalid default discipline name rules: " + err.Error())
+	}
+	currentDisciplineNameRules.Store(rules)
+}
+
+func getDisciplineNameRules() *DisciplineNameRules {
+	return currentDisciplineNameRules.Load()
+}
+
+func setDisciplineNameRules(rules *DisciplineNameRules) {
+	currentDisciplineNameRules.Store(rules)
+}
+
+// loadDisciplineNameRules loads rules from YAML or JSON file, embedded default rules are returned for empty filename
+func loadDisciplineNameRules(filename string) (*DisciplineNameRules, error) {
+	if filename == "" {
+		return parseDisciplineNameRules(defaultDisciplineNameRulesSource)
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading discipline name rules %s: %w", filename, err)
+	}
+
+	rules, err := parseDisciplineNameRules(content)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid discipline name rules %s: %w", filename, err)
+	}
+
+	return rules, nil
+}
+
+// parseDisciplineNameRules parses YAML (or JSON, as YAML superset) rules; unknown fields are not allowed
+func parseDisciplineNameRules(content []byte) (*DisciplineNameRules, error) {
+	source := DisciplineNameRulesSource{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	err := decoder.Decode(&source)
+	if err != nil {
+		return nil, err
+	}
+
+	return source.compile()
+}
+
+func (source DisciplineNameRulesSource) compile() (*DisciplineNameRules, error) {
+	rules := &DisciplineNameRules{
+		version:        source.getVersion(),
+		trimRight:      source.Trim.Right,
+		trimLeft:       source.Trim.Left,
+		collapseSpaces: source.CollapseSpaces,
+	}
+
+	var err error
+	for i, rewrite := range source.Rewrite {
+		compiled := disciplineNameRewrite{replacement: rewrite.Replacement}
+		compiled.regexp, err = regexp.Compile(rewrite.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rewrite #%d: %w", i, err)
+		}
+		rules.rewrites = append(rules.rewrites, compiled)
+	}
+
+	for i, replace := range source.Replace {
+		if replace.From == "" {
+			return nil, fmt.Errorf("replace #%d: empty from", i)
+		}
+		rules.replacers = append(rules.replacers, strings.NewReplacer(replace.From, replace.To))
+	}
+
+	for i, pattern := range source.Remove {
+		removal, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("remove #%d: %w", i, err)
+		}
+		rules.removals = append(rules.removals, removal)
+	}
+
+	switch source.Capitalize {
+	case DisciplineNameCapitalizeFirst:
+		rules.isCapitalizeFirst = true
+	case DisciplineNameCapitalizeNone, "":
+	default:
+		return nil, fmt.Errorf("unknown capitalize %q", source.Capitalize)
+	}
+
+	if len(rules.rewrites)+len(rules.replacers)+len(rules.removals) == 0 {
+		return nil, errors.New("empty rules")
+	}
+
+	return rules, nil
+}
+
+// getVersion returns hash of rules, so formatting and comments of rules file don't change version;
+// version of short and latin names algorithm is included, as they are derived from normalized name
+func (source DisciplineNameRulesSource) getVersion() string {
+	content, _ := json.Marshal(source)
+	hash := sha256.Sum256(append(content, strconv.Itoa(disciplineNameVariantsVersion)...))
+
+	return hex.EncodeToString(hash[:6])
+}
+
+func (rules *DisciplineNameRules) clear(name string) string {
+	for _, rewrite := range rules.rewrites {
+		name = rewrite.regexp.ReplaceAllString(name, rewrite.replacement)
+	}
+
+	for _, replacer := range rules.replacers {
+		name = replacer.Replace(name)
+	}
+
+	for _, removal := range rules.removals {
+		name = removal.ReplaceAllString(name, "")
+	}
+
+	name = strings.TrimSpace(name)
+	name = strings.TrimRight(name, rules.trimRight)
+	name = strings.TrimLeft(name, rules.trimLeft)
+	if rules.collapseSpaces {
+		name = removeDuplicateSpaces.ReplaceAllString(name, " ")
+	}
+
+	if rules.isCapitalizeFirst && len(name) != 0 {
+		_, size := utf8.DecodeRuneInString(name)
+		name = ukrainianToUpper.String(name[:size]) + name[size:]
+	}
+
+	return name
+}
+
+// DisciplineNameRulesReloader
+/*
+ * Reloads discipline name rules file on SIGHUP. Invalid file is only logged, previous rules are kept.
+ * After successful reload stored discipline names are re-normalized with optional renormalizer.
+ */
+type DisciplineNameRulesReloader struct {
+	logger       *slog.Logger
+	filename     string
+	signals      chan os.Signal
+	renormalizer *DisciplineNamesRenormalizer
+}
+
+func newDisciplineNameRulesReloader(
+	logger *slog.Logger, filename string, renormalizer *DisciplineNamesRenormalizer,
+) *DisciplineNameRulesReloader {
+	reloader := &DisciplineNameRulesReloader{
+		logger:       logger,
+		filename:     filename,
+		signals:      make(chan os.Signal, 1),
+		renormalizer: renormalizer,
+	}
+	signal.Notify(reloader.signals, syscall.SIGHUP)
+
+	return reloader
+}
+
+func (reloader *DisciplineNameRulesReloader) execute(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer signal.Stop(reloader.signals)
+
+	for {
+		select {
+		case <-reloader.signals:
+			reloader.reload()
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (reloader *DisciplineNameRulesReloader) reload() {
+	rules, err := loadDisciplineNameRules(reloader.filename)
+	if err == nil {
+		setDisciplineNameRules(rules)
+	}
+
+	logResult(reloader.logger, "reload discipline name rules", err, "file", reloader.filename)
+	if err == nil {
+		reloader.renormalizer.schedule()
+	}
+}
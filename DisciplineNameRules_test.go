@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDisciplineNameRules(t *testing.T) {
+	t.Run("default rules", func(t *testing.T) {
+		rules, err := loadDisciplineNameRules("")
+
+		assert.NoError(t, err)
+		assert.Len(t, rules.rewrites, 1)
+		assert.Len(t, rules.replacers, 8)
+		assert.Len(t, rules.removals, 6)
+		assert.Equal(t, "Економіка праці", rules.clear("економіка праці, 3 сем."))
+	})
+
+	t.Run("version", func(t *testing.T) {
+		rules, _ := parseDisciplineNameRules([]byte("remove: ['a']"))
+		formattedRules, _ := parseDisciplineNameRules([]byte("# comment\nremove:\n  - 'a'\n"))
+		changedRules, _ := parseDisciplineNameRules([]byte("remove: ['b']"))
+
+		assert.Len(t, rules.version, 12)
+		assert.Equal(t, rules.version, formattedRules.version)
+		assert.NotEqual(t, rules.version, changedRules.version)
+	})
+
+	t.Run("json rules", func(t *testing.T) {
+		rules, err := parseDisciplineNameRules([]byte(`{
+			"replace": [{"from": "мат.", "to": "математика"}],
+			"remove": ["\\s*\\(.*\\)"],
+			"trim": {"right": "."},
+			"collapseSpaces": true,
+			"capitalize": "none"
+		}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "вища математика", rules.clear("вища   мат. (залік)."))
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		invalidRules := map[string]string{
+			"remove: ['(']": "remove #0: error parsing regexp",
+			"rewrite: [{pattern: '[', replacement: ''}]": "rewrite #0: error parsing regexp",
+			"replace: [{from: '', to: 'a'}]":             "replace #0: empty from",
+			"remove: ['a']\ncapitalize: all":             `unknown capitalize "all"`,
+			"remove: ['a']\nunknown: true":               "field unknown not found",
+			"collapseSpaces: true":                       "empty rules",
+		}
+
+		for content, expectedError := range invalidRules {
+			_, err := parseDisciplineNameRules([]byte(content))
+			assert.ErrorContains(t, err, expectedError, content)
+		}
+	})
+
+	t.Run("load file", func(t *testing.T) {
+		rules, err := loadDisciplineNameRules("discipline-name-rules.yaml")
+		assert.NoError(t, err)
+		assert.Equal(t, "Фінанси підприємств", rules.clear("Фінанси підприємств, 5 сем., Екон. Упр."))
+
+		_, err = loadDisciplineNameRules("not-exists-rules.yaml")
+		assert.ErrorContains(t, err, "Error loading discipline name rules not-exists-rules.yaml")
+
+		filename := t.TempDir() + "/rules.yaml"
+		_ = os.WriteFile(filename, []byte("remove: ['(']"), 0644)
+		_, err = loadDisciplineNameRules(filename)
+		assert.ErrorContains(t, err, "Invalid discipline name rules "+filename)
+	})
+}
+
+func TestDisciplineNameRulesReloader(t *testing.T) {
+	defaultRules := getDisciplineNameRules()
+	defer setDisciplineNameRules(defaultRules)
+
+	filename := t.TempDir() + "/rules.yaml"
+	_ = os.WriteFile(filename, []byte("replace: [{from: 'мат.', to: 'математика'}]"), 0644)
+
+	out := &bytes.Buffer{}
+	reloader := newDisciplineNameRulesReloader(newTestLogger(out), filename, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go reloader.execute(ctx, wg)
+
+	reloader.signals <- syscall.SIGHUP
+	assert.Eventually(t, func() bool {
+		return clearDisciplineName("Вища мат.") == "Вища математика"
+	}, time.Second, time.Millisecond*10)
+
+	cancel()
+	wg.Wait()
+	assert.Contains(t, out.String(), `level=INFO msg="reload discipline name rules" file=`+filename)
+
+	// invalid file doesn't replace current rules
+	_ = os.WriteFile(filename, []byte("remove: ['(']"), 0644)
+	reloader.reload()
+	assert.Contains(t, out.String(), `level=ERROR msg="reload discipline name rules" file=`+filename)
+	assert.Equal(t, "Вища математика", clearDisciplineName("Вища мат."))
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DisciplineShortNameMaxLength is max count of characters in discipline short name, e.g. for bot button labels
+const DisciplineShortNameMaxLength = 30
+
+// disciplineNameVariantsVersion should be increased on change of short name, transliteration or search tokens
+// algorithm, as it is part of discipline name rules version and causes re-normalization of stored disciplines
+const disciplineNameVariantsVersion = 2
+
+const shortNameEllipsis = "…"
+
+// minAbbreviationLength is min count of letters kept in abbreviated word before its second syllable
+const minAbbreviationLength = 3
+
+const vowels = "аеєиіїоуюяАЕЄИІЇОУЮЯaeiouyAEIOUY"
+
+// ukrainianLatin is official Ukrainian transliteration table (Cabinet of Ministers resolution No. 55 of 27.01.2010)
+var ukrainianLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "h", 'ґ': "g", 'д': "d", 'е': "e", 'є': "ie", 'ж': "zh", 'з': "z",
+	'и': "y", 'і': "i", 'ї': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p",
+	'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ь': "", 'ю': "iu", 'я': "ia",
+}
+
+// ukrainianLatinWordStart contains letters transliterated differently at the beginning of word
+var ukrainianLatinWordStart = map[rune]string{
+	'є': "ye", 'ї': "yi", 'й': "y", 'ю': "yu", 'я': "ya",
+}
+
+var apostrophes = map[rune]bool{'ʼ': true, '\'': true, '’': true, '`': true}
+
+// getDisciplineNameFields returns discipline hash fields of name with its short and latin variants
+func getDisciplineNameFields(name string) []interface{} {
+	return []interface{}{
+		"name", name,
+		"shortName", getDisciplineShortName(name),
+		"latinName", transliterateUkrainian(name),
+	}
+}
+
+// transliterateUkrainian
+/*
+ * Transliterates Ukrainian text into Latin with official table: "є", "ї", "й", "ю", "я" are written
+ * as "ye", "yi", "y", "yu", "ya" at the beginning of word, "зг" as "zgh", soft sign and apostrophe are omitted.
+ * Capital letter is written as "Zh" before lowercase letter and as "ZH" in uppercase word. Other characters are kept.
+ */
+func transliterateUkrainian(text string) string {
+	runes := []rune(text)
+	builder := strings.Builder{}
+	builder.Grow(len(text))
+
+	for i, char := range runes {
+		if apostrophes[char] && i > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1]) {
+			continue
+		}
+
+		lower := unicode.ToLower(char)
+		latin, isUkrainian := ukrainianLatin[lower]
+		if !isUkrainian {
+			builder.WriteRune(char)
+			continue
+		}
+
+		isWordStart := i == 0 || !(unicode.IsLetter(runes[i-1]) || apostrophes[runes[i-1]])
+		if wordStartLatin, exists := ukrainianLatinWordStart[lower]; exists && isWordStart {
+			latin = wordStartLatin
+		}
+		if lower == 'г' && i > 0 && unicode.ToLower(runes[i-1]) == 'з' {
+			latin = "gh"
+		}
+
+		if unicode.IsUpper(char) && latin != "" {
+			if isUppercaseWordLetter(runes, i) {
+				latin = strings.ToUpper(latin)
+			} else {
+				latin = strings.ToUpper(latin[:1]) + latin[1:]
+			}
+		}
+		builder.WriteString(latin)
+	}
+
+	return builder.String()
+}
+
+// isUppercaseWordLetter returns true, when uppercase letter at index is a part of uppercase word like "ФЕУ"
+func isUppercaseWordLetter(runes []rune, index int) bool {
+	if index+1 < len(runes) && unicode.IsLetter(runes[index+1]) {
+		return unicode.IsUpper(runes[index+1])
+	}
+
+	return index > 0 && unicode.IsUpper(runes[index-1])
+}
+
+// getDisciplineShortName
+/*
+ * Returns name limited to DisciplineShortNameMaxLength characters. Long words are abbreviated
+ * before their second syllable ("Інформаційні технології" -> "Інф. техн."), starting with the longest word,
+ * until name fits. When abbreviated name is still too long, it is cut by word boundary with ellipsis.
+ */
+func getDisciplineShortName(name string) string {
+	if utf8.RuneCountInString(name) <= DisciplineShortNameMaxLength {
+		return name
+	}
+
+	words := strings.Fields(name)
+	length := utf8.RuneCountInString(strings.Join(words, " "))
+	for length > DisciplineShortNameMaxLength {
+		longest, abbreviation := -1, ""
+		for i, word := range words {
+			candidate := abbreviateWord(word)
+			if candidate != word && (longest == -1 || utf8.RuneCountInString(word) > utf8.RuneCountInString(words[longest])) {
+				longest, abbreviation = i, candidate
+			}
+		}
+		if longest == -1 {
+			break
+		}
+
+		length -= utf8.RuneCountInString(words[longest]) - utf8.RuneCountInString(abbreviation)
+		words[longest] = abbreviation
+	}
+
+	return truncateShortName(strings.Join(words, " "))
+}
+
+// abbreviateWord returns word cut before vowel of second syllable with dot, or word as is when it can't be shortened;
+// punctuation after word is kept: "інформації:" -> "інф.:"
+func abbreviateWord(word string) string {
+	letters := strings.TrimRight(word, ",:;")
+	punctuation := word[len(letters):]
+
+	runes := []rune(letters)
+	for _, char := range runes {
+		if !unicode.IsLetter(char) && !apostrophes[char] {
+			return word
+		}
+	}
+	// uppercase abbreviations like "ІТ" and "ФЕУ" are kept
+	if len(runes) < 2 || unicode.IsUpper(runes[1]) {
+		return word
+	}
+
+	for i := minAbbreviationLength; i < len(runes)-2; i++ {
+		if strings.ContainsRune(vowels, runes[i]) && !strings.ContainsRune(vowels, runes[i-1]) {
+			prefix := strings.TrimRightFunc(string(runes[:i]), func(char rune) bool {
+				return char == 'ь' || apostrophes[char]
+			})
+			return prefix + "." + punctuation
+		}
+	}
+
+	return word
+}
+
+func truncateShortName(name string) string {
+	runes := []rune(name)
+	if len(runes) <= DisciplineShortNameMaxLength {
+		return name
+	}
+
+	maxLength := DisciplineShortNameMaxLength - utf8.RuneCountInString(shortNameEllipsis)
+	cut := string(runes[:maxLength])
+	if wordEnd := strings.LastIndex(cut, " "); wordEnd > 0 && !unicode.IsSpace(runes[maxLength]) {
+		cut = cut[:wordEnd]
+	}
+
+	return strings.TrimRight(cut, " ,.:;-") + shortNameEllipsis
+}
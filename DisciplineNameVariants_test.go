@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTransliterateUkrainian(t *testing.T) {
+	expectMap := map[string]string{
+		"Алушта Андрій":                            "Alushta Andrii",
+		"Борщагівка Борисенко":                     "Borshchahivka Borysenko",
+		"Згорани Розгон":                           "Zghorany Rozghon",
+		"Єнакієве Гаєвич":                          "Yenakiieve Haievych",
+		"Їжакевич Кадиївка":                        "Yizhakevych Kadyivka",
+		"Йосипівка Стрий":                          "Yosypivka Stryi",
+		"Юрій Корюківка":                           "Yurii Koriukivka",
+		"Яготин Костянтин":                         "Yahotyn Kostiantyn",
+		"Знамʼянка Феодосія":                       "Znamianka Feodosiia",
+		"Компʼютерна математика":                   "Kompiuterna matematyka",
+		"Комп'ютерна математика":                   "Kompiuterna matematyka",
+		"Соціальна політика":                       "Sotsialna polityka",
+		"Щастя Шостка":                             "Shchastia Shostka",
+		"Управління ІТ-проєктами":                  "Upravlinnia IT-proiektamy",
+		"Бюджетування ФЕУ":                         "Biudzhetuvannia FEU",
+		"ЩОДЕННИК":                                 "SHCHODENNYK",
+		"Ґудзик":                                   "Gudzyk",
+		"Бухоблік з використанням 1С: Бухгалтерія": "Bukhoblik z vykorystanniam 1S: Bukhhalteriia",
+		"Smart-технології в бізнесі":               "Smart-tekhnolohii v biznesi",
+		"Операційні системи «Linux»":               "Operatsiini systemy «Linux»",
+	}
+
+	for name, expected := range expectMap {
+		assert.Equal(t, expected, transliterateUkrainian(name), name)
+	}
+}
+
+func TestGetDisciplineShortName(t *testing.T) {
+	expectMap := map[string]string{
+		"Фінанси":         "Фінанси",
+		"Економіка праці": "Економіка праці",
+		"Інформаційні технології в управлінні персоналом":                             "Інф. техн. в упр. персоналом",
+		"Комплексні системи захисту інформації: проектування, впровадження, супровід": "Компл. сист. зах. інф…",
+		"Управління ІТ-проєктами та ризиками підприємства":                            "Упр. ІТ-проєктами та риз…",
+		"Комп'ютерне моделювання економічних процесів":                                "Комп. мод. екон. процесів",
+		"Бухоблік з використанням 1С: Бухгалтерія":                                    "Бухоблік з вик. 1С: Бухг.",
+		"Маркетинг: інструменти, аналітика та стратегії розвитку":                     "Марк.: інстр., анал. та стр…",
+	}
+
+	for name, expected := range expectMap {
+		shortName := getDisciplineShortName(name)
+		assert.Equal(t, expected, shortName, name)
+		assert.LessOrEqual(t, utf8.RuneCountInString(shortName), DisciplineShortNameMaxLength, name)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// renormalizeDisciplineNameScriptSource
+/*
+ * Stores re-normalized discipline name with its short and latin variants, search index and rules version,
+ * only when origName was not changed by DisciplineWriter since it was read.
+ * Manually overridden name is kept, only rules version is written.
+ *
+ * KEYS: discipline info, discipline name overrides
+ * ARGV: origName, normalized name, rules version, discipline id, short name, latin name,
+ *       search index keys prefix, search index tokens
+ * Returns: 1 - name is changed, 0 - only rules version is changed, -1 - origName is changed and name is kept
+ */
+const renormalizeDisciplineNameScriptSource = updateSearchIndexLuaFunction + `
+if redis.call('HGET', KEYS[1], 'origName') ~= ARGV[1] then
+	return -1
+end
+
+if redis.call('HEXISTS', KEYS[2], ARGV[4]) == 1 then
+	redis.call('HSET', KEYS[1], 'nameRulesVersion', ARGV[3])
+	return 0
+end
+
+local isChanged = redis.call('HGET', KEYS[1], 'name') ~= ARGV[2]
+redis.call('HSET', KEYS[1], 'name', ARGV[2], 'shortName', ARGV[5], 'latinName', ARGV[6], 'nameRulesVersion', ARGV[3])
+updateSearchIndex(redis.call, KEYS[1], ARGV[7], ARGV[4], ARGV[8])
+if isChanged then
+	return 1
+end
+return 0
+`
+
+var renormalizeDisciplineNameScript = redis.NewScript(renormalizeDisciplineNameScriptSource)
+
+type RenormalizeResult struct {
+	disciplines int
+	outdated    int
+	renamed     int
+}
+
+// DisciplineNamesRenormalizer
+/*
+ * Applies current discipline name rules to stored disciplines "{year}:discipline:{id}", which have nameRulesVersion
+ * different from version of current rules. DisciplineWriter rewrites name only when origName is changed,
+ * so without this pass improved rules don't fix already stored names.
+ * Can be run once by renormalize command or as service job with execute: on start and after rules reload.
+ */
+type DisciplineNamesRenormalizer struct {
+	logger  *slog.Logger
+	redis   redis.UniversalClient
+	year    int
+	trigger chan struct{}
+}
+
+func newDisciplineNamesRenormalizer(logger *slog.Logger, redis redis.UniversalClient) *DisciplineNamesRenormalizer {
+	renormalizer := &DisciplineNamesRenormalizer{
+		logger:  logger,
+		redis:   redis,
+		trigger: make(chan struct{}, 1),
+	}
+	// first pass on start, as rules could be changed while service was stopped
+	renormalizer.schedule()
+
+	return renormalizer
+}
+
+// schedule requests renormalize pass; requests made while pass is waiting are merged into one
+func (renormalizer *DisciplineNamesRenormalizer) schedule() {
+	if renormalizer == nil {
+		return
+	}
+
+	select {
+	case renormalizer.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (renormalizer *DisciplineNamesRenormalizer) execute(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case <-renormalizer.trigger:
+			_, err := renormalizer.renormalize(ctx)
+			if err != nil && ctx.Err() == nil {
+				renormalizer.logger.Error("renormalize discipline names failed", "error", err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (renormalizer *DisciplineNamesRenormalizer) renormalize(ctx context.Context) (result RenormalizeResult, err error) {
+	rules := getDisciplineNameRules()
+
+	keys, err := renormalizer.collectDisciplineKeys(ctx)
+	for start := 0; start < len(keys) && err == nil; start += verifierScanCount {
+		end := min(start+verifierScanCount, len(keys))
+		var batchResult RenormalizeResult
+		batchResult, err = renormalizer.renormalizeBatch(ctx, rules, keys[start:end])
+
+		result.disciplines += batchResult.disciplines
+		result.outdated += batchResult.outdated
+		result.renamed += batchResult.renamed
+	}
+
+	disciplineNamesRenormalizedCount.Add(result.renamed)
+	logResult(
+		renormalizer.logger, "renormalize discipline names", err,
+		"version", rules.version, "disciplines", result.disciplines,
+		"outdated", result.outdated, "renamed", result.renamed,
+	)
+
+	return result, err
+}
+
+func (renormalizer *DisciplineNamesRenormalizer) collectDisciplineKeys(ctx context.Context) ([]string, error) {
+	year := "*"
+	if renormalizer.year != 0 {
+		year = strconv.Itoa(renormalizer.year)
+	}
+
+	var keys []string
+	iterator := renormalizer.redis.Scan(ctx, 0, year+":discipline:*", verifierScanCount).Iterator()
+	for iterator.Next(ctx) {
+		keys = append(keys, iterator.Val())
+	}
+
+	return keys, iterator.Err()
+}
+
+func (renormalizer *DisciplineNamesRenormalizer) renormalizeBatch(
+	ctx context.Context, rules *DisciplineNameRules, keys []string,
+) (result RenormalizeResult, err error) {
+	cmds := make([]*redis.SliceCmd, len(keys))
+	_, err = renormalizer.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.HMGet(ctx, key, "origName", "nameRulesVersion")
+		}
+		return nil
+	})
+
+	for i := 0; i < len(keys) && err == nil; i++ {
+		origName, _ := cmds[i].Val()[0].(string)
+		version, _ := cmds[i].Val()[1].(string)
+		if origName == "" {
+			continue
+		}
+
+		result.disciplines++
+		if version == rules.version {
+			continue
+		}
+
+		result.outdated++
+		name := rules.clear(origName)
+
+		// {year}:discipline:{id}
+		parts := strings.Split(keys[i], ":")
+		year, _ := strconv.Atoi(parts[0])
+		searchKeyPrefix, searchTokens := getDisciplineSearchIndexArgs(year, name)
+
+		var changed int64
+		changed, err = renormalizeDisciplineNameScript.Run(
+			ctx, renormalizer.redis, []string{keys[i], getDisciplineNameOverridesKey(year)},
+			origName, name, rules.version, parts[2], getDisciplineShortName(name), transliterateUkrainian(name),
+			searchKeyPrefix, searchTokens,
+		).Int64()
+		if err == nil && changed == 1 {
+			result.renamed++
+			renormalizer.logger.Info("discipline name renormalized", "key", keys[i], "name", name, "origName", origName)
+		}
+	}
+
+	return result, err
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDisciplineNamesRenormalizer(t *testing.T) {
+	version := getDisciplineNameRules().version
+
+	expectRenormalize := func(redisMock redismock.ClientMock) {
+		redisMock.ExpectScan(0, "2030:discipline:*", verifierScanCount).SetVal(
+			[]string{"2030:discipline:200", "2030:discipline:210", "2030:discipline:220", "2030:discipline:230"}, 0,
+		)
+		redisMock.ExpectHMGet("2030:discipline:200", "origName", "nameRulesVersion").SetVal(
+			[]interface{}{"Фінанси, 5 сем.", nil},
+		)
+		redisMock.ExpectHMGet("2030:discipline:210", "origName", "nameRulesVersion").SetVal(
+			[]interface{}{"Контролінг, 6 сем., ФЕУ", version},
+		)
+		redisMock.ExpectHMGet("2030:discipline:220", "origName", "nameRulesVersion").SetVal(
+			[]interface{}{"Маркетинг (залік)", "outdated"},
+		)
+		redisMock.ExpectHMGet("2030:discipline:230", "origName", "nameRulesVersion").SetVal([]interface{}{nil, nil})
+
+		redisMock.ExpectEvalSha(
+			renormalizeDisciplineNameScript.Hash(), []string{"2030:discipline:200", "2030:discipline_name_overrides"},
+			"Фінанси, 5 сем.", "Фінанси", version, "200", "Фінанси", "Finansy",
+			"2030:discipline_search:", "фі фін фіна фінан фінанс фінанси",
+		).SetVal(int64(1))
+		redisMock.ExpectEvalSha(
+			renormalizeDisciplineNameScript.Hash(), []string{"2030:discipline:220", "2030:discipline_name_overrides"},
+			"Маркетинг (залік)", "Маркетинг", version, "220", "Маркетинг", "Marketynh",
+			"2030:discipline_search:", "ма мар марк марке маркет маркети маркетин маркетинг",
+		).SetVal(int64(0))
+	}
+
+	t.Run("renormalize", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		expectRenormalize(redisMock)
+
+		renormalizedCountBefore := disciplineNamesRenormalizedCount.Get()
+
+		out := &bytes.Buffer{}
+		renormalizer := newDisciplineNamesRenormalizer(newTestLogger(out), redisClient)
+		renormalizer.year = 2030
+		result, err := renormalizer.renormalize(context.Background())
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, RenormalizeResult{disciplines: 3, outdated: 2, renamed: 1}, result)
+		assert.Equal(t, renormalizedCountBefore+1, disciplineNamesRenormalizedCount.Get())
+		assert.Contains(t, out.String(), `msg="discipline name renormalized" key=2030:discipline:200 name=Фінанси`)
+		assert.Contains(
+			t, out.String(),
+			`msg="renormalize discipline names" version=`+version+` disciplines=3 outdated=2 renamed=1`,
+		)
+	})
+
+	t.Run("execute on start and after schedule", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		expectRenormalize(redisMock)
+		redisMock.ExpectScan(0, "2030:discipline:*", verifierScanCount).SetVal([]string{}, 0)
+
+		out := &bytes.Buffer{}
+		renormalizer := newDisciplineNamesRenormalizer(newTestLogger(out), redisClient)
+		renormalizer.year = 2030
+
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go renormalizer.execute(ctx, wg)
+
+		assert.Eventually(t, func() bool {
+			return len(renormalizer.trigger) == 0
+		}, time.Second, time.Millisecond*10)
+		renormalizer.schedule()
+
+		assert.Eventually(t, func() bool {
+			return redisMock.ExpectationsWereMet() == nil
+		}, time.Second, time.Millisecond*10)
+		cancel()
+		wg.Wait()
+
+		assert.Contains(t, out.String(), `disciplines=0 outdated=0 renamed=0`)
+	})
+
+	t.Run("renormalize error", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectScan(0, "*:discipline:*", verifierScanCount).SetErr(assert.AnError)
+
+		out := &bytes.Buffer{}
+		renormalizer := newDisciplineNamesRenormalizer(newTestLogger(out), redisClient)
+		_, err := renormalizer.renormalize(context.Background())
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Contains(t, out.String(), `level=ERROR msg="renormalize discipline names"`)
+	})
+
+	t.Run("nil renormalizer schedule", func(t *testing.T) {
+		var renormalizer *DisciplineNamesRenormalizer
+		assert.NotPanics(t, renormalizer.schedule)
+	})
+}
+
+func TestRenormalizeDisciplineNameScript(t *testing.T) {
+	ctx := context.Background()
+	version := getDisciplineNameRules().version
+	keys := []string{"2030:discipline:200", "2030:discipline_name_overrides"}
+
+	runScript := func(redisClient *redis.Client) (int64, error) {
+		return renormalizeDisciplineNameScript.Run(
+			ctx, redisClient, keys,
+			"Фінанси, 5 сем.", "Фінанси", version, "200", "Фінанси", "Finansy",
+			"2030:discipline_search:", "фі фін фіна фінан фінанс фінанси",
+		).Int64()
+	}
+
+	t.Run("renamed", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(
+			ctx, "2030:discipline:200", "origName", "Фінанси, 5 сем.", "name", "Фінанси, 5 сем.",
+			"searchTokens", "фі фін се сем",
+		)
+		redisClient.SAdd(ctx, "2030:discipline_search:сем", "200", "210")
+		redisClient.SAdd(ctx, "2030:discipline_search:фін", "200")
+
+		changed, err := runScript(redisClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), changed)
+		assert.Equal(t, map[string]string{
+			"origName":         "Фінанси, 5 сем.",
+			"name":             "Фінанси",
+			"shortName":        "Фінанси",
+			"latinName":        "Finansy",
+			"nameRulesVersion": version,
+			"searchTokens":     "фі фін фіна фінан фінанс фінанси",
+		}, redisClient.HGetAll(ctx, "2030:discipline:200").Val())
+		assert.Equal(t, []string{"210"}, redisClient.SMembers(ctx, "2030:discipline_search:сем").Val())
+		assert.False(t, server.Exists("2030:discipline_search:се"))
+		assert.Equal(t, []string{"200"}, redisClient.SMembers(ctx, "2030:discipline_search:фін").Val())
+		assert.Equal(t, []string{"200"}, redisClient.SMembers(ctx, "2030:discipline_search:фінанси").Val())
+	})
+
+	t.Run("name is not changed", func(t *testing.T) {
+		_, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(
+			ctx, "2030:discipline:200", "origName", "Фінанси, 5 сем.", "name", "Фінанси", "nameRulesVersion", "outdated",
+		)
+
+		changed, err := runScript(redisClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), changed)
+		assert.Equal(t, version, redisClient.HGet(ctx, "2030:discipline:200", "nameRulesVersion").Val())
+		assert.Equal(t, []string{"200"}, redisClient.SMembers(ctx, "2030:discipline_search:фінанси").Val())
+	})
+
+	t.Run("name is overridden", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(
+			ctx, "2030:discipline:200", "origName", "Фінанси, 5 сем.", "name", "Фінанси та кредит",
+			"nameRulesVersion", "outdated",
+		)
+		redisClient.HSet(ctx, "2030:discipline_name_overrides", "200", "Фінанси та кредит")
+
+		changed, err := runScript(redisClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), changed)
+		assert.Equal(t, map[string]string{
+			"origName":         "Фінанси, 5 сем.",
+			"name":             "Фінанси та кредит",
+			"nameRulesVersion": version,
+		}, redisClient.HGetAll(ctx, "2030:discipline:200").Val())
+		assert.False(t, server.Exists("2030:discipline_search:фінанси"))
+	})
+
+	t.Run("origName is changed", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(
+			ctx, "2030:discipline:200", "origName", "Фінанси і кредит, 5 сем.", "name", "Фінанси і кредит",
+			"nameRulesVersion", "outdated",
+		)
+
+		changed, err := runScript(redisClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-1), changed)
+		assert.Equal(t, map[string]string{
+			"origName":         "Фінанси і кредит, 5 сем.",
+			"name":             "Фінанси і кредит",
+			"nameRulesVersion": "outdated",
+		}, redisClient.HGetAll(ctx, "2030:discipline:200").Val())
+		assert.False(t, server.Exists("2030:discipline_search:фінанси"))
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"github.com/kneu-messenger-pigeon/storage-writer/search"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+)
+
+// updateSearchIndexLuaFunction
+/*
+ * Lua function included into scripts, which write discipline name: moves discipline id from search index sets
+ * of previous tokens, stored in "searchTokens" field of discipline hash, to sets of new tokens.
+ * write is redis.call or dry-run aware write function of script.
+ *
+ * Arguments: write, discipline info key, search index keys prefix, discipline id, space separated tokens
+ */
+const updateSearchIndexLuaFunction = `
+local function updateSearchIndex(write, key, prefix, id, tokens)
+	local isNew = {}
+	for token in string.gmatch(tokens, '%S+') do
+		isNew[token] = true
+	end
+
+	local isOld = {}
+	for token in string.gmatch(redis.call('HGET', key, 'searchTokens') or '', '%S+') do
+		isOld[token] = true
+		if not isNew[token] then
+			write('SREM', prefix .. token, id)
+		end
+	end
+
+	for token in string.gmatch(tokens, '%S+') do
+		if not isOld[token] then
+			write('SADD', prefix .. token, id)
+		end
+	end
+	write('HSET', key, 'searchTokens', tokens)
+end
+`
+
+// updateDisciplineSearchIndexScriptSource
+/*
+ * Updates search index of discipline name, which is written without other scripts (e.g. manual override).
+ *
+ * KEYS: discipline info
+ * ARGV: search index keys prefix, discipline id, space separated tokens
+ */
+const updateDisciplineSearchIndexScriptSource = updateSearchIndexLuaFunction + `
+updateSearchIndex(redis.call, KEYS[1], ARGV[1], ARGV[2], ARGV[3])
+return 1
+`
+
+var updateDisciplineSearchIndexScript = redis.NewScript(updateDisciplineSearchIndexScriptSource)
+
+// getDisciplineSearchIndexArgs returns search index keys prefix and space separated index tokens of name
+func getDisciplineSearchIndexArgs(year int, name string) (string, string) {
+	return search.GetIndexKeyPrefix(year), strings.Join(search.GetIndexTokens(name), " ")
+}
+
+// updateDisciplineSearchIndex
+/*
+ * Queues update of search index of discipline name into transaction, so name and its index are written together.
+ * Script is sent with EVAL, as NOSCRIPT error of EVALSHA can't be retried inside transaction.
+ */
+func updateDisciplineSearchIndex(ctx context.Context, pipe redis.Pipeliner, year int, id uint, name string) *redis.Cmd {
+	keyPrefix, tokens := getDisciplineSearchIndexArgs(year, name)
+
+	return updateDisciplineSearchIndexScript.Eval(
+		ctx, pipe, []string{getDisciplineInfoKey(year, id)},
+		keyPrefix, strconv.FormatUint(uint64(id), 10), tokens,
+	)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"github.com/kneu-messenger-pigeon/storage-writer/search"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUpdateDisciplineSearchIndex(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stale tokens are removed", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, "2030:discipline:200", "name", "Маркетинг")
+		redisClient.HSet(ctx, "2030:discipline:210", "name", "Макроекономіка")
+
+		_, err := redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			updateDisciplineSearchIndex(ctx, pipe, 2030, 200, "Маркетинг")
+			updateDisciplineSearchIndex(ctx, pipe, 2030, 210, "Макроекономіка")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"200", "210"}, redisClient.SMembers(ctx, "2030:discipline_search:ма").Val())
+
+		_, err = redisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			updateDisciplineSearchIndex(ctx, pipe, 2030, 200, "Менеджмент")
+			return nil
+		})
+		assert.NoError(t, err)
+
+		assert.Equal(
+			t, "ме мен мене менед менедж менеджм менеджме менеджмен менеджмент",
+			redisClient.HGet(ctx, "2030:discipline:200", "searchTokens").Val(),
+		)
+		assert.False(t, server.Exists("2030:discipline_search:мар"))
+		assert.False(t, server.Exists("2030:discipline_search:маркетинг"))
+		assert.Equal(t, []string{"210"}, redisClient.SMembers(ctx, "2030:discipline_search:ма").Val())
+		assert.Equal(t, []string{"200"}, redisClient.SMembers(ctx, "2030:discipline_search:менеджмент").Val())
+
+		ids, err := search.Search(ctx, redisClient, 2030, "маркет")
+		assert.NoError(t, err)
+		assert.Empty(t, ids)
+
+		ids, err = search.Search(ctx, redisClient, 2030, "ма")
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{210}, ids)
+
+		ids, err = search.Search(ctx, redisClient, 2030, "менедж")
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{200}, ids)
+	})
+
+	t.Run("script removes all tokens of empty name", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, "2030:discipline:200", "searchTokens", "ма мар")
+		redisClient.SAdd(ctx, "2030:discipline_search:ма", "200", "210")
+		redisClient.SAdd(ctx, "2030:discipline_search:мар", "200")
+
+		err := updateDisciplineSearchIndexScript.Run(
+			ctx, redisClient, []string{"2030:discipline:200"}, "2030:discipline_search:", "200", "",
+		).Err()
+
+		assert.NoError(t, err)
+		assert.Equal(t, "", redisClient.HGet(ctx, "2030:discipline:200", "searchTokens").Val())
+		assert.Equal(t, []string{"210"}, redisClient.SMembers(ctx, "2030:discipline_search:ма").Val())
+		assert.False(t, server.Exists("2030:discipline_search:мар"))
+	})
+}
@@ -2,15 +2,58 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"github.com/kneu-messenger-pigeon/events"
 	"github.com/redis/go-redis/v9"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
-	"regexp"
-	"strings"
+	"strconv"
 )
 
+// writeDisciplineNameScriptSource
+/*
+ * Writes discipline origName with normalized name, its short and latin variants and rules version,
+ * and updates search index of name. When name of discipline is overridden manually, only origName is written.
+ * Override is checked inside Redis, so concurrently set override is not lost.
+ * Returns 1 when name is written, 0 when name is overridden.
+ *
+ * KEYS: discipline info, discipline name overrides
+ * ARGV: discipline id, origName, normalized name, short name, latin name, rules version,
+ *       search index keys prefix, search index tokens, dry-run flag (appended by DryRunRedisHook)
+ * In dry-run mode nothing is written: script returns {result, planned write commands}.
+ */
+const writeDisciplineNameScriptSource = updateSearchIndexLuaFunction + `
+local dryRun = ARGV[9] == '` + DryRunScriptFlag + `'
+local planned = {}
+local function write(...)
+	if dryRun then
+		local command = {}
+		for i, value in ipairs({...}) do
+			command[i] = tostring(value)
+		end
+		table.insert(planned, command)
+	else
+		redis.call(...)
+	end
+end
+
+local result = 1
+if redis.call('HEXISTS', KEYS[2], ARGV[1]) == 1 then
+	write('HSET', KEYS[1], 'origName', ARGV[2])
+	result = 0
+else
+	write(
+		'HSET', KEYS[1], 'name', ARGV[3], 'shortName', ARGV[4], 'latinName', ARGV[5],
+		'origName', ARGV[2], 'nameRulesVersion', ARGV[6]
+	)
+	updateSearchIndex(write, KEYS[1], ARGV[7], ARGV[1], ARGV[8])
+end
+
+if dryRun then
+	return {result, planned}
+end
+return result
+`
+
+var writeDisciplineNameScript = redis.NewScript(writeDisciplineNameScriptSource)
+
 type DisciplineWriter struct {
 	redis redis.UniversalClient
 }
@@ -30,95 +73,101 @@ func (writer *DisciplineWriter) getExpectedEventType() any {
 func (writer *DisciplineWriter) write(e interface{}) error {
 	event := e.(*events.DisciplineEvent)
 
-	key := fmt.Sprintf("%d:discipline:%d", event.Year, event.Id)
+	key := getDisciplineInfoKey(event.Year, event.Id)
 	if writer.redis.HGet(context.Background(), key, "origName").Val() != event.Name {
-		return writer.redis.HSet(
-			context.Background(), key,
-			"name", clearDisciplineName(event.Name),
-			"origName", event.Name,
+		return writeDisciplineNameScript.Run(
+			context.Background(), writer.redis,
+			getWriteDisciplineNameScriptKeys(event), getWriteDisciplineNameScriptArgs(event)...,
 		).Err()
 	}
 
 	return nil
 }
 
-var regexps = [6]*regexp.Regexp{
-	/**
-	 * Remove starting with: "Тренінг-курс(Створення власного ІТ-бізнесу)", "Тренінг-курс `Управління командами`"
-	 * https://regex101.com/r/j8BjSd/1
-	 */
-	regexp.MustCompile(`(?i)^\s*Тренінг-курс\s*\(?`),
-
-	/**
-	 * Remove Faculty shortnames: Маркетинг в агробізнесі, 5 сем., Фт маркет.
-	 * https://regex101.com/r/9RHG5U/1
-	 */
-	regexp.MustCompile(`,\s*Фт\.?\s+\p{L}{3,10}\s*($|,)`),
-
-	/**
-	 * Remove Faculty abbreviation: Контролінг, 6 сем., ФЕУ; Бюджетування на підприємстві, 6 сем., ФЕУ
-	 * https://regex101.com/r/eLjT6G/1
-	 */
-	regexp.MustCompile(`,\s*[А-ЯIЇ]{2,4}\s*($|,)`),
-
-	/**
-	 * remove ending with: ", Юр. Інст."; ", Фін.", ", Марк.", ", Інф. Інст."
-	 * https://regex101.com/r/Q1Uq1f/1
-	 */
-	regexp.MustCompile(`(?i),(\s*\p{L}{2,5}\.){1,2}\s*$`),
-
-	/**
-	 * Remove ending with: ", 3 сем.", ", 4 сем.", ", 5 сем., Юрінст", ", 5 сем., Інфінст"
-	 * https://regex101.com/r/kWUKoH/2
-	 */
-	regexp.MustCompile(`(,\s*)?[1-9-]{1,3}\s+сем\.?\s*(,\s*\p{L}{2,7})?$`),
-
-	/**
-	 * Remove parentheses: " (Туристичне країнознавство)", " (залік)", " (англомовна)"
-	 */
-	regexp.MustCompile(`\s*\([^\)]*\)`),
-}
-
-var removeDuplicateSpaces = regexp.MustCompile(`\s+`)
-
-/**
- * Normalize apostrophe: "Компʼютерна математика"
- * https://regex101.com/r/xtA6HI/1
- */
-var replaceApostrophe = regexp.MustCompile("(\\p{L})[`’'ʼ](\\p{L})")
-
-var ukrainianToUpper = cases.Upper(language.Ukrainian)
-
-var replacers = [8]*strings.Replacer{
-	strings.NewReplacer("`", ""),
-	strings.NewReplacer("\\", ""),
-	strings.NewReplacer("1 С:", "1С:"),
-	strings.NewReplacer("іноз мова", "іноземна мова"),
-	strings.NewReplacer("_", " "),
-	strings.NewReplacer("+", " "),
-	strings.NewReplacer("~", " "),
-	strings.NewReplacer("*", " "),
-}
+// writeBatch reads stored origName of all disciplines with one pipeline and rewrites changed ones with another
+func (writer *DisciplineWriter) writeBatch(e []any) error {
+	ctx := context.Background()
+	origNameCmds := make([]*redis.SliceCmd, len(e))
+	_, err := writer.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, event := range e {
+			disciplineEvent := event.(*events.DisciplineEvent)
+			origNameCmds[i] = pipe.HMGet(ctx, getDisciplineInfoKey(disciplineEvent.Year, disciplineEvent.Id), "origName")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-func clearDisciplineName(name string) string {
-	name = replaceApostrophe.ReplaceAllString(name, "$1\\ʼ$2")
+	storedOrigNames := make(map[string]string, len(e))
+	lastEvents := make(map[string]*events.DisciplineEvent, len(e))
+	var keys []string
+	for i, event := range e {
+		disciplineEvent := event.(*events.DisciplineEvent)
+		key := getDisciplineInfoKey(disciplineEvent.Year, disciplineEvent.Id)
+		if _, exists := storedOrigNames[key]; !exists {
+			storedOrigNames[key], _ = origNameCmds[i].Val()[0].(string)
+			keys = append(keys, key)
+		}
+		// the last event for discipline wins, like with sequential writes
+		lastEvents[key] = disciplineEvent
+	}
 
-	for _, replacer := range replacers {
-		name = replacer.Replace(name)
+	var cmds []*redis.Cmd
+	runPipeline := func() error {
+		cmds = cmds[:0]
+		_, err := writer.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, key := range keys {
+				if lastEvents[key].Name != storedOrigNames[key] {
+					cmds = append(cmds, writeDisciplineNameScript.EvalSha(
+						ctx, pipe,
+						getWriteDisciplineNameScriptKeys(lastEvents[key]),
+						getWriteDisciplineNameScriptArgs(lastEvents[key])...,
+					))
+				}
+			}
+			return nil
+		})
+		return err
 	}
 
-	for i := 0; i < len(regexps); i++ {
-		name = regexps[i].ReplaceAllString(name, "")
+	err = runPipeline()
+	// script is not cached by Redis (e.g. after restart), so none of the calls were executed
+	if len(cmds) != 0 && redis.HasErrorPrefix(cmds[0].Err(), "NOSCRIPT") {
+		err = writeDisciplineNameScript.Load(ctx, writer.redis).Err()
+		if err == nil {
+			err = runPipeline()
+		}
 	}
 
-	name = strings.TrimSpace(name)
-	name = strings.TrimRight(name, "_-`.123  #&$/»)")
-	name = strings.TrimLeft(name, "_-`.  #&$«(")
-	name = removeDuplicateSpaces.ReplaceAllString(name, " ")
+	return err
+}
+
+func getWriteDisciplineNameScriptKeys(event *events.DisciplineEvent) []string {
+	return []string{
+		getDisciplineInfoKey(event.Year, event.Id),
+		getDisciplineNameOverridesKey(event.Year),
+	}
+}
 
-	if len(name) != 0 {
-		name = ukrainianToUpper.String(name[:1]) + name[1:]
+func getWriteDisciplineNameScriptArgs(event *events.DisciplineEvent) []interface{} {
+	rules := getDisciplineNameRules()
+	name := rules.clear(event.Name)
+	searchKeyPrefix, searchTokens := getDisciplineSearchIndexArgs(event.Year, name)
+
+	return []interface{}{
+		strconv.FormatUint(uint64(event.Id), 10),
+		event.Name,
+		name,
+		getDisciplineShortName(name),
+		transliterateUkrainian(name),
+		rules.version,
+		searchKeyPrefix,
+		searchTokens,
 	}
+}
 
-	return name
+// clearDisciplineName normalizes discipline name with current discipline name rules
+func clearDisciplineName(name string) string {
+	return getDisciplineNameRules().clear(name)
 }
@@ -20,7 +20,11 @@ func TestWriteDiscipline(t *testing.T) {
 		redis, redisMock := redismock.NewClientMock()
 
 		redisMock.ExpectHGet("2045:discipline:200", "origName").RedisNil()
-		redisMock.ExpectHSet("2045:discipline:200", "name", "Фінанси", "origName", event.Name).SetVal(2)
+		redisMock.ExpectEvalSha(
+			writeDisciplineNameScript.Hash(), []string{"2045:discipline:200", "2045:discipline_name_overrides"},
+			"200", event.Name, "Фінанси", "Фінанси", "Finansy", getDisciplineNameRules().version,
+			"2045:discipline_search:", "фі фін фіна фінан фінанс фінанси",
+		).SetVal(int64(1))
 
 		disciplineWriter := DisciplineWriter{}
 		disciplineWriter.setRedis(redis)
@@ -59,6 +63,77 @@ func TestWriteDiscipline(t *testing.T) {
 	})
 }
 
+func TestWriteDisciplinesBatch(t *testing.T) {
+	newEvent := events.DisciplineEvent{
+		Year: 2045,
+		Discipline: events.Discipline{
+			Id:   200,
+			Name: "Фінанси (модуль 1 Гроші та кредит, модуль 2 Фінанси)",
+		},
+	}
+
+	existsEvent := events.DisciplineEvent{
+		Year: 2045,
+		Discipline: events.Discipline{
+			Id:   210,
+			Name: "Контролінг, 6 сем., ФЕУ",
+		},
+	}
+
+	renamedEvent := events.DisciplineEvent{
+		Year: 2045,
+		Discipline: events.Discipline{
+			Id:   200,
+			Name: "Фінанси підприємств, 5 сем., Екон. Упр.",
+		},
+	}
+
+	expectReadOrigNames := func(redisMock redismock.ClientMock) {
+		redisMock.ExpectHMGet("2045:discipline:200", "origName").SetVal([]interface{}{nil})
+		redisMock.ExpectHMGet("2045:discipline:210", "origName").SetVal([]interface{}{existsEvent.Name})
+		redisMock.ExpectHMGet("2045:discipline:200", "origName").SetVal([]interface{}{nil})
+	}
+
+	expectWriteName := func(redisMock redismock.ClientMock) *redismock.ExpectedCmd {
+		return redisMock.ExpectEvalSha(
+			writeDisciplineNameScript.Hash(), []string{"2045:discipline:200", "2045:discipline_name_overrides"},
+			"200", renamedEvent.Name, "Фінанси підприємств", "Фінанси підприємств", "Finansy pidpryiemstv",
+			getDisciplineNameRules().version, "2045:discipline_search:",
+			"фі фін фіна фінан фінанс фінанси пі під підп підпр підпри підприє підприєм підприємс підприємст підприємств",
+		)
+	}
+
+	t.Run("write batch", func(t *testing.T) {
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
+		expectReadOrigNames(redisMock)
+		expectWriteName(redisMock).SetVal(int64(1))
+
+		disciplineWriter := DisciplineWriter{}
+		disciplineWriter.setRedis(redis)
+		err := disciplineWriter.writeBatch([]any{&newEvent, &existsEvent, &renamedEvent})
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("script is not loaded", func(t *testing.T) {
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
+		expectReadOrigNames(redisMock)
+		expectWriteName(redisMock).SetErr(noScriptError{})
+		redisMock.ExpectScriptLoad(writeDisciplineNameScriptSource).SetVal(writeDisciplineNameScript.Hash())
+		expectWriteName(redisMock).SetVal(int64(0))
+
+		disciplineWriter := DisciplineWriter{}
+		disciplineWriter.setRedis(redis)
+		err := disciplineWriter.writeBatch([]any{&newEvent, &existsEvent, &renamedEvent})
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+}
+
 func TestClearDisciplineName(t *testing.T) {
 	expectMap := map[string]string{
 		"Фінанси (модуль 1 Гроші та кредит, модуль 2 Фінанси)":                         "Фінанси",
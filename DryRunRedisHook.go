@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+// DryRunScriptFlag is appended to ARGV of dry-run aware scripts; such script returns {result, planned write commands}
+const DryRunScriptFlag = "dry-run"
+
+// dryRunAwareScripts contains SHA1 of scripts, which support DryRunScriptFlag
+var dryRunAwareScripts = map[string]bool{
+	getScriptHash(writeScoreScriptSource):          true,
+	getScriptHash(deleteLessonScoresScriptSource):  true,
+	getScriptHash(writeDisciplineNameScriptSource): true,
+}
+
+// DryRunRedisHook
+/*
+ * Recording Redis command layer for dry-run mode: read-only and control commands are executed as usual,
+ * any other command is logged as planned and not sent to Redis (its command has empty result).
+ * GETSET is replaced with GET, so writer sees real previous value.
+ * Dry-run aware scripts are called with DryRunScriptFlag and planned commands from their reply are logged.
+ */
+type DryRunRedisHook struct {
+	logger *slog.Logger
+}
+
+func newDryRunRedisHook(logger *slog.Logger) *DryRunRedisHook {
+	return &DryRunRedisHook{logger: logger}
+}
+
+func (hook *DryRunRedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (hook *DryRunRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		executed, isExecuted := hook.prepare(ctx, cmd)
+		if !isExecuted {
+			return cmd.Err()
+		}
+
+		err := next(ctx, executed)
+		hook.complete(cmd, executed)
+		if executed != cmd {
+			return cmd.Err()
+		}
+		return err
+	}
+}
+
+func (hook *DryRunRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		originals := make([]redis.Cmder, 0, len(cmds))
+		executedCmds := make([]redis.Cmder, 0, len(cmds))
+		for _, cmd := range cmds {
+			if executed, isExecuted := hook.prepare(ctx, cmd); isExecuted {
+				originals = append(originals, cmd)
+				executedCmds = append(executedCmds, executed)
+			}
+		}
+
+		if len(executedCmds) == 0 {
+			return nil
+		}
+
+		err := next(ctx, executedCmds)
+		for i := range executedCmds {
+			hook.complete(originals[i], executedCmds[i])
+		}
+		return err
+	}
+}
+
+// prepare returns command to execute instead of cmd; false when cmd is mutation and should not be executed
+func (hook *DryRunRedisHook) prepare(ctx context.Context, cmd redis.Cmder) (redis.Cmder, bool) {
+	args := cmd.Args()
+	switch name := cmd.Name(); {
+	case !isRedisMutationCommand(name):
+		return cmd, true
+
+	case name == "getset":
+		hook.logPlanned("", stringifyArgs(args))
+		return redis.NewStringCmd(ctx, "get", args[1]), true
+
+	case name == "eval" || name == "evalsha":
+		scriptHash := getCmdScriptHash(cmd)
+		if !dryRunAwareScripts[scriptHash] {
+			cmd.SetErr(fmt.Errorf("dry-run: script %s is not dry-run aware", scriptHash))
+			return nil, false
+		}
+
+		dryRunArgs := make([]interface{}, len(args), len(args)+1)
+		copy(dryRunArgs, args)
+		return redis.NewCmd(ctx, append(dryRunArgs, DryRunScriptFlag)...), true
+	}
+
+	hook.logPlanned("", stringifyArgs(args))
+	return nil, false
+}
+
+// complete copies result of dry-run script call into original command and logs planned commands
+func (hook *DryRunRedisHook) complete(original redis.Cmder, executed redis.Cmder) {
+	if original == executed {
+		return
+	}
+
+	switch originalCmd := original.(type) {
+	case *redis.StringCmd:
+		executedCmd := executed.(*redis.StringCmd)
+		originalCmd.SetVal(executedCmd.Val())
+		originalCmd.SetErr(executedCmd.Err())
+
+	case *redis.Cmd:
+		reply, err := executed.(*redis.Cmd).Slice()
+		if err == nil && len(reply) != 2 {
+			err = errors.New("dry-run: unexpected script reply")
+		}
+		if err != nil {
+			originalCmd.SetErr(err)
+			return
+		}
+
+		planned, _ := reply[1].([]interface{})
+		scriptHash := getCmdScriptHash(executed)
+		for _, command := range planned {
+			commandArgs, _ := command.([]interface{})
+			hook.logPlanned(scriptHash, stringifyArgs(commandArgs))
+		}
+
+		if reply[0] == nil {
+			originalCmd.SetErr(redis.Nil)
+		} else {
+			originalCmd.SetVal(reply[0])
+		}
+	}
+}
+
+func (hook *DryRunRedisHook) logPlanned(script string, args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	attrs := []any{"command", strings.ToUpper(args[0])}
+	if len(args) > 1 {
+		attrs = append(attrs, "key", args[1])
+	}
+	if len(args) > 2 {
+		attrs = append(attrs, "args", args[2:])
+	}
+	if script != "" {
+		attrs = append(attrs, "script", script)
+	}
+
+	hook.logger.Info("dry-run: planned redis command", attrs...)
+}
+
+func stringifyArgs(args []interface{}) []string {
+	stringArgs := make([]string, len(args))
+	for i, arg := range args {
+		stringArgs[i] = fmt.Sprint(arg)
+	}
+
+	return stringArgs
+}
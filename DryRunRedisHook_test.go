@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDryRunRedisHook(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("mutation is logged and not executed", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		hook := newDryRunRedisHook(newTestLogger(out))
+
+		var executed []redis.Cmder
+		process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+			executed = append(executed, cmd)
+			return nil
+		})
+
+		cmd := redis.NewIntCmd(ctx, "hset", "2030:1:lessons:15", "150", "payload")
+		assert.NoError(t, process(ctx, cmd))
+		assert.NoError(t, cmd.Err())
+
+		readCmd := redis.NewStringCmd(ctx, "hget", "2030:1:lessons:15", "150")
+		assert.NoError(t, process(ctx, readCmd))
+
+		pingCmd := redis.NewStatusCmd(ctx, "ping")
+		assert.NoError(t, process(ctx, pingCmd))
+
+		// command, which is not known as read-only, is not executed
+		unknownCmd := redis.NewIntCmd(ctx, "lpush", "2030:queue", "payload")
+		assert.NoError(t, process(ctx, unknownCmd))
+
+		assert.Equal(t, []redis.Cmder{readCmd, pingCmd}, executed)
+		assert.Contains(
+			t, out.String(),
+			`msg="dry-run: planned redis command" command=HSET key=2030:1:lessons:15 args="[150 payload]"`,
+		)
+		assert.Contains(t, out.String(), `command=LPUSH key=2030:queue args=[payload]`)
+	})
+
+	t.Run("getset is replaced with get", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		hook := newDryRunRedisHook(newTestLogger(out))
+
+		process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+			assert.Equal(t, []interface{}{"get", "lessonTypes"}, cmd.Args())
+			cmd.(*redis.StringCmd).SetVal("previous")
+			return nil
+		})
+
+		cmd := redis.NewStringCmd(ctx, "getset", "lessonTypes", "new")
+		assert.NoError(t, process(ctx, cmd))
+		assert.Equal(t, "previous", cmd.Val())
+		assert.Contains(t, out.String(), `command=GETSET key=lessonTypes args=[new]`)
+	})
+
+	t.Run("dry-run aware script in pipeline", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		hook := newDryRunRedisHook(newTestLogger(out))
+		scriptHash := getScriptHash(writeScoreScriptSource)
+
+		var executedArgs [][]interface{}
+		processPipeline := hook.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+			for _, cmd := range cmds {
+				executedArgs = append(executedArgs, cmd.Args())
+			}
+			cmds[0].(*redis.Cmd).SetVal([]interface{}{
+				"2.5",
+				[]interface{}{
+					[]interface{}{"HSET", "2030:1:scores:5:15", "150:1", "3"},
+					[]interface{}{"ZINCRBY", "2030:1:totals:15", "0.5", "5"},
+				},
+			})
+			cmds[1].(*redis.Cmd).SetVal([]interface{}{nil, []interface{}{}})
+			return nil
+		})
+
+		changedCmd := redis.NewCmd(ctx, "evalsha", scriptHash, "1", "key", "arg")
+		unchangedCmd := redis.NewCmd(ctx, "eval", writeScoreScriptSource, "1", "key", "arg")
+		mutationCmd := redis.NewIntCmd(ctx, "del", "key")
+
+		assert.NoError(t, processPipeline(ctx, []redis.Cmder{changedCmd, mutationCmd, unchangedCmd}))
+
+		assert.Equal(t, [][]interface{}{
+			{"evalsha", scriptHash, "1", "key", "arg", DryRunScriptFlag},
+			{"eval", writeScoreScriptSource, "1", "key", "arg", DryRunScriptFlag},
+		}, executedArgs)
+		assert.Equal(t, []interface{}{"evalsha", scriptHash, "1", "key", "arg"}, changedCmd.Args())
+
+		assert.Equal(t, "2.5", changedCmd.Val())
+		assert.Equal(t, redis.Nil, unchangedCmd.Err())
+
+		assert.Contains(t, out.String(), `command=HSET key=2030:1:scores:5:15 args="[150:1 3]" script=`+scriptHash)
+		assert.Contains(t, out.String(), `command=ZINCRBY key=2030:1:totals:15 args="[0.5 5]" script=`+scriptHash)
+		assert.Contains(t, out.String(), `command=DEL key=key`)
+	})
+
+	t.Run("script error", func(t *testing.T) {
+		hook := newDryRunRedisHook(newTestLogger(&bytes.Buffer{}))
+		expectedError := noScriptError{}
+
+		process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+			cmd.SetErr(expectedError)
+			return expectedError
+		})
+
+		cmd := redis.NewCmd(ctx, "evalsha", getScriptHash(writeScoreScriptSource), "0")
+		assert.Equal(t, expectedError, process(ctx, cmd))
+		assert.True(t, redis.HasErrorPrefix(cmd.Err(), "NOSCRIPT"))
+	})
+
+	t.Run("unknown script is not executed", func(t *testing.T) {
+		hook := newDryRunRedisHook(newTestLogger(&bytes.Buffer{}))
+		process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+			t.Fatal("should not be executed")
+			return nil
+		})
+
+		cmd := redis.NewCmd(ctx, "eval", "return redis.call('DEL', KEYS[1])", "1", "key")
+		err := process(ctx, cmd)
+		assert.ErrorContains(t, err, "is not dry-run aware")
+		assert.Equal(t, err, cmd.Err())
+		assert.False(t, errors.Is(err, redis.Nil))
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"time"
+)
+
+const DefaultReadinessThreshold = time.Minute * 2
+
+const RedisPingTimeout = time.Second * 2
+
+// HealthServer
+/*
+ * Serves liveness (/healthz), readiness (/readyz), detailed JSON status (/status)
+ * and Prometheus metrics (/metrics) endpoints.
+ * Service is ready when Redis responds to ping, every connector fetched recently
+ * and scores changes feed writer is not failing longer than readiness threshold.
+ */
+type HealthServer struct {
+	redis                   redis.UniversalClient
+	connectors              []*ConnectorStatus
+	scoresChangesFeedHealth *componentHealth
+	readinessThreshold      time.Duration
+}
+
+type RedisStatus struct {
+	Ready bool   `json:"ready"`
+	Error string `json:"error,omitempty"`
+}
+
+type ScoresChangesFeedStatus struct {
+	componentHealthSnapshot
+	Ready bool `json:"ready"`
+}
+
+type ServiceStatus struct {
+	Ready             bool                      `json:"ready"`
+	Redis             RedisStatus               `json:"redis"`
+	Connectors        []ConnectorStatusSnapshot `json:"connectors"`
+	ScoresChangesFeed ScoresChangesFeedStatus   `json:"scoresChangesFeed"`
+}
+
+func (server *HealthServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", server.handleHealthz)
+	mux.HandleFunc("/readyz", server.handleReadyz)
+	mux.HandleFunc("/status", server.handleStatus)
+	mux.HandleFunc("/metrics", server.handleMetrics)
+
+	return mux
+}
+
+func (server *HealthServer) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (server *HealthServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !server.getStatus(r.Context()).Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("not ready"))
+		return
+	}
+
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (server *HealthServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := server.getStatus(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+func (server *HealthServer) handleMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.WritePrometheus(w, true)
+}
+
+func (server *HealthServer) getStatus(ctx context.Context) ServiceStatus {
+	now := time.Now()
+	threshold := server.readinessThreshold
+	if threshold <= 0 {
+		threshold = DefaultReadinessThreshold
+	}
+
+	status := ServiceStatus{
+		Redis:      server.pingRedis(ctx),
+		Connectors: make([]ConnectorStatusSnapshot, len(server.connectors)),
+	}
+	status.Ready = status.Redis.Ready
+
+	for i, connector := range server.connectors {
+		status.Connectors[i] = connector.snapshot(now, threshold)
+		status.Ready = status.Ready && status.Connectors[i].Ready
+	}
+
+	if server.scoresChangesFeedHealth != nil {
+		status.ScoresChangesFeed.componentHealthSnapshot, status.ScoresChangesFeed.Ready =
+			server.scoresChangesFeedHealth.snapshot(now, threshold)
+	} else {
+		status.ScoresChangesFeed.Ready = true
+	}
+	status.Ready = status.Ready && status.ScoresChangesFeed.Ready
+
+	return status
+}
+
+func (server *HealthServer) pingRedis(ctx context.Context) RedisStatus {
+	ctx, cancel := context.WithTimeout(ctx, RedisPingTimeout)
+	defer cancel()
+
+	err := server.redis.Ping(ctx).Err()
+	if err != nil {
+		return RedisStatus{Error: err.Error()}
+	}
+
+	return RedisStatus{Ready: true}
+}
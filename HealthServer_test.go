@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthServer(t *testing.T) {
+	newReadyConnectorStatus := func(name string) *ConnectorStatus {
+		status := newConnectorStatus(name, "topic", nil)
+		status.started()
+		status.fetchStarted()
+		return status
+	}
+
+	request := func(server *HealthServer, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		server.handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("healthz", func(t *testing.T) {
+		redis, redisMock := redismock.NewClientMock()
+		server := &HealthServer{redis: redis}
+
+		response := request(server, "/healthz")
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "ok", response.Body.String())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		redis, _ := redismock.NewClientMock()
+		server := &HealthServer{redis: redis}
+		newConnectorMetrics("test-metrics-endpoint#0", TopologyScoresWriter).fetched(3)
+
+		response := request(server, "/metrics")
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Contains(
+			t, response.Body.String(),
+			`connector__fetched_messages_count{connector="test-metrics-endpoint#0",writer="scores"} 3`,
+		)
+		assert.Contains(t, response.Body.String(), `scores_changes_feed__queue_length{queue="waiting"}`)
+	})
+
+	t.Run("readyz ok", func(t *testing.T) {
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectPing().SetVal("PONG")
+
+		server := &HealthServer{
+			redis:                   redis,
+			connectors:              []*ConnectorStatus{newReadyConnectorStatus("scores#0")},
+			scoresChangesFeedHealth: &componentHealth{},
+		}
+
+		response := request(server, "/readyz")
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		assert.Equal(t, "ok", response.Body.String())
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("readyz redis error", func(t *testing.T) {
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectPing().SetErr(errors.New("connection refused"))
+
+		server := &HealthServer{
+			redis:      redis,
+			connectors: []*ConnectorStatus{newReadyConnectorStatus("scores#0")},
+		}
+
+		response := request(server, "/readyz")
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("readyz connector not started", func(t *testing.T) {
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectPing().SetVal("PONG")
+
+		server := &HealthServer{
+			redis: redis,
+			connectors: []*ConnectorStatus{
+				newReadyConnectorStatus("scores#0"),
+				newConnectorStatus("lessons#0", "lessons", nil),
+			},
+		}
+
+		response := request(server, "/readyz")
+
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("status with failing scores changes feed", func(t *testing.T) {
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectPing().SetVal("PONG")
+
+		feedHealth := &componentHealth{}
+		feedHealth.failed(errors.New("kafka is not available"))
+		feedHealth.failingSince = time.Now().Add(-time.Minute)
+
+		connector := newReadyConnectorStatus("scores#0")
+		connector.setPendingCommit(5)
+
+		server := &HealthServer{
+			redis:                   redis,
+			connectors:              []*ConnectorStatus{connector},
+			scoresChangesFeedHealth: feedHealth,
+			readinessThreshold:      time.Second * 30,
+		}
+
+		response := request(server, "/status")
+		assert.Equal(t, http.StatusServiceUnavailable, response.Code)
+		assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+
+		status := ServiceStatus{}
+		assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &status))
+
+		assert.False(t, status.Ready)
+		assert.True(t, status.Redis.Ready)
+		assert.False(t, status.ScoresChangesFeed.Ready)
+		assert.Equal(t, "kafka is not available", status.ScoresChangesFeed.LastError)
+		if assert.Len(t, status.Connectors, 1) {
+			assert.True(t, status.Connectors[0].Ready)
+			assert.Equal(t, "scores#0", status.Connectors[0].Name)
+			assert.Equal(t, 5, status.Connectors[0].PendingCommit)
+		}
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+}
@@ -4,11 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/kneu-messenger-pigeon/events"
 	"github.com/redis/go-redis/v9"
 	"github.com/segmentio/kafka-go"
-	"io"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -18,17 +17,26 @@ type ConnectorInterface interface {
 }
 
 type KafkaToRedisConnector struct {
-	out    io.Writer
-	reader events.ReaderInterface
-	redis  redis.UniversalClient
-	writer WriterInterface
+	logger           *slog.Logger
+	reader           events.ReaderInterface
+	redis            redis.UniversalClient
+	writer           WriterInterface
+	deadLetterWriter DeadLetterWriterInterface
+	status           *ConnectorStatus
+	metrics          *ConnectorMetrics
 }
 
 const RedisBackgroundSaveInProgress = "ERR Background save already in progress"
 
+// MaxBatchSize limits count of messages passed into BatchWriterInterface.writeBatch at once
+const MaxBatchSize = 500
+
+// BatchFetchWait is how long to wait for next message of burst before writing already fetched ones
+const BatchFetchWait = time.Millisecond * 10
+
 func (connector *KafkaToRedisConnector) execute(ctx context.Context, wg *sync.WaitGroup) {
 	var err error
-	var message kafka.Message
+	var messages []kafka.Message
 	var messagesToCommit []kafka.Message
 	var lastWriteTimestamp int64
 	var fetchContext = ctx
@@ -42,49 +50,219 @@ func (connector *KafkaToRedisConnector) execute(ctx context.Context, wg *sync.Wa
 		return
 	}
 
-	fmt.Fprintf(connector.out, "%T connector started \n", connector.writer)
+	logger := connector.getLogger()
+	logger.Info("connector started")
+	connector.status.started()
 
 	for ctx.Err() == nil {
-		message, err = connector.reader.FetchMessage(fetchContext)
-		if err == nil && expectedMessageKey == string(message.Key) {
-			event := connector.writer.getExpectedEventType()
-			err = json.Unmarshal(message.Value, &event)
-			if err == nil {
-				err = connector.writer.write(event)
+		var writtenMessages []kafka.Message
+		var failedMessage kafka.Message
+		var errorArgs []any
+		messages, err = connector.fetchMessages(fetchContext)
+		if err == nil {
+			connector.metrics.fetched(len(messages))
+			writeStartedAt := time.Now()
+			writtenMessages, failedMessage, err = connector.writeMessages(ctx, messages)
+			connector.metrics.writeFinished(writeStartedAt)
+			if err != nil {
+				errorArgs = messageLogArgs(failedMessage)
+				if len(messages) > 1 {
+					errorArgs = append(errorArgs, batchLogArgs(messages)...)
+				}
 			}
 		}
-		if err == nil {
+		if len(writtenMessages) != 0 {
 			if len(messagesToCommit) == 0 {
 				fetchContext, fetchContextCancel = context.WithTimeout(ctx, time.Second*60)
 			}
 
-			messagesToCommit = append(messagesToCommit, message)
+			messagesToCommit = append(messagesToCommit, writtenMessages...)
 			lastWriteTimestamp = time.Now().Unix()
 		}
 
-		if len(messagesToCommit) != 0 && (len(messagesToCommit) >= 5000 || fetchContext.Err() != nil) {
+		// on shutdown pending messages are committed by drain below
+		if len(messagesToCommit) != 0 && ctx.Err() == nil && (len(messagesToCommit) >= 5000 || fetchContext.Err() != nil) {
 			// revert context with time to usual
 			fetchContextCancel()
 			fetchContext = ctx
-			err = connector.saveRedisIfLastSaveOlderThan(lastWriteTimestamp)
-			if err == nil {
-				err = connector.reader.CommitMessages(context.Background(), messagesToCommit...)
-			}
-			fmt.Fprintf(connector.out, "%T Commit %d messages (err: %v) \n", connector.writer, len(messagesToCommit), err)
+			err = connector.commit(messagesToCommit, lastWriteTimestamp)
+			logResult(logger, "commit messages", err, "count", len(messagesToCommit))
 			if err == nil {
 				messagesToCommit = []kafka.Message{}
+			} else {
+				errorArgs = batchLogArgs(messagesToCommit)
 			}
 		}
 
 		if err != nil && !errors.Is(err, context.Canceled) {
-			fmt.Fprintf(connector.out, "%T error: %v \n", connector.writer, err)
+			logger.Error("connector error", append(errorArgs, "error", err)...)
+			connector.status.failed(err)
+		} else if err == nil {
+			connector.status.succeeded()
 		}
+		connector.status.setPendingCommit(len(messagesToCommit))
 	}
 	fetchContextCancel()
 
+	if len(messagesToCommit) != 0 {
+		err = connector.commit(messagesToCommit, lastWriteTimestamp)
+		logResult(logger, "drain: commit messages", err, "count", len(messagesToCommit))
+		if err == nil {
+			connector.status.setPendingCommit(0)
+		}
+	}
+
+	connector.status.stopped()
 	wg.Done()
 }
 
+func (connector *KafkaToRedisConnector) commit(messages []kafka.Message, lastWriteTimestamp int64) error {
+	err := connector.saveRedisIfLastSaveOlderThan(lastWriteTimestamp)
+	if err == nil {
+		err = connector.reader.CommitMessages(context.Background(), messages...)
+	}
+	if err == nil {
+		connector.metrics.committed(len(messages))
+	}
+
+	return err
+}
+
+// fetchMessages
+/*
+ * Waits for one message. For batch writers also takes all messages available right after it (fetch burst),
+ * up to MaxBatchSize, so they could be written with one pipelined batch.
+ */
+func (connector *KafkaToRedisConnector) fetchMessages(ctx context.Context) ([]kafka.Message, error) {
+	connector.status.fetchStarted()
+	message, err := connector.reader.FetchMessage(ctx)
+	if err != nil {
+		connector.status.fetched(nil)
+		return nil, err
+	}
+
+	messages := []kafka.Message{message}
+	if _, isBatchWriter := connector.writer.(BatchWriterInterface); !isBatchWriter {
+		connector.status.fetched(messages)
+		return messages, nil
+	}
+
+	burstContext, burstContextCancel := context.WithTimeout(ctx, BatchFetchWait)
+	defer burstContextCancel()
+	for len(messages) < MaxBatchSize {
+		message, err = connector.reader.FetchMessage(burstContext)
+		if err != nil {
+			break
+		}
+		messages = append(messages, message)
+	}
+
+	connector.status.fetched(messages)
+	return messages, nil
+}
+
+// writeMessages returns successfully written (or dead-lettered) messages, the first failed message and its error
+func (connector *KafkaToRedisConnector) writeMessages(
+	ctx context.Context, messages []kafka.Message,
+) (written []kafka.Message, failed kafka.Message, err error) {
+	if batchWriter, isBatchWriter := connector.writer.(BatchWriterInterface); isBatchWriter && len(messages) > 1 {
+		messages, written = connector.writeBatch(batchWriter, messages)
+	}
+
+	for _, message := range messages {
+		handleErr, messageErr := writeWithDeadLetter(
+			ctx, connector.getLogger(), connector.deadLetterWriter, message, connector.handleMessage,
+		)
+		if handleErr != nil || messageErr != nil {
+			connector.metrics.failed(1)
+		}
+		if handleErr != nil && messageErr == nil {
+			connector.getLogger().Warn(
+				"message moved to dead-letter topic", append(messageLogArgs(message), "error", handleErr)...,
+			)
+		}
+
+		if messageErr != nil {
+			// commit is offset based, so later messages of the partition must not be committed before failed one
+			return writtenBefore(written, message), message, messageErr
+		}
+		written = append(written, message)
+	}
+
+	return written, kafka.Message{}, nil
+}
+
+// writtenBefore returns written messages except ones of failed message partition with later offset
+func writtenBefore(written []kafka.Message, failed kafka.Message) []kafka.Message {
+	result := make([]kafka.Message, 0, len(written))
+	for _, message := range written {
+		if message.Partition != failed.Partition || message.Offset < failed.Offset {
+			result = append(result, message)
+		}
+	}
+
+	return result
+}
+
+// writeBatch returns written messages and remaining ones, which should be written one by one
+func (connector *KafkaToRedisConnector) writeBatch(batchWriter BatchWriterInterface, messages []kafka.Message) (remaining []kafka.Message, written []kafka.Message) {
+	expectedMessageKey := connector.writer.getExpectedMessageKey()
+	batchEvents := make([]any, 0, len(messages))
+	batchMessages := make([]kafka.Message, 0, len(messages))
+
+	for _, message := range messages {
+		if expectedMessageKey != string(message.Key) {
+			connector.metrics.skipped(1)
+			written = append(written, message)
+			continue
+		}
+
+		event := connector.writer.getExpectedEventType()
+		if json.Unmarshal(message.Value, &event) != nil {
+			remaining = append(remaining, message)
+			continue
+		}
+
+		batchEvents = append(batchEvents, event)
+		batchMessages = append(batchMessages, message)
+	}
+
+	if len(batchEvents) == 0 {
+		return remaining, written
+	}
+
+	err := batchWriter.writeBatch(batchEvents)
+	if err != nil {
+		connector.getLogger().Warn("batch write failed, write one by one", "count", len(batchEvents), "error", err)
+		return append(remaining, batchMessages...), written
+	}
+
+	connector.metrics.written(len(batchMessages))
+	return remaining, append(written, batchMessages...)
+}
+
+func (connector *KafkaToRedisConnector) handleMessage(message kafka.Message) (err error) {
+	if connector.writer.getExpectedMessageKey() != string(message.Key) {
+		connector.metrics.skipped(1)
+		return nil
+	}
+
+	event := connector.writer.getExpectedEventType()
+	err = json.Unmarshal(message.Value, &event)
+	if err == nil {
+		err = connector.writer.write(event)
+	}
+	if err == nil {
+		connector.metrics.written(1)
+	}
+
+	return err
+}
+
+func (connector *KafkaToRedisConnector) getLogger() *slog.Logger {
+	return connector.logger.With("connector", getTypeName(connector.writer))
+}
+
 func (connector *KafkaToRedisConnector) saveRedisIfLastSaveOlderThan(lastSaveShouldBeAfter int64) error {
 	if lastSaveShouldBeAfter < connector.redis.LastSave(context.Background()).Val() {
 		return nil
@@ -94,5 +272,8 @@ func (connector *KafkaToRedisConnector) saveRedisIfLastSaveOlderThan(lastSaveSho
 	if err != nil && err.Error() == RedisBackgroundSaveInProgress {
 		err = nil
 	}
+	if err == nil {
+		connector.metrics.bgSaved()
+	}
 	return err
 }
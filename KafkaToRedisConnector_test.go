@@ -57,11 +57,13 @@ func TestKafkaToRedisConnector(t *testing.T) {
 
 		reader.On("CommitMessages", matchContext, message, kafka.Message{}).Return(nil)
 
+		connectorMetrics := newConnectorMetrics("test-two-iteration#0", TopologyDisciplinesWriter)
 		connector := KafkaToRedisConnector{
-			out:    out,
-			redis:  redis,
-			reader: reader,
-			writer: writer,
+			logger:  newTestLogger(out),
+			redis:   redis,
+			reader:  reader,
+			writer:  writer,
+			metrics: connectorMetrics,
 		}
 
 		wg := sync.WaitGroup{}
@@ -69,6 +71,13 @@ func TestKafkaToRedisConnector(t *testing.T) {
 		connector.execute(ctx, &wg)
 
 		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Contains(t, out.String(), `msg="drain: commit messages" connector=*main.MockWriterInterface count=2`)
+
+		assert.Equal(t, uint64(2), connectorMetrics.fetchedCount.Get())
+		assert.Equal(t, uint64(1), connectorMetrics.writtenCount.Get())
+		assert.Equal(t, uint64(1), connectorMetrics.skippedCount.Get())
+		assert.Equal(t, uint64(0), connectorMetrics.failedCount.Get())
+		assert.Equal(t, uint64(1), connectorMetrics.bgSaveCount.Get())
 	})
 
 	t.Run("Emulate write error", func(t *testing.T) {
@@ -107,11 +116,13 @@ func TestKafkaToRedisConnector(t *testing.T) {
 			return message
 		}, nil)
 
+		connectorMetrics := newConnectorMetrics("test-write-error#0", TopologyDisciplinesWriter)
 		connector := KafkaToRedisConnector{
-			out:    out,
-			redis:  redis,
-			reader: reader,
-			writer: writer,
+			logger:  newTestLogger(out),
+			redis:   redis,
+			reader:  reader,
+			writer:  writer,
+			metrics: connectorMetrics,
 		}
 
 		wg := sync.WaitGroup{}
@@ -121,7 +132,84 @@ func TestKafkaToRedisConnector(t *testing.T) {
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 		reader.AssertNotCalled(t, "CommitMessages")
 
-		assert.Contains(t, out.String(), expectedError.Error())
+		assert.Contains(
+			t, out.String(),
+			`msg="connector error" connector=*main.MockWriterInterface topic="" partition=0 offset=0 key=`+
+				events.DisciplineEventName+` error="`+expectedError.Error(),
+		)
+		assert.Equal(t, uint64(1), connectorMetrics.fetchedCount.Get())
+		assert.Equal(t, uint64(0), connectorMetrics.writtenCount.Get())
+		assert.Equal(t, uint64(1), connectorMetrics.failedCount.Get())
+	})
+
+	t.Run("Emulate write error with dead-letter", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		out := &bytes.Buffer{}
+
+		event := events.DisciplineEvent{
+			Year: 2035,
+			Discipline: events.Discipline{
+				Id:   1,
+				Name: "test discipline name",
+			},
+		}
+
+		payload, _ := json.Marshal(event)
+		message := kafka.Message{
+			Topic:     events.DisciplinesTopic,
+			Partition: 0,
+			Offset:    25,
+			Key:       []byte(events.DisciplineEventName),
+			Value:     payload,
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectBgSave().SetVal("")
+
+		writer := NewMockWriterInterface(t)
+		writer.On("setRedis", redis).Once()
+		writer.On("write", &event).Times(2).Return(expectedError)
+		writer.On("getExpectedMessageKey").Return(events.DisciplineEventName)
+		writer.On("getExpectedEventType").Return(func() any { return &events.DisciplineEvent{} })
+
+		deadLetterWriter := NewMockDeadLetterWriterInterface(t)
+		deadLetterWriter.On("getMaxAttempts").Return(2)
+		deadLetterWriter.On("write", message, 2, expectedError).Once().Return(nil)
+
+		reader := mocks.NewReaderInterface(t)
+		reader.On("FetchMessage", matchContext).Once().Return(message, nil)
+		reader.On("FetchMessage", matchContext).Once().Return(func(_ context.Context) kafka.Message {
+			cancel()
+			return kafka.Message{}
+		}, nil)
+
+		reader.On("CommitMessages", matchContext, message, kafka.Message{}).Return(nil)
+
+		connector := KafkaToRedisConnector{
+			logger:           newTestLogger(out),
+			redis:            redis,
+			reader:           reader,
+			writer:           writer,
+			deadLetterWriter: deadLetterWriter,
+		}
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		connector.execute(ctx, &wg)
+
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Contains(t, out.String(), `msg="message moved to dead-letter topic"`)
+	})
+
+	t.Run("Batch write of fetch burst", func(t *testing.T) {
+		testBatchWrite(t, nil)
+	})
+
+	t.Run("Batch write error and fallback to single writes", func(t *testing.T) {
+		testBatchWrite(t, errors.New("expected error"))
 	})
 
 	t.Run("Emulate writer init error", func(t *testing.T) {
@@ -137,7 +225,7 @@ func TestKafkaToRedisConnector(t *testing.T) {
 		reader := mocks.NewReaderInterface(t)
 
 		connector := KafkaToRedisConnector{
-			out:    out,
+			logger: newTestLogger(out),
 			redis:  redis,
 			reader: reader,
 			writer: writer,
@@ -146,7 +234,8 @@ func TestKafkaToRedisConnector(t *testing.T) {
 		wg := sync.WaitGroup{}
 		wg.Add(1)
 
-		ctx, _ := context.WithTimeout(context.Background(), time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
 		connector.execute(ctx, &wg)
 
 		assert.NoError(t, redisMock.ExpectationsWereMet())
@@ -158,6 +247,96 @@ func TestKafkaToRedisConnector(t *testing.T) {
 	})
 }
 
+type mockBatchWriter struct {
+	*MockWriterInterface
+	*MockBatchWriterInterface
+}
+
+func testBatchWrite(t *testing.T, batchWriteError error) {
+	matchContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+	out := &bytes.Buffer{}
+
+	makeMessage := func(event events.DisciplineEvent) kafka.Message {
+		payload, _ := json.Marshal(event)
+		return kafka.Message{
+			Key:   []byte(events.DisciplineEventName),
+			Value: payload,
+		}
+	}
+
+	event1 := events.DisciplineEvent{Year: 2035, Discipline: events.Discipline{Id: 1, Name: "first discipline"}}
+	event2 := events.DisciplineEvent{Year: 2035, Discipline: events.Discipline{Id: 2, Name: "second discipline"}}
+	message1 := makeMessage(event1)
+	message2 := makeMessage(event2)
+	notExpectedMessage := kafka.Message{Key: []byte("unexpected"), Value: []byte("{}")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	redis, redisMock := redismock.NewClientMock()
+	redisMock.ExpectBgSave().SetVal("")
+
+	writer := mockBatchWriter{
+		MockWriterInterface:      NewMockWriterInterface(t),
+		MockBatchWriterInterface: NewMockBatchWriterInterface(t),
+	}
+	writer.MockWriterInterface.On("setRedis", redis).Once()
+	writer.MockWriterInterface.On("getExpectedMessageKey").Return(events.DisciplineEventName)
+	writer.MockWriterInterface.On("getExpectedEventType").Return(func() any { return &events.DisciplineEvent{} })
+	writer.MockBatchWriterInterface.On("writeBatch", []any{&event1, &event2}).Once().Return(batchWriteError)
+	if batchWriteError != nil {
+		writer.MockWriterInterface.On("write", &event1).Once().Return(nil)
+		writer.MockWriterInterface.On("write", &event2).Once().Return(nil)
+	}
+
+	reader := mocks.NewReaderInterface(t)
+	reader.On("FetchMessage", matchContext).Once().Return(message1, nil)
+	reader.On("FetchMessage", matchContext).Once().Return(notExpectedMessage, nil)
+	reader.On("FetchMessage", matchContext).Once().Return(message2, nil)
+	reader.On("FetchMessage", matchContext).Once().Return(kafka.Message{}, context.DeadlineExceeded)
+	reader.On("FetchMessage", matchContext).Once().Return(func(_ context.Context) kafka.Message {
+		cancel()
+		return kafka.Message{}
+	}, nil)
+	reader.On("FetchMessage", matchContext).Once().Return(kafka.Message{}, context.Canceled)
+
+	reader.On(
+		"CommitMessages", matchContext,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+	).Once().Return(func(_ context.Context, messages ...kafka.Message) error {
+		assert.ElementsMatch(t, []kafka.Message{message1, notExpectedMessage, message2, {}}, messages)
+		return nil
+	})
+
+	connector := KafkaToRedisConnector{
+		logger: newTestLogger(out),
+		redis:  redis,
+		reader: reader,
+		writer: writer,
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	connector.execute(ctx, &wg)
+
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+	assert.Contains(t, out.String(), `msg="drain: commit messages"`)
+	if batchWriteError != nil {
+		assert.Contains(t, out.String(), `msg="batch write failed, write one by one" connector=main.mockBatchWriter count=2 error="`+batchWriteError.Error())
+	}
+}
+
+func TestWrittenBefore(t *testing.T) {
+	written := []kafka.Message{
+		{Partition: 0, Offset: 10},
+		{Partition: 0, Offset: 12},
+		{Partition: 1, Offset: 12},
+		{Partition: 0, Offset: 14},
+	}
+
+	actual := writtenBefore(written, kafka.Message{Partition: 0, Offset: 11})
+	assert.Equal(t, []kafka.Message{{Partition: 0, Offset: 10}, {Partition: 1, Offset: 12}}, actual)
+}
+
 func TestConnectorSaveRedisIfLastSaveOlderThan(t *testing.T) {
 
 	t.Run("Already saved", func(t *testing.T) {
@@ -169,8 +348,8 @@ func TestConnectorSaveRedisIfLastSaveOlderThan(t *testing.T) {
 		redisMock.ExpectLastSave().SetVal(now)
 
 		connector := KafkaToRedisConnector{
-			redis: redis,
-			out:   out,
+			redis:  redis,
+			logger: newTestLogger(out),
 		}
 
 		err := connector.saveRedisIfLastSaveOlderThan(now - 3600)
@@ -187,8 +366,8 @@ func TestConnectorSaveRedisIfLastSaveOlderThan(t *testing.T) {
 		redisMock.ExpectBgSave().SetErr(errors.New(RedisBackgroundSaveInProgress))
 
 		connector := KafkaToRedisConnector{
-			redis: redis,
-			out:   out,
+			redis:  redis,
+			logger: newTestLogger(out),
 		}
 
 		err := connector.saveRedisIfLastSaveOlderThan(now)
@@ -207,8 +386,8 @@ func TestConnectorSaveRedisIfLastSaveOlderThan(t *testing.T) {
 		redisMock.ExpectBgSave().SetErr(expectedError)
 
 		connector := KafkaToRedisConnector{
-			redis: redis,
-			out:   out,
+			redis:  redis,
+			logger: newTestLogger(out),
 		}
 
 		err := connector.saveRedisIfLastSaveOlderThan(now)
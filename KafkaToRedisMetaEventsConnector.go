@@ -4,28 +4,30 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/kneu-messenger-pigeon/events"
 	"github.com/redis/go-redis/v9"
 	"github.com/segmentio/kafka-go"
-	"io"
+	"log/slog"
 	"sync"
+	"time"
 )
 
 type KafkaToRedisMetaEventsConnector struct {
-	out                   io.Writer
+	logger                *slog.Logger
 	reader                events.ReaderInterface
 	redis                 redis.UniversalClient
 	currentYearWriter     WriterInterface
 	lessonTypesListWriter WriterInterface
+	deadLetterWriter      DeadLetterWriterInterface
+	status                *ConnectorStatus
+	metrics               *ConnectorMetrics
 }
 
 func (connector *KafkaToRedisMetaEventsConnector) execute(ctx context.Context, wg *sync.WaitGroup) {
 	var err error
+	var handleErr error
 	var message kafka.Message
 	var messageKey string
-	currentYearEvent := &events.CurrentYearEvent{}
-	lessonTypeList := &events.LessonTypesList{}
 
 	if connector.currentYearWriter == nil || connector.lessonTypesListWriter == nil {
 		wg.Done()
@@ -35,34 +37,76 @@ func (connector *KafkaToRedisMetaEventsConnector) execute(ctx context.Context, w
 	connector.currentYearWriter.setRedis(connector.redis)
 	connector.lessonTypesListWriter.setRedis(connector.redis)
 
-	fmt.Fprintf(connector.out, "%T connector started \n", connector)
+	logger := connector.logger.With("connector", getTypeName(connector))
+	logger.Info("connector started")
+	connector.status.started()
 
 	for ctx.Err() == nil {
+		var errorArgs []any
+		connector.status.fetchStarted()
 		message, err = connector.reader.FetchMessage(ctx)
 		messageKey = string(message.Key)
-		if err == nil {
-			if messageKey == events.CurrentYearEventName {
-				err = json.Unmarshal(message.Value, &currentYearEvent)
-				if err == nil {
-					err = connector.currentYearWriter.write(currentYearEvent)
-				}
-			} else if messageKey == events.LessonTypesListName {
-				err = json.Unmarshal(message.Value, &lessonTypeList)
-				if err == nil {
-					err = connector.lessonTypesListWriter.write(lessonTypeList)
-				}
+		if err != nil {
+			connector.status.fetched(nil)
+		} else {
+			errorArgs = messageLogArgs(message)
+			connector.status.fetched([]kafka.Message{message})
+			connector.metrics.fetched(1)
+			writeStartedAt := time.Now()
+			handleErr, err = writeWithDeadLetter(ctx, logger, connector.deadLetterWriter, message, connector.handleMessage)
+			connector.metrics.writeFinished(writeStartedAt)
+			if handleErr != nil || err != nil {
+				connector.metrics.failed(1)
+			}
+			if handleErr != nil && err == nil {
+				logger.Warn("message moved to dead-letter topic", append(messageLogArgs(message), "error", handleErr)...)
 			}
 		}
 
 		if err == nil {
 			err = connector.reader.CommitMessages(context.Background(), message)
-			fmt.Fprintf(connector.out, "%T Commit %s message (err: %v) \n", connector, messageKey, err)
+			logResult(logger, "commit message", err, "key", messageKey)
+			if err == nil {
+				connector.metrics.committed(1)
+			}
 		}
 
 		if err != nil && !errors.Is(err, context.Canceled) {
-			fmt.Fprintf(connector.out, "%T error: %v \n", connector, err)
+			logger.Error("connector error", append(errorArgs, "error", err)...)
+			connector.status.failed(err)
+		} else if err == nil {
+			connector.status.succeeded()
 		}
 	}
 
+	connector.status.stopped()
 	wg.Done()
 }
+
+func (connector *KafkaToRedisMetaEventsConnector) handleMessage(message kafka.Message) (err error) {
+	switch string(message.Key) {
+	case events.CurrentYearEventName:
+		currentYearEvent := &events.CurrentYearEvent{}
+		err = json.Unmarshal(message.Value, &currentYearEvent)
+		if err == nil {
+			err = connector.currentYearWriter.write(currentYearEvent)
+		}
+
+	case events.LessonTypesListName:
+		lessonTypeList := &events.LessonTypesList{}
+		err = json.Unmarshal(message.Value, &lessonTypeList)
+		if err == nil {
+			err = connector.lessonTypesListWriter.write(lessonTypeList)
+		}
+
+	default:
+		connector.metrics.skipped(1)
+		return nil
+	}
+
+	if err == nil {
+		connector.metrics.written(1)
+	}
+
+	return err
+}
@@ -49,7 +49,7 @@ func TestKafkaToRedisMetaEventsConnector(t *testing.T) {
 		reader.On("CommitMessages", matchContext, message).Return(nil)
 
 		connector := KafkaToRedisMetaEventsConnector{
-			out:                   out,
+			logger:                newTestLogger(out),
 			redis:                 redis,
 			reader:                reader,
 			currentYearWriter:     currentYearWriter,
@@ -99,7 +99,7 @@ func TestKafkaToRedisMetaEventsConnector(t *testing.T) {
 		reader.On("CommitMessages", matchContext, message).Return(nil)
 
 		connector := KafkaToRedisMetaEventsConnector{
-			out:                   out,
+			logger:                newTestLogger(out),
 			redis:                 redis,
 			reader:                reader,
 			currentYearWriter:     currentYearWriter,
@@ -124,8 +124,11 @@ func TestKafkaToRedisMetaEventsConnector(t *testing.T) {
 
 		payload, _ := json.Marshal(event)
 		message := kafka.Message{
-			Key:   []byte(events.CurrentYearEventName),
-			Value: payload,
+			Topic:     events.MetaEventsTopic,
+			Partition: 0,
+			Offset:    42,
+			Key:       []byte(events.CurrentYearEventName),
+			Value:     payload,
 		}
 
 		ctx, cancel := context.WithCancel(context.Background())
@@ -143,7 +146,7 @@ func TestKafkaToRedisMetaEventsConnector(t *testing.T) {
 		}, nil)
 
 		connector := KafkaToRedisMetaEventsConnector{
-			out:                   out,
+			logger:                newTestLogger(out),
 			redis:                 redis,
 			reader:                reader,
 			currentYearWriter:     writer,
@@ -157,20 +160,69 @@ func TestKafkaToRedisMetaEventsConnector(t *testing.T) {
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 		reader.AssertNotCalled(t, "CommitMessages")
 
-		assert.Contains(t, out.String(), expectedError.Error())
+		assert.Contains(
+			t, out.String(),
+			`msg="connector error" connector=*main.KafkaToRedisMetaEventsConnector topic=`+events.MetaEventsTopic+
+				` partition=0 offset=42 key=`+events.CurrentYearEventName+` error="`+expectedError.Error(),
+		)
+	})
+
+	t.Run("Emulate decode error with dead-letter", func(t *testing.T) {
+		out := &bytes.Buffer{}
+
+		message := kafka.Message{
+			Topic: events.MetaEventsTopic,
+			Key:   []byte(events.CurrentYearEventName),
+			Value: []byte("{broken"),
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		redis, redisMock := redismock.NewClientMock()
+
+		writer := NewMockWriterInterface(t)
+		writer.On("setRedis", redis).Times(2)
+
+		deadLetterWriter := NewMockDeadLetterWriterInterface(t)
+		deadLetterWriter.On("getMaxAttempts").Return(1)
+		deadLetterWriter.On("write", message, 1, mock.Anything).Once().Return(nil)
+
+		reader := mocks.NewReaderInterface(t)
+		reader.On("FetchMessage", matchContext).Once().Return(func(_ context.Context) kafka.Message {
+			cancel()
+			return message
+		}, nil)
+		reader.On("CommitMessages", matchContext, message).Return(nil)
+
+		connector := KafkaToRedisMetaEventsConnector{
+			logger:                newTestLogger(out),
+			redis:                 redis,
+			reader:                reader,
+			currentYearWriter:     writer,
+			lessonTypesListWriter: writer,
+			deadLetterWriter:      deadLetterWriter,
+		}
+
+		wg := sync.WaitGroup{}
+		wg.Add(1)
+		connector.execute(ctx, &wg)
+
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		writer.AssertNotCalled(t, "write")
+		assert.Contains(t, out.String(), "moved to dead-letter topic")
 	})
 
 	t.Run("Emulate writer init error", func(t *testing.T) {
 		out := &bytes.Buffer{}
 
 		connector := KafkaToRedisMetaEventsConnector{
-			out: out,
+			logger: newTestLogger(out),
 		}
 
 		wg := sync.WaitGroup{}
 		wg.Add(1)
 
-		ctx, _ := context.WithTimeout(context.Background(), time.Millisecond)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
 		connector.execute(ctx, &wg)
 
 		assert.Empty(t, out.String())
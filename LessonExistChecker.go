@@ -3,27 +3,124 @@ package main
 import (
 	"context"
 	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"sync"
+	"time"
 )
 
+// MaxKnownLessonsCacheSize limits memory used by known lessons cache; cache is reset when limit is reached
+const MaxKnownLessonsCacheSize = 200000
+
+// KnownLessonsCacheTTL
+/*
+ * Known lesson is checked in Redis again after TTL, so lesson deleted by another instance without delivered
+ * notification is not reported as existing after its tombstone has expired. Shorter than deleted lesson tombstone TTL.
+ */
+const KnownLessonsCacheTTL = time.Hour
+
 type LessonExistCheckerInterface interface {
 	Exists(year int, semester uint8, disciplineId uint, lessonId uint) bool
+	ExistsMany(lessons []lessonIdentifier) []bool
+	Remember(lesson lessonIdentifier)
+	Forget(lesson lessonIdentifier)
 }
 
+// LessonExistChecker
+/*
+ * Checks that lesson is stored (or recently deleted) in Redis.
+ * Known lessons are cached in-process for KnownLessonsCacheTTL: cache is filled by LessonWriter writes
+ * and positive lookups and invalidated by LessonWriter on lesson deletion and by lesson written notifications
+ * of other instances.
+ */
 type LessonExistChecker struct {
-	redis redis.UniversalClient
+	logger       *slog.Logger
+	redis        redis.UniversalClient
+	knownLessons map[lessonIdentifier]time.Time
+	mutex        sync.RWMutex
 }
 
-func newLessonExistChecker(redis redis.UniversalClient) *LessonExistChecker {
-	return &LessonExistChecker{redis: redis}
+func newLessonExistChecker(logger *slog.Logger, redis redis.UniversalClient) *LessonExistChecker {
+	return &LessonExistChecker{
+		logger:       logger,
+		redis:        redis,
+		knownLessons: make(map[lessonIdentifier]time.Time),
+	}
 }
 
 func (checker *LessonExistChecker) Exists(year int, semester uint8, disciplineId uint, lessonId uint) bool {
-	return checker.redis.HExists(
-		context.Background(),
-		getDisciplineKey(year, semester, disciplineId),
-		getLessonKey(lessonId),
-	).Val() || checker.redis.Exists(
-		context.Background(),
-		getDeletedLessonKey(year, semester, disciplineId, lessonId),
-	).Val() == 1
+	return checker.ExistsMany([]lessonIdentifier{
+		{
+			Year:         year,
+			Semester:     semester,
+			DisciplineId: disciplineId,
+			LessonId:     lessonId,
+		},
+	})[0]
+}
+
+// ExistsMany checks lessons not found in cache with one pipelined request
+func (checker *LessonExistChecker) ExistsMany(lessons []lessonIdentifier) []bool {
+	result := make([]bool, len(lessons))
+	var missedIndexes []int
+
+	expiredAt := time.Now().Add(-KnownLessonsCacheTTL)
+	checker.mutex.RLock()
+	for i, lesson := range lessons {
+		rememberedAt, exists := checker.knownLessons[lesson]
+		result[i] = exists && rememberedAt.After(expiredAt)
+		if !result[i] {
+			missedIndexes = append(missedIndexes, i)
+		}
+	}
+	checker.mutex.RUnlock()
+
+	lessonsExistCacheHitCount.Add(len(lessons) - len(missedIndexes))
+	lessonsExistCacheMissCount.Add(len(missedIndexes))
+	if len(missedIndexes) == 0 {
+		return result
+	}
+
+	ctx := context.Background()
+	lessonCmds := make([]*redis.BoolCmd, len(missedIndexes))
+	deletedLessonCmds := make([]*redis.IntCmd, len(missedIndexes))
+	_, err := checker.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, index := range missedIndexes {
+			lesson := lessons[index]
+			lessonCmds[i] = pipe.HExists(ctx, getDisciplineKey(lesson.Year, lesson.Semester, lesson.DisciplineId), getLessonKey(lesson.LessonId))
+			deletedLessonCmds[i] = pipe.Exists(ctx, getDeletedLessonKey(lesson.Year, lesson.Semester, lesson.DisciplineId, lesson.LessonId))
+		}
+		return nil
+	})
+	if err != nil {
+		// not confirmed lessons are reported as not existing, so their score changes wait for the next check
+		lessonsExistCheckErrorsCount.Inc()
+		checker.logger.Error("failed to check lessons existence", "count", len(missedIndexes), "error", err)
+	}
+
+	for i, index := range missedIndexes {
+		if lessonCmds[i].Val() {
+			result[index] = true
+			checker.Remember(lessons[index])
+		} else {
+			// deleted lesson is not cached: tombstone expires
+			result[index] = deletedLessonCmds[i].Val() == 1
+		}
+	}
+
+	return result
+}
+
+func (checker *LessonExistChecker) Remember(lesson lessonIdentifier) {
+	checker.mutex.Lock()
+	if len(checker.knownLessons) >= MaxKnownLessonsCacheSize {
+		checker.knownLessons = make(map[lessonIdentifier]time.Time)
+	}
+	checker.knownLessons[lesson] = time.Now()
+	checker.mutex.Unlock()
+}
+
+func (checker *LessonExistChecker) Forget(lesson lessonIdentifier) {
+	checker.mutex.Lock()
+	delete(checker.knownLessons, lesson)
+	checker.mutex.Unlock()
 }
@@ -1,23 +1,32 @@
 package main
 
 import (
+	"bytes"
 	"github.com/go-redis/redismock/v9"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestLessonExistChecker_Exists(t *testing.T) {
 	redis, redisMock := redismock.NewClientMock()
+	redisMock.MatchExpectationsInOrder(true)
 
 	redisMock.ExpectHExists(
 		getDisciplineKey(2024, 1, 235),
 		getLessonKey(4120),
 	).SetVal(true)
+	redisMock.ExpectExists(
+		getDeletedLessonKey(2024, 1, 235, 4120),
+	).SetVal(0)
 
 	redisMock.ExpectHExists(
 		getDisciplineKey(2024, 2, 645),
 		getLessonKey(5780),
-	).SetVal(true)
+	).SetVal(false)
+	redisMock.ExpectExists(
+		getDeletedLessonKey(2024, 2, 645, 5780),
+	).SetVal(1)
 
 	redisMock.ExpectHExists(
 		getDisciplineKey(2030, 1, 980),
@@ -27,9 +36,82 @@ func TestLessonExistChecker_Exists(t *testing.T) {
 		getDeletedLessonKey(2030, 1, 980, 6500),
 	).SetVal(0)
 
-	checker := newLessonExistChecker(redis)
+	checker := newLessonExistChecker(newTestLogger(&bytes.Buffer{}), redis)
 
 	assert.True(t, checker.Exists(2024, 1, 235, 4120))
 	assert.True(t, checker.Exists(2024, 2, 645, 5780))
 	assert.False(t, checker.Exists(2030, 1, 980, 6500))
+
+	// cached after positive lookup
+	assert.True(t, checker.Exists(2024, 1, 235, 4120))
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+}
+
+func TestLessonExistChecker_ExistsMany(t *testing.T) {
+	knownLesson := lessonIdentifier{Year: 2024, Semester: 1, DisciplineId: 235, LessonId: 4120}
+	storedLesson := lessonIdentifier{Year: 2024, Semester: 2, DisciplineId: 645, LessonId: 5780}
+	missingLesson := lessonIdentifier{Year: 2030, Semester: 1, DisciplineId: 980, LessonId: 6500}
+
+	redis, redisMock := redismock.NewClientMock()
+	redisMock.MatchExpectationsInOrder(true)
+
+	redisMock.ExpectHExists(getDisciplineKey(2024, 2, 645), getLessonKey(5780)).SetVal(true)
+	redisMock.ExpectExists(getDeletedLessonKey(2024, 2, 645, 5780)).SetVal(0)
+	redisMock.ExpectHExists(getDisciplineKey(2030, 1, 980), getLessonKey(6500)).SetVal(false)
+	redisMock.ExpectExists(getDeletedLessonKey(2030, 1, 980, 6500)).SetVal(0)
+
+	checker := newLessonExistChecker(newTestLogger(&bytes.Buffer{}), redis)
+	checker.Remember(knownLesson)
+
+	hitCountBefore := lessonsExistCacheHitCount.Get()
+	missCountBefore := lessonsExistCacheMissCount.Get()
+
+	assert.Equal(
+		t, []bool{true, true, false},
+		checker.ExistsMany([]lessonIdentifier{knownLesson, storedLesson, missingLesson}),
+	)
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+
+	assert.Equal(t, hitCountBefore+1, lessonsExistCacheHitCount.Get())
+	assert.Equal(t, missCountBefore+2, lessonsExistCacheMissCount.Get())
+
+	// all known lessons from cache without Redis requests
+	assert.Equal(t, []bool{true, true}, checker.ExistsMany([]lessonIdentifier{knownLesson, storedLesson}))
+
+	checker.Forget(knownLesson)
+	redisMock.ExpectHExists(getDisciplineKey(2024, 1, 235), getLessonKey(4120)).SetVal(false)
+	redisMock.ExpectExists(getDeletedLessonKey(2024, 1, 235, 4120)).SetVal(1)
+
+	assert.Equal(t, []bool{true}, checker.ExistsMany([]lessonIdentifier{knownLesson}))
+	assert.NotContains(t, checker.knownLessons, knownLesson)
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+}
+
+func TestLessonExistChecker_ExpiredCache(t *testing.T) {
+	lesson := lessonIdentifier{Year: 2024, Semester: 1, DisciplineId: 235, LessonId: 4120}
+
+	redis, redisMock := redismock.NewClientMock()
+	redisMock.ExpectHExists(getDisciplineKey(2024, 1, 235), getLessonKey(4120)).SetVal(false)
+	redisMock.ExpectExists(getDeletedLessonKey(2024, 1, 235, 4120)).SetVal(0)
+
+	checker := newLessonExistChecker(newTestLogger(&bytes.Buffer{}), redis)
+	checker.knownLessons[lesson] = time.Now().Add(-KnownLessonsCacheTTL - time.Second)
+
+	// lesson deleted by another instance, tombstone is expired
+	assert.False(t, checker.Exists(2024, 1, 235, 4120))
+	assert.NoError(t, redisMock.ExpectationsWereMet())
+}
+
+func TestLessonExistChecker_Error(t *testing.T) {
+	out := &bytes.Buffer{}
+	redis, redisMock := redismock.NewClientMock()
+	redisMock.ExpectHExists(getDisciplineKey(2024, 1, 235), getLessonKey(4120)).SetErr(assert.AnError)
+
+	errorsCountBefore := lessonsExistCheckErrorsCount.Get()
+	checker := newLessonExistChecker(newTestLogger(out), redis)
+
+	assert.False(t, checker.Exists(2024, 1, 235, 4120))
+	assert.Equal(t, errorsCountBefore+1, lessonsExistCheckErrorsCount.Get())
+	assert.Contains(t, out.String(), `level=ERROR msg="failed to check lessons existence" count=1 error="`+assert.AnError.Error())
+	assert.Empty(t, checker.knownLessons)
 }
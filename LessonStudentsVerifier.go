@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"strings"
+)
+
+// repairLessonStudentsScriptSource
+/*
+ * Adds student to lesson students index when student has score of lesson part and removes it otherwise.
+ * Score existence is checked inside Redis, so concurrent score write can't be lost between read and repair.
+ *
+ * KEYS: lesson students, scores of checked students, in the same order as checked lesson students
+ * ARGV: lesson id, checked "{student}:{lesson part}" lesson students
+ */
+const repairLessonStudentsScriptSource = `
+local changed = 0
+for i = 2, #ARGV do
+	local lessonPart = string.match(ARGV[i], ':(%d+)$')
+	if lessonPart and redis.call('HEXISTS', KEYS[i], ARGV[1] .. ':' .. lessonPart) == 1 then
+		changed = changed + redis.call('SADD', KEYS[1], ARGV[i])
+	else
+		changed = changed + redis.call('SREM', KEYS[1], ARGV[i])
+	end
+end
+return changed
+`
+
+var repairLessonStudentsScript = redis.NewScript(repairLessonStudentsScriptSource)
+
+type LessonStudentsVerifyResult struct {
+	lessons    int
+	mismatches int
+	repaired   int
+}
+
+// LessonStudentsVerifier
+/*
+ * Checks lesson students index, which is used to delete scores of deleted lesson, against students scores hashes:
+ * index should contain exactly "{student}:{lesson part}" of students having score of lesson.
+ * ScoreWriter maintains index only on score change, so scores written before the index was introduced
+ * are indexed by running verify with repair option once.
+ */
+type LessonStudentsVerifier struct {
+	logger  *slog.Logger
+	redis   redis.UniversalClient
+	options VerifyOptions
+}
+
+func (verifier *LessonStudentsVerifier) verify(ctx context.Context) (result LessonStudentsVerifyResult, err error) {
+	expected, err := verifier.collectLessonStudents(ctx)
+
+	reported := 0
+	keys := getSortedMapKeys(expected)
+	for start := 0; start < len(keys) && err == nil; start += verifierScanCount {
+		var mismatches []setMismatch
+		mismatches, err = compareVerifiedSets(ctx, verifier.redis, keys[start:min(start+verifierScanCount, len(keys))], expected)
+		result.mismatches += len(mismatches)
+
+		for _, mismatch := range mismatches {
+			if reported < verifier.options.maxReported {
+				reported++
+				verifier.logger.Warn(
+					"lesson students mismatch",
+					"key", mismatch.key, "missing", mismatch.missing, "extra", mismatch.extra,
+				)
+			}
+
+			if err == nil && verifier.options.repair {
+				err = verifier.repair(ctx, mismatch)
+				if err == nil {
+					result.repaired++
+				}
+			}
+		}
+	}
+	result.lessons = len(keys)
+
+	lessonStudentsMismatchesCount.Add(result.mismatches)
+	lessonStudentsRepairedCount.Add(result.repaired)
+	verifier.logger.Info(
+		"lesson students verified",
+		"lessons", result.lessons, "mismatches", result.mismatches, "repaired", result.repaired,
+	)
+
+	return result, err
+}
+
+// collectLessonStudents returns lesson students keys with "{student}:{lesson part}" of students having lesson score
+func (verifier *LessonStudentsVerifier) collectLessonStudents(ctx context.Context) (map[string]map[string]bool, error) {
+	expected := make(map[string]map[string]bool)
+
+	var scoresKeys []string
+	err := scanVerifiedKeys(ctx, verifier.redis, verifier.options, "scores:*:*", func(key string, _ []string) {
+		scoresKeys = append(scoresKeys, key)
+	})
+
+	for start := 0; start < len(scoresKeys) && err == nil; start += verifierScanCount {
+		keys := scoresKeys[start:min(start+verifierScanCount, len(scoresKeys))]
+		cmds := make([]*redis.StringSliceCmd, len(keys))
+		_, err = verifier.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.HKeys(ctx, key)
+			}
+			return nil
+		})
+
+		for i := 0; i < len(keys) && err == nil; i++ {
+			// {year}:{semester}:scores:{student}:{discipline} with "{lesson}:{lesson part}" fields
+			parts := strings.Split(keys[i], ":")
+			for _, field := range cmds[i].Val() {
+				lessonId, lessonPart, found := strings.Cut(field, ":")
+				if !found {
+					continue
+				}
+
+				lessonStudentsKey := fmt.Sprintf("%s:%s:lesson_students:%s:%s", parts[0], parts[1], parts[4], lessonId)
+				if expected[lessonStudentsKey] == nil {
+					expected[lessonStudentsKey] = make(map[string]bool)
+				}
+				expected[lessonStudentsKey][parts[3]+":"+lessonPart] = true
+			}
+		}
+	}
+
+	if err == nil {
+		// index of lesson without scores should be empty
+		err = scanVerifiedKeys(ctx, verifier.redis, verifier.options, "lesson_students:*:*", func(key string, _ []string) {
+			if expected[key] == nil {
+				expected[key] = make(map[string]bool)
+			}
+		})
+	}
+
+	return expected, err
+}
+
+func (verifier *LessonStudentsVerifier) repair(ctx context.Context, mismatch setMismatch) error {
+	// {year}:{semester}:lesson_students:{discipline}:{lesson} -> {year}:{semester}:scores:{student}:{discipline}
+	parts := strings.Split(mismatch.key, ":")
+	lessonStudents := append(append([]string{}, mismatch.missing...), mismatch.extra...)
+
+	keys := []string{mismatch.key}
+	args := []interface{}{parts[4]}
+	for _, lessonStudent := range lessonStudents {
+		studentId, _, _ := strings.Cut(lessonStudent, ":")
+		keys = append(keys, fmt.Sprintf("%s:%s:scores:%s:%s", parts[0], parts[1], studentId, parts[3]))
+		args = append(args, lessonStudent)
+	}
+
+	changed, err := repairLessonStudentsScript.Run(ctx, verifier.redis, keys, args...).Int()
+	if err == nil {
+		verifier.logger.Info("lesson students repaired", "key", mismatch.key, "changed", changed)
+	}
+
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLessonStudentsVerifier(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("index is consistent", func(t *testing.T) {
+		_, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, "2030:1:scores:5:15", "150:1", "2.5", "150:2", "-999999", "151:1", "1")
+		redisClient.HSet(ctx, "2030:1:scores:6:15", "150:1", "3")
+		redisClient.SAdd(ctx, "2030:1:lesson_students:15:150", "5:1", "5:2", "6:1")
+		redisClient.SAdd(ctx, "2030:1:lesson_students:15:151", "5:1")
+		redisClient.HSet(ctx, "2030:2:scores:5:15", "160:1", "2")
+
+		out := &bytes.Buffer{}
+		verifier := &LessonStudentsVerifier{
+			logger:  newTestLogger(out),
+			redis:   redisClient,
+			options: VerifyOptions{year: 2030, semester: 1, maxReported: DefaultVerifyMaxReported},
+		}
+		result, err := verifier.verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, LessonStudentsVerifyResult{lessons: 2}, result)
+		assert.Contains(t, out.String(), `msg="lesson students verified" lessons=2 mismatches=0 repaired=0`)
+	})
+
+	t.Run("index is built and repaired", func(t *testing.T) {
+		_, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, "2030:1:scores:5:15", "150:1", "2.5", "150:2", "-999999", "151:1", "1")
+		redisClient.HSet(ctx, "2030:1:scores:6:15", "150:1", "3")
+		redisClient.SAdd(ctx, "2030:1:lesson_students:15:150", "6:1", "7:1")
+		redisClient.SAdd(ctx, "2030:1:lesson_students:15:152", "5:1")
+
+		mismatchesCountBefore := lessonStudentsMismatchesCount.Get()
+		repairedCountBefore := lessonStudentsRepairedCount.Get()
+
+		out := &bytes.Buffer{}
+		verifier := &LessonStudentsVerifier{
+			logger:  newTestLogger(out),
+			redis:   redisClient,
+			options: VerifyOptions{repair: true, maxReported: 1},
+		}
+		result, err := verifier.verify(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, LessonStudentsVerifyResult{lessons: 3, mismatches: 3, repaired: 3}, result)
+		assert.Equal(t, mismatchesCountBefore+3, lessonStudentsMismatchesCount.Get())
+		assert.Equal(t, repairedCountBefore+3, lessonStudentsRepairedCount.Get())
+
+		assert.Equal(t, []string{"5:1", "5:2", "6:1"}, redisClient.SMembers(ctx, "2030:1:lesson_students:15:150").Val())
+		assert.Equal(t, []string{"5:1"}, redisClient.SMembers(ctx, "2030:1:lesson_students:15:151").Val())
+		assert.Zero(t, redisClient.Exists(ctx, "2030:1:lesson_students:15:152").Val())
+
+		assert.Contains(
+			t, out.String(),
+			`msg="lesson students mismatch" key=2030:1:lesson_students:15:150 missing="[5:1 5:2]" extra=[7:1]`,
+		)
+		assert.NotContains(t, out.String(), `msg="lesson students mismatch" key=2030:1:lesson_students:15:151`)
+		assert.Contains(t, out.String(), `msg="lesson students repaired" key=2030:1:lesson_students:15:150 changed=3`)
+		assert.Contains(t, out.String(), `msg="lesson students verified" lessons=3 mismatches=3 repaired=3`)
+
+		result, err = verifier.verify(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, LessonStudentsVerifyResult{lessons: 2}, result)
+	})
+
+	t.Run("score is deleted before repair", func(t *testing.T) {
+		_, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, "2030:1:scores:5:15", "151:1", "1")
+
+		verifier := &LessonStudentsVerifier{logger: newTestLogger(&bytes.Buffer{}), redis: redisClient}
+		redisClient.HDel(ctx, "2030:1:scores:5:15", "151:1")
+
+		err := verifier.repair(ctx, setMismatch{key: "2030:1:lesson_students:15:151", missing: []string{"5:1"}})
+		assert.NoError(t, err)
+		assert.Zero(t, redisClient.Exists(ctx, "2030:1:lesson_students:15:151").Val())
+	})
+}
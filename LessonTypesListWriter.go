@@ -6,12 +6,12 @@ import (
 	"encoding/json"
 	"github.com/kneu-messenger-pigeon/events"
 	"github.com/redis/go-redis/v9"
-	"io"
+	"log/slog"
 )
 
 type LessonTypesListWriter struct {
-	out   io.Writer
-	redis redis.UniversalClient
+	logger *slog.Logger
+	redis  redis.UniversalClient
 }
 
 func (writer *LessonTypesListWriter) setRedis(redis redis.UniversalClient) {
@@ -27,7 +27,8 @@ func (writer *LessonTypesListWriter) getExpectedEventType() any {
 }
 
 func (writer *LessonTypesListWriter) write(s any) (err error) {
-	lessonTypesList := s.(*events.LessonTypesList).List
+	event := s.(*events.LessonTypesList)
+	lessonTypesList := event.List
 	serializedList, _ := json.Marshal(lessonTypesList)
 	prevSerializedList, err := writer.redis.GetSet(context.Background(), "lessonTypes", serializedList).Bytes()
 	if err == redis.Nil {
@@ -35,7 +36,11 @@ func (writer *LessonTypesListWriter) write(s any) (err error) {
 		prevSerializedList = make([]byte, 0)
 	}
 	if err == nil && !bytes.Equal(serializedList, prevSerializedList) {
+		writer.logger.Info("lesson types list changed", "year", event.Year, "count", len(lessonTypesList))
 		err = writer.redis.BgSave(context.Background()).Err()
+		if err == nil {
+			lessonTypesListBgSaveCount.Inc()
+		}
 	}
 	return
 }
@@ -33,7 +33,7 @@ func TestLessonTypesListWriter(t *testing.T) {
 		redisMock.ExpectBgSave().SetVal("OK")
 
 		lessonTypesListWriter := LessonTypesListWriter{
-			out: out,
+			logger: newTestLogger(out),
 		}
 
 		lessonTypesListWriter.setRedis(redis)
@@ -44,6 +44,7 @@ func TestLessonTypesListWriter(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Contains(t, out.String(), `msg="lesson types list changed" year=2031 count=1`)
 	})
 
 	t.Run("accept new list which equal to stored in Redis", func(t *testing.T) {
@@ -68,7 +69,7 @@ func TestLessonTypesListWriter(t *testing.T) {
 		redisMock.ExpectGetSet("lessonTypes", expectedString).SetVal(string(expectedString))
 
 		lessonTypesListWriter := LessonTypesListWriter{
-			out: out,
+			logger: newTestLogger(out),
 		}
 
 		lessonTypesListWriter.setRedis(redis)
@@ -79,6 +80,7 @@ func TestLessonTypesListWriter(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Empty(t, out.String())
 	})
 
 	t.Run("error on write key", func(t *testing.T) {
@@ -103,7 +105,7 @@ func TestLessonTypesListWriter(t *testing.T) {
 		redisMock.ExpectGetSet("lessonTypes", expectedString).SetErr(expectedError)
 
 		lessonTypesListWriter := LessonTypesListWriter{
-			out: out,
+			logger: newTestLogger(out),
 		}
 
 		lessonTypesListWriter.setRedis(redis)
@@ -138,7 +140,7 @@ func TestLessonTypesListWriter(t *testing.T) {
 		redisMock.ExpectBgSave().SetErr(expectedError)
 
 		lessonTypesListWriter := LessonTypesListWriter{
-			out: out,
+			logger: newTestLogger(out),
 		}
 
 		lessonTypesListWriter.setRedis(redis)
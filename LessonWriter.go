@@ -5,11 +5,101 @@ import (
 	"fmt"
 	"github.com/kneu-messenger-pigeon/events"
 	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"strconv"
 	"time"
 )
 
+// deleteLessonScoresScriptSource
+/*
+ * Atomically deletes scores of deleted lesson of all students from lesson students index:
+ * removes score fields, subtracts scores from discipline totals, removes discipline from student disciplines
+ * when the last student score of discipline is deleted, and deletes the index.
+ * Deletion time is written into scores updated at of deleted scores, so delayed score event can't restore
+ * deleted score (see writeScoreScriptSource), and discipline semester last update is bumped.
+ * Scores, scores updated at and student disciplines keys are built from prefix, as students are known only
+ * from the index, so keys are not declared in KEYS and script requires single Redis node
+ * (it can't be routed in Redis Cluster).
+ * Index covers scores written after it was introduced, see LessonStudentsVerifier.
+ * Returns deleted scores as {student id, lesson part, stored value}.
+ *
+ * KEYS: lesson students, discipline totals, discipline semester updated at
+ * ARGV: "{year}:{semester}:" keys prefix, discipline id, lesson id, absent score value,
+ *       deleted at (unix timestamp), discipline semester updated at new value,
+ *       dry-run flag (appended by DryRunRedisHook)
+ * In dry-run mode nothing is written: script returns {result, planned write commands}.
+ */
+const deleteLessonScoresScriptSource = `
+local dryRun = ARGV[7] == '` + DryRunScriptFlag + `'
+local planned = {}
+local function write(...)
+	if dryRun then
+		local command = {}
+		for i, value in ipairs({...}) do
+			command[i] = tostring(value)
+		end
+		table.insert(planned, command)
+	else
+		redis.call(...)
+	end
+end
+
+local prefix = ARGV[1]
+local absentValue = tonumber(ARGV[4])
+local deletedAt = tonumber(ARGV[5])
+local deleted = {}
+for _, lessonStudent in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+	local studentId, lessonPart = string.match(lessonStudent, '^(%d+):(%d+)$')
+	if studentId then
+		local scoresKey = prefix .. 'scores:' .. studentId .. ':' .. ARGV[2]
+		local field = ARGV[3] .. ':' .. lessonPart
+		local stored = redis.call('HGET', scoresKey, field)
+		if stored then
+			local isEmptied = redis.call('HLEN', scoresKey) <= 1
+			write('HDEL', scoresKey, field)
+			local updatedAtKey = prefix .. 'scores_updated_at:' .. studentId .. ':' .. ARGV[2]
+			local storedUpdatedAt = tonumber(redis.call('HGET', updatedAtKey, field))
+			if (not storedUpdatedAt) or deletedAt > storedUpdatedAt then
+				write('HSET', updatedAtKey, field, ARGV[5])
+			end
+			if tonumber(stored) ~= absentValue then
+				write('ZINCRBY', KEYS[2], -tonumber(stored), studentId)
+			end
+			if isEmptied then
+				write('SREM', prefix .. 'student_disciplines:' .. studentId, ARGV[2])
+			end
+			table.insert(deleted, {studentId, lessonPart, stored})
+		end
+	end
+end
+write('DEL', KEYS[1])
+
+if #deleted ~= 0 then
+	local lastUpdate = redis.call('GET', KEYS[3])
+	if (not lastUpdate) or ARGV[6] > lastUpdate then
+		write('SET', KEYS[3], ARGV[6])
+	end
+end
+
+if dryRun then
+	return {deleted, planned}
+end
+return deleted
+`
+
+var deleteLessonScoresScript = redis.NewScript(deleteLessonScoresScriptSource)
+
+// LessonWriter
+/*
+ * Writes lessons into discipline lessons hash. Deleted lesson gets tombstone and its scores are deleted
+ * by cascade; when scoresChangesFeedWriter is set, deleted scores are pushed into scores changes feed.
+ */
 type LessonWriter struct {
-	redis redis.UniversalClient
+	logger                  *slog.Logger
+	redis                   redis.UniversalClient
+	lessonExistChecker      LessonExistCheckerInterface
+	lessonWrittenNotifier   LessonWrittenNotifierInterface
+	scoresChangesFeedWriter ScoresChangesFeedWriterInterface
 }
 
 func (writer *LessonWriter) setRedis(redis redis.UniversalClient) {
@@ -26,18 +116,144 @@ func (writer *LessonWriter) getExpectedEventType() any {
 
 func (writer *LessonWriter) write(s any) error {
 	event := s.(*events.LessonEvent)
+	err := writer.writeEvent(writer.redis, event)
+	if err == nil && event.IsDeleted {
+		err = writer.deleteLessonScores(event)
+	}
+	if err == nil {
+		writer.notifyLessonWritten(event)
+	}
+
+	return err
+}
+
+func (writer *LessonWriter) writeBatch(s []any) error {
+	_, err := writer.redis.Pipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		for _, event := range s {
+			_ = writer.writeEvent(pipe, event.(*events.LessonEvent))
+		}
+		return nil
+	})
+
+	// scores are deleted after tombstones are written, so scores changes feed sees deleted lessons as existing
+	for _, event := range s {
+		if err == nil && event.(*events.LessonEvent).IsDeleted {
+			err = writer.deleteLessonScores(event.(*events.LessonEvent))
+		}
+	}
+
+	if err == nil {
+		for _, event := range s {
+			writer.notifyLessonWritten(event.(*events.LessonEvent))
+		}
+	}
+
+	return err
+}
+
+func (writer *LessonWriter) notifyLessonWritten(event *events.LessonEvent) {
+	lesson := lessonIdentifier{
+		Year:         event.Year,
+		Semester:     event.Semester,
+		DisciplineId: event.DisciplineId,
+		LessonId:     event.Id,
+	}
+
+	if writer.lessonExistChecker != nil {
+		if event.IsDeleted {
+			writer.lessonExistChecker.Forget(lesson)
+		} else {
+			writer.lessonExistChecker.Remember(lesson)
+		}
+	}
 
+	if writer.lessonWrittenNotifier != nil {
+		writer.lessonWrittenNotifier.notifyLessonWritten(lesson)
+	}
+}
+
+func (writer *LessonWriter) writeEvent(redis redis.Cmdable, event *events.LessonEvent) error {
 	disciplineKey := getDisciplineKey(event.Year, event.Semester, event.DisciplineId)
 	lessonKey := getLessonKey(event.Id)
 
 	value := fmt.Sprintf("%s%d", event.Date.Format("060102"), event.TypeId)
 	if event.IsDeleted {
 		deletedLessonKey := getDeletedLessonKey(event.Year, event.Semester, event.DisciplineId, event.Id)
-		writer.redis.SetEx(context.Background(), deletedLessonKey, value, time.Hour*24)
+		redis.SetEx(context.Background(), deletedLessonKey, value, time.Hour*24)
 
-		return writer.redis.HDel(context.Background(), disciplineKey, lessonKey).Err()
+		return redis.HDel(context.Background(), disciplineKey, lessonKey).Err()
 	} else {
-		err := writer.redis.HSet(context.Background(), disciplineKey, lessonKey, value).Err()
+		err := redis.HSet(context.Background(), disciplineKey, lessonKey, value).Err()
+		return err
+	}
+}
+
+func (writer *LessonWriter) deleteLessonScores(event *events.LessonEvent) error {
+	now := time.Now()
+	reply, err := deleteLessonScoresScript.Run(
+		context.Background(), writer.redis,
+		[]string{
+			getLessonStudentsKey(event.Year, event.Semester, event.DisciplineId, event.Id),
+			fmt.Sprintf("%d:%d:totals:%d", event.Year, event.Semester, event.DisciplineId),
+			getDisciplineSemesterUpdatedAtKey(event.Year, event.DisciplineId),
+		},
+		fmt.Sprintf("%d:%d:", event.Year, event.Semester),
+		strconv.Itoa(int(event.DisciplineId)), strconv.Itoa(int(event.Id)), formatScoreStorageValue(IsAbsentScoreValue),
+		strconv.FormatInt(now.Unix(), 10), formatDisciplineSemesterUpdatedAt(event.Semester, now),
+	).Slice()
+	if err != nil {
 		return err
 	}
+
+	cascadeDeletedScoresCount.Add(len(reply))
+	if len(reply) != 0 && writer.logger != nil {
+		writer.logger.Info(
+			"lesson scores deleted",
+			"year", event.Year, "semester", event.Semester, "disciplineId", event.DisciplineId,
+			"lessonId", event.Id, "count", len(reply),
+		)
+	}
+
+	if writer.scoresChangesFeedWriter != nil {
+		for _, deleted := range reply {
+			writer.addDeletedScoreToFeed(event, deleted, now)
+		}
+	}
+
+	return nil
+}
+
+// addDeletedScoreToFeed
+/*
+ * Pushes deleted score {student id, lesson part, stored value} into scores changes feed.
+ * Scores hash doesn't keep score id, so Id of deleted score event is absent (0);
+ * consumers identify deleted score by student, discipline, lesson and lesson part.
+ */
+func (writer *LessonWriter) addDeletedScoreToFeed(event *events.LessonEvent, deleted interface{}, now time.Time) {
+	values, _ := deleted.([]interface{})
+	if len(values) != 3 {
+		return
+	}
+
+	studentId, err := strconv.ParseUint(fmt.Sprint(values[0]), 10, 0)
+	lessonPart, lessonPartErr := strconv.ParseUint(fmt.Sprint(values[1]), 10, 8)
+	previousValue, previousValueErr := parsePreviousScoreValue(fmt.Sprint(values[2]))
+	if err != nil || lessonPartErr != nil || previousValueErr != nil {
+		return
+	}
+
+	writer.scoresChangesFeedWriter.addToQueue(
+		events.ScoreEvent{
+			StudentId:    uint(studentId),
+			LessonId:     event.Id,
+			LessonPart:   uint8(lessonPart),
+			DisciplineId: event.DisciplineId,
+			Year:         event.Year,
+			Semester:     event.Semester,
+			ScoreValue:   events.ScoreValue{IsDeleted: true},
+			UpdatedAt:    now,
+			SyncedAt:     now,
+		},
+		previousValue,
+	)
 }
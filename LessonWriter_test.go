@@ -1,13 +1,42 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"github.com/go-redis/redismock/v9"
 	"github.com/kneu-messenger-pigeon/events"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strconv"
 	"testing"
 	"time"
 )
 
+func expectDeleteLessonScores(redisMock redismock.ClientMock) *redismock.ExpectedCmd {
+	// deleted at and discipline semester updated at are current time, they are checked separately
+	return redisMock.CustomMatch(func(expected, actual []interface{}) error {
+		if len(actual) != len(expected) || !assert.ObjectsAreEqual(expected[:10], actual[:10]) {
+			return errors.New("unexpected command")
+		}
+
+		deletedAt, err := strconv.ParseInt(fmt.Sprint(actual[10]), 10, 64)
+		if err != nil || time.Since(time.Unix(deletedAt, 0)) > time.Minute {
+			return fmt.Errorf("unexpected deleted at %v", actual[10])
+		}
+		if actual[11] != fmt.Sprintf("2%d", deletedAt) {
+			return fmt.Errorf("unexpected discipline semester updated at %v", actual[11])
+		}
+		return nil
+	}).ExpectEvalSha(
+		deleteLessonScoresScript.Hash(),
+		[]string{"2029:2:lesson_students:250:650", "2029:2:totals:250", "2029:discipline_semester_updated_at:250"},
+		"2029:2:", "250", "650", "-999999", "", "",
+	)
+}
+
 func TestWriteLesson(t *testing.T) {
 	t.Run("write lesson", func(t *testing.T) {
 		event := events.LessonEvent{
@@ -51,8 +80,19 @@ func TestWriteLesson(t *testing.T) {
 
 		redisMock.ExpectSetEx("2029:2:deleted-lessons:250:650", "3004265", time.Hour*24).SetVal("OK")
 		redisMock.ExpectHDel("2029:2:lessons:250", "650").SetVal(1)
+		expectDeleteLessonScores(redisMock).SetVal([]interface{}{})
 
-		lessonWriter := LessonWriter{}
+		lessonWrittenNotifier := NewMockLessonWrittenNotifierInterface(t)
+		lessonWrittenNotifier.On("notifyLessonWritten", lessonIdentifier{
+			Year:         2029,
+			Semester:     2,
+			DisciplineId: 250,
+			LessonId:     650,
+		}).Once()
+
+		lessonWriter := LessonWriter{
+			lessonWrittenNotifier: lessonWrittenNotifier,
+		}
 
 		lessonWriter.setRedis(redis)
 		err := lessonWriter.write(&event)
@@ -63,4 +103,312 @@ func TestWriteLesson(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
+
+	t.Run("write lesson error", func(t *testing.T) {
+		event := events.LessonEvent{
+			Id:           600,
+			DisciplineId: 200,
+			TypeId:       5,
+			Date:         time.Date(2027, time.Month(5), 13, 0, 0, 0, 0, time.Local),
+			Year:         2026,
+			Semester:     2,
+		}
+
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectHSet("2026:2:lessons:200", "600", "2705135").SetErr(errors.New("expected error"))
+
+		lessonWrittenNotifier := NewMockLessonWrittenNotifierInterface(t)
+
+		lessonWriter := LessonWriter{
+			lessonWrittenNotifier: lessonWrittenNotifier,
+		}
+
+		lessonWriter.setRedis(redis)
+		err := lessonWriter.write(&event)
+
+		assert.Error(t, err)
+		lessonWrittenNotifier.AssertNotCalled(t, "notifyLessonWritten")
+	})
+
+	t.Run("write lessons batch", func(t *testing.T) {
+		writtenEvent := events.LessonEvent{
+			Id:           600,
+			DisciplineId: 200,
+			TypeId:       5,
+			Date:         time.Date(2027, time.Month(5), 13, 0, 0, 0, 0, time.Local),
+			Year:         2026,
+			Semester:     2,
+			IsDeleted:    false,
+		}
+
+		deletedEvent := events.LessonEvent{
+			Id:           650,
+			DisciplineId: 250,
+			TypeId:       5,
+			Date:         time.Date(2030, time.Month(4), 26, 0, 0, 0, 0, time.Local),
+			Year:         2029,
+			Semester:     2,
+			IsDeleted:    true,
+		}
+
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
+
+		redisMock.ExpectHSet("2026:2:lessons:200", "600", "2705135").SetVal(1)
+		redisMock.ExpectSetEx("2029:2:deleted-lessons:250:650", "3004265", time.Hour*24).SetVal("OK")
+		redisMock.ExpectHDel("2029:2:lessons:250", "650").SetVal(1)
+		expectDeleteLessonScores(redisMock).SetVal([]interface{}{})
+
+		lessonWrittenNotifier := NewMockLessonWrittenNotifierInterface(t)
+		lessonWrittenNotifier.On("notifyLessonWritten", lessonIdentifier{
+			Year:         2026,
+			Semester:     2,
+			DisciplineId: 200,
+			LessonId:     600,
+		}).Once()
+		lessonWrittenNotifier.On("notifyLessonWritten", lessonIdentifier{
+			Year:         2029,
+			Semester:     2,
+			DisciplineId: 250,
+			LessonId:     650,
+		}).Once()
+
+		lessonExistChecker := NewMockLessonExistCheckerInterface(t)
+		lessonExistChecker.On("Remember", lessonIdentifier{
+			Year:         2026,
+			Semester:     2,
+			DisciplineId: 200,
+			LessonId:     600,
+		}).Once()
+		lessonExistChecker.On("Forget", lessonIdentifier{
+			Year:         2029,
+			Semester:     2,
+			DisciplineId: 250,
+			LessonId:     650,
+		}).Once()
+
+		lessonWriter := LessonWriter{
+			lessonExistChecker:    lessonExistChecker,
+			lessonWrittenNotifier: lessonWrittenNotifier,
+		}
+
+		lessonWriter.setRedis(redis)
+		err := lessonWriter.writeBatch([]any{&writtenEvent, &deletedEvent})
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("delete lesson scores", func(t *testing.T) {
+		event := events.LessonEvent{
+			Id:           650,
+			DisciplineId: 250,
+			TypeId:       5,
+			Date:         time.Date(2030, time.Month(4), 26, 0, 0, 0, 0, time.Local),
+			Year:         2029,
+			Semester:     2,
+			IsDeleted:    true,
+		}
+
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
+
+		redisMock.ExpectSetEx("2029:2:deleted-lessons:250:650", "3004265", time.Hour*24).SetVal("OK")
+		redisMock.ExpectHDel("2029:2:lessons:250", "650").SetVal(1)
+		expectDeleteLessonScores(redisMock).SetVal([]interface{}{
+			[]interface{}{"123", "1", "4.5"},
+			[]interface{}{"124", "2", "-999999"},
+		})
+
+		var changedEvents []events.ScoreEvent
+		var previousValues []events.ScoreValue
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("addToQueue", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			changedEvents = append(changedEvents, args.Get(0).(events.ScoreEvent))
+			previousValues = append(previousValues, args.Get(1).(events.ScoreValue))
+		}).Times(2)
+
+		cascadeDeletedCountBefore := cascadeDeletedScoresCount.Get()
+		out := &bytes.Buffer{}
+		lessonWriter := LessonWriter{
+			logger:                  newTestLogger(out),
+			scoresChangesFeedWriter: scoresChangesFeedWriter,
+		}
+
+		lessonWriter.setRedis(redis)
+		err := lessonWriter.write(&event)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, cascadeDeletedCountBefore+2, cascadeDeletedScoresCount.Get())
+		assert.Contains(
+			t, out.String(),
+			`msg="lesson scores deleted" year=2029 semester=2 disciplineId=250 lessonId=650 count=2`,
+		)
+
+		assert.Len(t, changedEvents, 2)
+		assert.Equal(t, uint(123), changedEvents[0].StudentId)
+		assert.Equal(t, uint8(1), changedEvents[0].LessonPart)
+		assert.Equal(t, uint(650), changedEvents[0].LessonId)
+		assert.Equal(t, uint(250), changedEvents[0].DisciplineId)
+		assert.Equal(t, 2029, changedEvents[0].Year)
+		assert.Equal(t, uint8(2), changedEvents[0].Semester)
+		assert.Equal(t, events.ScoreValue{IsDeleted: true}, changedEvents[0].ScoreValue)
+		assert.False(t, changedEvents[0].SyncedAt.IsZero())
+		assert.Equal(t, events.ScoreValue{Value: 4.5}, previousValues[0])
+
+		assert.Equal(t, uint(124), changedEvents[1].StudentId)
+		assert.Equal(t, uint8(2), changedEvents[1].LessonPart)
+		assert.Equal(t, events.ScoreValue{IsAbsent: true}, previousValues[1])
+	})
+
+	t.Run("delete lesson scores error", func(t *testing.T) {
+		event := events.LessonEvent{
+			Id:           650,
+			DisciplineId: 250,
+			TypeId:       5,
+			Date:         time.Date(2030, time.Month(4), 26, 0, 0, 0, 0, time.Local),
+			Year:         2029,
+			Semester:     2,
+			IsDeleted:    true,
+		}
+
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectSetEx("2029:2:deleted-lessons:250:650", "3004265", time.Hour*24).SetVal("OK")
+		redisMock.ExpectHDel("2029:2:lessons:250", "650").SetVal(1)
+		expectDeleteLessonScores(redisMock).SetErr(errors.New("expected error"))
+
+		lessonWrittenNotifier := NewMockLessonWrittenNotifierInterface(t)
+		lessonWriter := LessonWriter{
+			lessonWrittenNotifier: lessonWrittenNotifier,
+		}
+
+		lessonWriter.setRedis(redis)
+		err := lessonWriter.write(&event)
+
+		assert.EqualError(t, err, "expected error")
+		lessonWrittenNotifier.AssertNotCalled(t, "notifyLessonWritten")
+	})
+}
+
+func TestDeleteLessonScoresScript(t *testing.T) {
+	ctx := context.Background()
+	lessonStudentsKey := "2029:2:lesson_students:250:650"
+	totalsKey := "2029:2:totals:250"
+	disciplineUpdatedAtKey := "2029:discipline_semester_updated_at:250"
+	// score of student 124 is updated after deletion time, e.g. by out-of-order event
+	laterUpdatedAt := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+
+	event := &events.LessonEvent{
+		Id:           650,
+		DisciplineId: 250,
+		TypeId:       5,
+		Date:         time.Date(2030, time.Month(4), 26, 0, 0, 0, 0, time.Local),
+		Year:         2029,
+		Semester:     2,
+		IsDeleted:    true,
+	}
+
+	prepare := func(redisClient *redis.Client) {
+		redisClient.HSet(ctx, "2029:2:lessons:250", "650", "3004265", "651", "3004275")
+		redisClient.HSet(ctx, "2029:2:scores:123:250", "650:1", "4.5", "651:1", "2")
+		redisClient.HSet(ctx, "2029:2:scores:124:250", "650:2", "-999999")
+		redisClient.HSet(ctx, "2029:2:scores:125:250", "650:1", "3")
+		redisClient.ZAdd(
+			ctx, totalsKey,
+			redis.Z{Score: 6.5, Member: "123"}, redis.Z{Score: 0, Member: "124"}, redis.Z{Score: 3, Member: "125"},
+		)
+		redisClient.SAdd(ctx, "2029:2:student_disciplines:123", "250", "251")
+		redisClient.SAdd(ctx, "2029:2:student_disciplines:124", "250")
+		redisClient.SAdd(ctx, "2029:2:student_disciplines:125", "250", "251")
+		// "126:1" has no score, e.g. it was deleted before the index was verified
+		redisClient.SAdd(ctx, lessonStudentsKey, "123:1", "124:2", "126:1")
+		redisClient.SAdd(ctx, "2029:2:lesson_students:250:651", "123:1")
+		redisClient.HSet(ctx, "2029:2:scores_updated_at:123:250", "650:1", "1700000000", "651:1", "1700000000")
+		redisClient.HSet(ctx, "2029:2:scores_updated_at:124:250", "650:2", laterUpdatedAt)
+		redisClient.Set(ctx, disciplineUpdatedAtKey, "21700000000", 0)
+	}
+
+	t.Run("delete lesson scores", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		prepare(redisClient)
+
+		var changedEvents []events.ScoreEvent
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("addToQueue", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			changedEvents = append(changedEvents, args.Get(0).(events.ScoreEvent))
+		}).Times(2)
+
+		lessonWriter := LessonWriter{redis: redisClient, scoresChangesFeedWriter: scoresChangesFeedWriter}
+		err := lessonWriter.write(event)
+
+		assert.NoError(t, err)
+		assert.Len(t, changedEvents, 2)
+
+		// totals are recomputed, absent score is not subtracted
+		assert.Equal(t, map[string]string{"651:1": "2"}, redisClient.HGetAll(ctx, "2029:2:scores:123:250").Val())
+		assert.Equal(t, []redis.Z{
+			{Score: 0, Member: "124"}, {Score: 2, Member: "123"}, {Score: 3, Member: "125"},
+		}, redisClient.ZRangeWithScores(ctx, totalsKey, 0, -1).Val())
+
+		// student disciplines entry is removed with the last score of discipline only
+		assert.False(t, server.Exists("2029:2:scores:124:250"))
+		assert.False(t, server.Exists("2029:2:student_disciplines:124"))
+		assert.Equal(t, []string{"250", "251"}, redisClient.SMembers(ctx, "2029:2:student_disciplines:123").Val())
+
+		// not indexed score is kept, index of deleted lesson is removed
+		assert.Equal(t, map[string]string{"650:1": "3"}, redisClient.HGetAll(ctx, "2029:2:scores:125:250").Val())
+		assert.Equal(t, []string{"250", "251"}, redisClient.SMembers(ctx, "2029:2:student_disciplines:125").Val())
+		assert.False(t, server.Exists(lessonStudentsKey))
+		assert.True(t, server.Exists("2029:2:lesson_students:250:651"))
+		assert.True(t, server.Exists("2029:2:deleted-lessons:250:650"))
+
+		// deletion time is kept in scores updated at, so delayed score event can't restore deleted score
+		deletedAt := redisClient.HGet(ctx, "2029:2:scores_updated_at:123:250", "650:1").Val()
+		deletedAtUnix, _ := strconv.ParseInt(deletedAt, 10, 64)
+		assert.InDelta(t, time.Now().Unix(), deletedAtUnix, 60)
+		assert.Equal(t, "1700000000", redisClient.HGet(ctx, "2029:2:scores_updated_at:123:250", "651:1").Val())
+		assert.Equal(t, laterUpdatedAt, redisClient.HGet(ctx, "2029:2:scores_updated_at:124:250", "650:2").Val())
+		assert.Empty(t, redisClient.HGetAll(ctx, "2029:2:scores_updated_at:125:250").Val())
+		assert.Equal(t, "2"+deletedAt, redisClient.Get(ctx, disciplineUpdatedAtKey).Val())
+
+		staleScoreEvent := &events.ScoreEvent{
+			Id:           112233,
+			StudentId:    123,
+			LessonId:     650,
+			LessonPart:   1,
+			DisciplineId: 250,
+			Year:         2029,
+			Semester:     2,
+			ScoreValue:   events.ScoreValue{Value: 4.5},
+			UpdatedAt:    time.Unix(deletedAtUnix-60, 0),
+		}
+		scoreWriter := ScoreWriter{redis: redisClient}
+		assert.NoError(t, scoreWriter.write(staleScoreEvent))
+		assert.Equal(t, map[string]string{"651:1": "2"}, redisClient.HGetAll(ctx, "2029:2:scores:123:250").Val())
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		prepare(redisClient)
+		dump := server.Dump()
+
+		reply, err := deleteLessonScoresScript.Run(
+			ctx, redisClient, []string{lessonStudentsKey, totalsKey, disciplineUpdatedAtKey},
+			"2029:2:", "250", "650", "-999999", "2000000000", "22000000000", DryRunScriptFlag,
+		).Slice()
+
+		assert.NoError(t, err)
+		assert.Equal(t, dump, server.Dump())
+		assert.Len(t, reply, 2)
+		assert.ElementsMatch(t, []interface{}{
+			[]interface{}{"123", "1", "4.5"},
+			[]interface{}{"124", "2", "-999999"},
+		}, reply[0])
+		assert.Contains(t, reply[1], []interface{}{"ZINCRBY", totalsKey, "-4.5", "123"})
+		assert.Contains(t, reply[1], []interface{}{"SREM", "2029:2:student_disciplines:124", "250"})
+		assert.Contains(t, reply[1], []interface{}{"DEL", lessonStudentsKey})
+		assert.Contains(t, reply[1], []interface{}{"HSET", "2029:2:scores_updated_at:123:250", "650:1", "2000000000"})
+		assert.Contains(t, reply[1], []interface{}{"SET", disciplineUpdatedAtKey, "22000000000"})
+	})
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const LessonWrittenChannel = "lesson_written"
+
+type lessonIdentifier struct {
+	Year         int   `json:"year"`
+	Semester     uint8 `json:"semester"`
+	DisciplineId uint  `json:"disciplineId"`
+	LessonId     uint  `json:"lessonId"`
+}
+
+// LessonWrittenNotifierInterface is notified when a lesson is written or tombstoned by LessonWriter
+type LessonWrittenNotifierInterface interface {
+	notifyLessonWritten(lesson lessonIdentifier)
+}
+
+type lessonWrittenMessage struct {
+	Instance string           `json:"instance"`
+	Lesson   lessonIdentifier `json:"lesson"`
+}
+
+// RedisLessonWrittenNotifier
+/*
+ * Delivers lesson written notifications to the local notifier directly and to other storage-writer instances
+ * via Redis pub/sub. Subscriber part runs as connector in the event loop.
+ * Lesson of notification from other instance is removed from local known lessons cache,
+ * as the lesson could be deleted there; it is checked in Redis again on the next lookup.
+ */
+type RedisLessonWrittenNotifier struct {
+	logger             *slog.Logger
+	redis              redis.UniversalClient
+	local              LessonWrittenNotifierInterface
+	lessonExistChecker LessonExistCheckerInterface
+	instance           string
+}
+
+func newRedisLessonWrittenNotifier(
+	logger *slog.Logger, redis redis.UniversalClient,
+	local LessonWrittenNotifierInterface, lessonExistChecker LessonExistCheckerInterface,
+) *RedisLessonWrittenNotifier {
+	hostname, _ := os.Hostname()
+
+	return &RedisLessonWrittenNotifier{
+		logger:             logger,
+		redis:              redis,
+		local:              local,
+		lessonExistChecker: lessonExistChecker,
+		instance:           hostname + "-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+func (notifier *RedisLessonWrittenNotifier) notifyLessonWritten(lesson lessonIdentifier) {
+	notifier.local.notifyLessonWritten(lesson)
+
+	payload, _ := json.Marshal(lessonWrittenMessage{
+		Instance: notifier.instance,
+		Lesson:   lesson,
+	})
+	err := notifier.redis.Publish(context.Background(), LessonWrittenChannel, payload).Err()
+	if err != nil {
+		notifier.logger.Error("failed to publish lesson written notification", "error", err)
+	}
+}
+
+func (notifier *RedisLessonWrittenNotifier) execute(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	pubSub := notifier.redis.Subscribe(ctx, LessonWrittenChannel)
+	defer pubSub.Close()
+
+	channel := pubSub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case redisMessage, ok := <-channel:
+			if !ok {
+				return
+			}
+
+			var message lessonWrittenMessage
+			err := json.Unmarshal([]byte(redisMessage.Payload), &message)
+			if err != nil {
+				notifier.logger.Error("failed to decode lesson written notification", "error", err)
+			} else if message.Instance != notifier.instance {
+				if notifier.lessonExistChecker != nil {
+					notifier.lessonExistChecker.Forget(message.Lesson)
+				}
+				notifier.local.notifyLessonWritten(message.Lesson)
+			}
+		}
+	}
+}
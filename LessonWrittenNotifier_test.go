@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRedisLessonWrittenNotifier(t *testing.T) {
+	lesson := lessonIdentifier{
+		Year:         2028,
+		Semester:     1,
+		DisciplineId: 234,
+		LessonId:     150,
+	}
+
+	t.Run("notify", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+
+		localNotifier := NewMockLessonWrittenNotifierInterface(t)
+		localNotifier.On("notifyLessonWritten", lesson).Once()
+
+		notifier := newRedisLessonWrittenNotifier(newTestLogger(&bytes.Buffer{}), redisClient, localNotifier, nil)
+		assert.NotEmpty(t, notifier.instance)
+
+		payload, _ := json.Marshal(lessonWrittenMessage{
+			Instance: notifier.instance,
+			Lesson:   lesson,
+		})
+		redisMock.ExpectPublish(LessonWrittenChannel, payload).SetVal(1)
+
+		notifier.notifyLessonWritten(lesson)
+
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("publish error", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		redisClient, redisMock := redismock.NewClientMock()
+
+		localNotifier := NewMockLessonWrittenNotifierInterface(t)
+		localNotifier.On("notifyLessonWritten", lesson).Once()
+
+		notifier := newRedisLessonWrittenNotifier(newTestLogger(out), redisClient, localNotifier, nil)
+
+		payload, _ := json.Marshal(lessonWrittenMessage{
+			Instance: notifier.instance,
+			Lesson:   lesson,
+		})
+		redisMock.ExpectPublish(LessonWrittenChannel, payload).SetErr(errors.New("expected error"))
+
+		notifier.notifyLessonWritten(lesson)
+
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Contains(t, out.String(), `msg="failed to publish lesson written notification" error="expected error"`)
+	})
+
+	t.Run("receive notification of other instance", func(t *testing.T) {
+		_, redisClient := newMiniRedisClient(t)
+
+		otherLesson := lesson
+		otherLesson.LessonId = 151
+
+		received := make(chan struct{})
+		localNotifier := NewMockLessonWrittenNotifierInterface(t)
+		localNotifier.On("notifyLessonWritten", otherLesson).Once().Run(func(mock.Arguments) {
+			close(received)
+		})
+
+		lessonExistChecker := NewMockLessonExistCheckerInterface(t)
+		lessonExistChecker.On("Forget", otherLesson).Once()
+
+		notifier := newRedisLessonWrittenNotifier(
+			newTestLogger(&bytes.Buffer{}), redisClient, localNotifier, lessonExistChecker,
+		)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go notifier.execute(ctx, wg)
+
+		assert.Eventually(t, func() bool {
+			return redisClient.PubSubNumSub(ctx, LessonWrittenChannel).Val()[LessonWrittenChannel] == 1
+		}, time.Second, time.Millisecond*10)
+
+		ownPayload, _ := json.Marshal(lessonWrittenMessage{Instance: notifier.instance, Lesson: lesson})
+		otherPayload, _ := json.Marshal(lessonWrittenMessage{Instance: "other", Lesson: otherLesson})
+		redisClient.Publish(ctx, LessonWrittenChannel, ownPayload)
+		redisClient.Publish(ctx, LessonWrittenChannel, otherPayload)
+
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Error("notification is not received")
+		}
+
+		cancel()
+		wg.Wait()
+	})
+}
@@ -6,14 +6,118 @@ import (
 	"fmt"
 	"github.com/kneu-messenger-pigeon/events"
 	"github.com/redis/go-redis/v9"
+	"log/slog"
 	"strconv"
+	"time"
 )
 
 const IsAbsentScoreValue = float64(-999999)
 
-const maxWriteRetries = 3
+// writeScoreScriptSource
+/*
+ * Atomically applies score event: compares it with stored value, updates scores hash, discipline totals,
+ * discipline semester last update and student disciplines set. Discipline is removed from student disciplines set
+ * when the last student score of discipline is deleted. Lesson students index ("{student}:{lesson part}" members)
+ * is kept for cascade deletion of lesson scores.
+ * Returns previous stored value ("" if score was not stored), nil when storage state is equal to event
+ * or staleScoreWriteResult when stored score was updated later than event (out-of-order delivery).
+ * Score updated at is kept after delete too, so delayed event can't restore deleted score.
+ *
+ * KEYS: student discipline scores, discipline totals, discipline semester updated at, student disciplines,
+ *       student discipline scores updated at, lesson students
+ * ARGV: lesson key, is deleted (1/0), new value, student id, discipline semester updated at new value,
+ *       discipline id, absent score value, score updated at (unix timestamp, 0 - unknown),
+ *       dry-run flag (appended by DryRunRedisHook)
+ * In dry-run mode nothing is written: script returns {result, planned write commands}.
+ */
+const writeScoreScriptSource = `
+local dryRun = ARGV[9] == '` + DryRunScriptFlag + `'
+local planned = {}
+local function write(...)
+	if dryRun then
+		local command = {}
+		for i, value in ipairs({...}) do
+			command[i] = tostring(value)
+		end
+		table.insert(planned, command)
+	else
+		redis.call(...)
+	end
+end
+local function reply(result)
+	if dryRun then
+		return {result, planned}
+	end
+	return result
+end
+
+local updatedAt = tonumber(ARGV[8])
+if updatedAt > 0 then
+	local storedUpdatedAt = tonumber(redis.call('HGET', KEYS[5], ARGV[1]))
+	if storedUpdatedAt and storedUpdatedAt > updatedAt then
+		return reply('` + staleScoreWriteResult + `')
+	end
+	if (not storedUpdatedAt) or updatedAt > storedUpdatedAt then
+		write('HSET', KEYS[5], ARGV[1], ARGV[8])
+	end
+end
+
+local stored = redis.call('HGET', KEYS[1], ARGV[1])
+local isDeleted = ARGV[2] == '1'
+local newValue = tonumber(ARGV[3])
+local absentValue = tonumber(ARGV[7])
+
+local storedValue = 0
+if stored then
+	storedValue = tonumber(stored)
+end
+
+if isDeleted == (not stored) and newValue == storedValue then
+	return reply(false)
+end
+
+local lastUpdate = redis.call('GET', KEYS[3])
+if (not lastUpdate) or ARGV[5] > lastUpdate then
+	write('SET', KEYS[3], ARGV[5])
+end
+
+local lessonStudent = ARGV[4] .. ':' .. string.match(ARGV[1], ':(%d+)$')
+local isEmptied = false
+if isDeleted then
+	isEmptied = redis.call('HLEN', KEYS[1]) <= 1
+	write('HDEL', KEYS[1], ARGV[1])
+	write('SREM', KEYS[6], lessonStudent)
+else
+	write('HSET', KEYS[1], ARGV[1], ARGV[3])
+	write('SADD', KEYS[6], lessonStudent)
+end
+
+local scoreDiff = 0
+if storedValue ~= absentValue then
+	scoreDiff = scoreDiff - storedValue
+end
+if newValue ~= absentValue then
+	scoreDiff = scoreDiff + newValue
+end
+if scoreDiff ~= 0 then
+	write('ZINCRBY', KEYS[2], scoreDiff, ARGV[4])
+end
+
+if isEmptied then
+	write('SREM', KEYS[4], ARGV[6])
+else
+	write('SADD', KEYS[4], ARGV[6])
+end
+
+return reply(stored or '')
+`
+
+const staleScoreWriteResult = "stale"
+
+var writeScoreScript = redis.NewScript(writeScoreScriptSource)
 
 type ScoreWriter struct {
+	logger                  *slog.Logger
 	redis                   redis.UniversalClient
 	scoresChangesFeedWriter ScoresChangesFeedWriterInterface
 }
@@ -30,91 +134,160 @@ func (writer *ScoreWriter) getExpectedEventType() any {
 	return &events.ScoreEvent{}
 }
 
-func (writer *ScoreWriter) write(s any) (err error) {
+func (writer *ScoreWriter) write(s any) error {
 	event := s.(*events.ScoreEvent)
 
-	studentDisciplineScoresKey := fmt.Sprintf("%d:%d:scores:%d:%d", event.Year, event.Semester, event.StudentId, event.DisciplineId)
-	lessonKey := fmt.Sprintf("%d:%d", event.LessonId, event.LessonPart)
+	cmd := writeScoreScript.Run(
+		context.Background(), writer.redis,
+		getWriteScoreScriptKeys(event), getWriteScoreScriptArgs(event)...,
+	)
 
-	disciplineTotalsKey := fmt.Sprintf("%d:%d:totals:%d", event.Year, event.Semester, event.DisciplineId)
-
-	disciplineLastUpdateAtKey := fmt.Sprintf("%d:discipline_semester_updated_at:%d", event.Year, event.DisciplineId)
-	disciplineLastUpdateNewValue := fmt.Sprintf("%d%d", event.Semester, event.UpdatedAt.Unix())
+	changedEvent, err := writer.handleWriteScoreResult(event, cmd)
+	if changedEvent != nil {
+		writer.scoresChangesFeedWriter.addToQueue(changedEvent.ScoreEvent, changedEvent.Previous)
+	}
 
-	studentDisciplinesKey := fmt.Sprintf("%d:%d:student_disciplines:%d", event.Year, event.Semester, event.StudentId)
-	studentKey := strconv.Itoa(int(event.StudentId))
+	return err
+}
 
-	hasChanges := false
-	var previousValue events.ScoreValue
+// writeBatch
+/*
+ * Runs write score script for every event in one pipeline. Each script call is atomic
+ * and calls are executed in order, so the result is the same as after sequential write calls.
+ * Score changes of batch are passed to scores changes feed writer at once, so they are stored with one call.
+ */
+func (writer *ScoreWriter) writeBatch(s []any) (err error) {
 	ctx := context.Background()
-	writeValueFunc := func(tx *redis.Tx) (err error) {
-		disciplineLastUpdateStoredValue := writer.redis.Get(ctx, disciplineLastUpdateAtKey).Val()
-		storedValue, err := writer.redis.HGet(ctx, studentDisciplineScoresKey, lessonKey).Float64()
-		storedIsDeleted := errors.Is(err, redis.Nil)
-		if err != nil && !storedIsDeleted {
-			return err
-		}
-		newValue := makeScoreStorageValue(event)
-
-		if event.IsDeleted == storedIsDeleted && newValue == storedValue {
-			// do nothing, storage state equal to event (value match or already deleted form storage)
-			return nil
-		}
-		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-			if disciplineLastUpdateNewValue > disciplineLastUpdateStoredValue {
-				pipe.Set(ctx, disciplineLastUpdateAtKey, disciplineLastUpdateNewValue, 0)
-			}
-
-			if event.IsDeleted {
-				pipe.HDel(ctx, studentDisciplineScoresKey, lessonKey)
-			} else {
-				pipe.HSet(ctx, studentDisciplineScoresKey, lessonKey, newValue)
-			}
+	scoreEvents := make([]*events.ScoreEvent, len(s))
+	for i := range s {
+		scoreEvents[i] = s[i].(*events.ScoreEvent)
+	}
 
-			scoreDiff := float64(0)
-			if storedValue != IsAbsentScoreValue {
-				scoreDiff -= storedValue
-			}
-			if newValue != IsAbsentScoreValue {
-				scoreDiff += newValue
-			}
-			if scoreDiff != 0 {
-				pipe.ZIncrBy(ctx, disciplineTotalsKey, scoreDiff, studentKey)
+	cmds := make([]*redis.Cmd, len(scoreEvents))
+	runPipeline := func() {
+		_, _ = writer.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, event := range scoreEvents {
+				cmds[i] = writeScoreScript.EvalSha(ctx, pipe, getWriteScoreScriptKeys(event), getWriteScoreScriptArgs(event)...)
 			}
 			return nil
 		})
-		if err == nil {
-			hasChanges = true
-			previousValue = events.ScoreValue{
-				IsAbsent:  storedValue == IsAbsentScoreValue,
-				IsDeleted: storedIsDeleted,
-			}
+	}
 
-			if storedValue != IsAbsentScoreValue {
-				previousValue.Value = float32(storedValue)
-			}
+	runPipeline()
+	// script is not cached by Redis (e.g. after restart), so none of the calls were executed
+	if len(cmds) != 0 && redis.HasErrorPrefix(cmds[0].Err(), "NOSCRIPT") {
+		err = writeScoreScript.Load(ctx, writer.redis).Err()
+		if err != nil {
+			return err
 		}
-		return err
+		runPipeline()
 	}
 
-	// Retry if the key has been changed.
-	for i := 0; i < maxWriteRetries; i++ {
-		err = writer.redis.Watch(ctx, writeValueFunc, studentDisciplineScoresKey)
-		if err == nil || !errors.Is(err, redis.TxFailedErr) {
-			break
+	var changedEvents []events.ScoreChangedEvent
+	for i, event := range scoreEvents {
+		changedEvent, cmdErr := writer.handleWriteScoreResult(event, cmds[i])
+		if changedEvent != nil {
+			changedEvents = append(changedEvents, *changedEvent)
+		}
+		if cmdErr != nil && err == nil {
+			err = cmdErr
 		}
 	}
 
-	if hasChanges && err == nil {
-		var isMember bool
-		isMember, err = writer.redis.SIsMember(ctx, studentDisciplinesKey, event.DisciplineId).Result()
-		if !isMember {
-			err = writer.redis.SAdd(ctx, studentDisciplinesKey, event.DisciplineId).Err()
+	if len(changedEvents) != 0 {
+		writer.scoresChangesFeedWriter.addBatchToQueue(changedEvents)
+	}
+
+	return err
+}
+
+// handleWriteScoreResult returns score change for scores changes feed, or nil when score is not changed
+func (writer *ScoreWriter) handleWriteScoreResult(
+	event *events.ScoreEvent, cmd *redis.Cmd,
+) (*events.ScoreChangedEvent, error) {
+	storedValue, err := cmd.Text()
+	if errors.Is(err, redis.Nil) {
+		// do nothing, storage state equal to event (value match or already deleted form storage)
+		return nil, nil
+	}
+
+	if err == nil && storedValue == staleScoreWriteResult {
+		staleScoreEventsCount.Inc()
+		if writer.logger != nil {
+			writer.logger.Warn(
+				"skip stale score event: updated at is older than stored score",
+				"scoreId", event.Id, "studentId", event.StudentId, "disciplineId", event.DisciplineId,
+				"lessonId", event.LessonId, "lessonPart", event.LessonPart, "updatedAt", event.UpdatedAt,
+			)
 		}
+		return nil, nil
+	}
+
+	var previousValue events.ScoreValue
+	if err == nil {
+		previousValue, err = parsePreviousScoreValue(storedValue)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &events.ScoreChangedEvent{ScoreEvent: *event, Previous: previousValue}, nil
+}
 
-		writer.scoresChangesFeedWriter.addToQueue(*event, previousValue)
+func getWriteScoreScriptKeys(event *events.ScoreEvent) []string {
+	return []string{
+		fmt.Sprintf("%d:%d:scores:%d:%d", event.Year, event.Semester, event.StudentId, event.DisciplineId),
+		fmt.Sprintf("%d:%d:totals:%d", event.Year, event.Semester, event.DisciplineId),
+		getDisciplineSemesterUpdatedAtKey(event.Year, event.DisciplineId),
+		fmt.Sprintf("%d:%d:student_disciplines:%d", event.Year, event.Semester, event.StudentId),
+		fmt.Sprintf("%d:%d:scores_updated_at:%d:%d", event.Year, event.Semester, event.StudentId, event.DisciplineId),
+		getLessonStudentsKey(event.Year, event.Semester, event.DisciplineId, event.LessonId),
 	}
-	return err
+}
+
+func getWriteScoreScriptArgs(event *events.ScoreEvent) []any {
+	isDeleted := "0"
+	if event.IsDeleted {
+		isDeleted = "1"
+	}
+
+	updatedAt := int64(0)
+	if !event.UpdatedAt.IsZero() {
+		updatedAt = event.UpdatedAt.Unix()
+	}
+
+	return []any{
+		fmt.Sprintf("%d:%d", event.LessonId, event.LessonPart),
+		isDeleted,
+		formatScoreStorageValue(makeScoreStorageValue(event)),
+		strconv.Itoa(int(event.StudentId)),
+		formatDisciplineSemesterUpdatedAt(event.Semester, event.UpdatedAt),
+		strconv.Itoa(int(event.DisciplineId)),
+		formatScoreStorageValue(IsAbsentScoreValue),
+		strconv.FormatInt(updatedAt, 10),
+	}
+}
+
+func parsePreviousScoreValue(storedValue string) (events.ScoreValue, error) {
+	if storedValue == "" {
+		return events.ScoreValue{IsDeleted: true}, nil
+	}
+
+	value, err := strconv.ParseFloat(storedValue, 64)
+	if err != nil || value == IsAbsentScoreValue {
+		return events.ScoreValue{IsAbsent: true}, err
+	}
+
+	return events.ScoreValue{Value: float32(value)}, nil
+}
+
+// formatDisciplineSemesterUpdatedAt returns value of discipline semester updated at, which is compared as string
+func formatDisciplineSemesterUpdatedAt(semester uint8, updatedAt time.Time) string {
+	return fmt.Sprintf("%d%d", semester, updatedAt.Unix())
+}
+
+func formatScoreStorageValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
 }
 
 func makeScoreStorageValue(event *events.ScoreEvent) float64 {
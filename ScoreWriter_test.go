@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"github.com/go-redis/redismock/v9"
 	"github.com/kneu-messenger-pigeon/events"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"strconv"
 	"testing"
@@ -11,15 +14,17 @@ import (
 )
 
 func TestWriteScore(t *testing.T) {
-	t.Run("general expectation score", func(t *testing.T) {
-		scoreWriter := ScoreWriter{}
-
-		assert.IsType(t, scoreWriter.getExpectedEventType(), &events.ScoreEvent{})
-		assert.Equal(t, scoreWriter.getExpectedMessageKey(), events.ScoreEventName)
-	})
-
-	t.Run("write score", func(t *testing.T) {
-		event := events.ScoreEvent{
+	scriptKeys := []string{
+		"2028:1:scores:123:234",
+		"2028:1:totals:234",
+		"2028:discipline_semester_updated_at:234",
+		"2028:1:student_disciplines:123",
+		"2028:1:scores_updated_at:123:234",
+		"2028:1:lesson_students:234:150",
+	}
+
+	newScoreEvent := func() events.ScoreEvent {
+		return events.ScoreEvent{
 			Id:           112233,
 			StudentId:    123,
 			LessonId:     150,
@@ -35,41 +40,61 @@ func TestWriteScore(t *testing.T) {
 			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
 			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
 		}
+	}
 
-		redis, redisMock := redismock.NewClientMock()
+	updatedAtExpectedValue := strconv.Itoa(int(newScoreEvent().UpdatedAt.Unix()))
+	disciplineSemesterUpdatedAtExpectedValue := "1" + updatedAtExpectedValue
 
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:1"
+	t.Run("general expectation score", func(t *testing.T) {
+		scoreWriter := ScoreWriter{}
 
-		disciplineTotalsKey := "2028:1:totals:234"
-		studentDisciplinesKey := "2028:1:student_disciplines:123"
+		assert.IsType(t, scoreWriter.getExpectedEventType(), &events.ScoreEvent{})
+		assert.Equal(t, scoreWriter.getExpectedMessageKey(), events.ScoreEventName)
+	})
 
-		disciplineSemesterUpdatedAtKey := "2028:discipline_semester_updated_at:234"
-		disciplineSemesterUpdatedAtKeyExpectedValue := "1" + strconv.Itoa(int(event.UpdatedAt.Unix()))
+	t.Run("write score", func(t *testing.T) {
+		event := newScoreEvent()
 
-		redisMock.MatchExpectationsInOrder(true)
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), scriptKeys,
+			"150:1", "0", "2.5", "123", disciplineSemesterUpdatedAtExpectedValue, "234", "-999999", updatedAtExpectedValue,
+		).SetVal("")
 
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("addToQueue", event, events.ScoreValue{
+			Value:     0,
+			IsAbsent:  false,
+			IsDeleted: true,
+		})
 
-		redisMock.ExpectGet(disciplineSemesterUpdatedAtKey).RedisNil()
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).RedisNil()
-		redisMock.ExpectTxPipeline()
+		scoreWriter := ScoreWriter{
+			scoresChangesFeedWriter: scoresChangesFeedWriter,
+		}
+		scoreWriter.setRedis(redis)
 
-		redisMock.ExpectSet(disciplineSemesterUpdatedAtKey, disciplineSemesterUpdatedAtKeyExpectedValue, 0).SetVal("OK")
-		redisMock.ExpectHSet(studentDisciplineScoresKey, lessonKey, 2.5).SetVal(1)
+		err := scoreWriter.write(&event)
 
-		redisMock.ExpectZIncrBy(disciplineTotalsKey, 2.5, "123").SetVal(1)
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
 
-		redisMock.ExpectTxPipelineExec()
+	t.Run("write absent", func(t *testing.T) {
+		event := newScoreEvent()
+		event.Value = 0
+		event.IsAbsent = true
 
-		redisMock.ExpectSIsMember(studentDisciplinesKey, uint(234)).SetVal(false)
-		redisMock.ExpectSAdd(studentDisciplinesKey, uint(234)).SetVal(1)
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), scriptKeys,
+			"150:1", "0", "-999999", "123", disciplineSemesterUpdatedAtExpectedValue, "234", "-999999", updatedAtExpectedValue,
+		).SetVal("3")
 
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
 		scoresChangesFeedWriter.On("addToQueue", event, events.ScoreValue{
-			Value:     0,
+			Value:     3,
 			IsAbsent:  false,
-			IsDeleted: true,
+			IsDeleted: false,
 		})
 
 		scoreWriter := ScoreWriter{
@@ -83,55 +108,47 @@ func TestWriteScore(t *testing.T) {
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 
-	t.Run("write absent", func(t *testing.T) {
-		event := events.ScoreEvent{
-			Id:           112233,
-			StudentId:    123,
-			LessonId:     150,
-			LessonPart:   1,
-			DisciplineId: 234,
-			Year:         2028,
-			Semester:     1,
-			ScoreValue: events.ScoreValue{
-				Value:     0,
-				IsAbsent:  true,
-				IsDeleted: false,
-			},
-			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
-			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
-		}
+	t.Run("write not changed score", func(t *testing.T) {
+		event := newScoreEvent()
 
 		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), scriptKeys,
+			"150:1", "0", "2.5", "123", disciplineSemesterUpdatedAtExpectedValue, "234", "-999999", updatedAtExpectedValue,
+		).RedisNil()
 
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:1"
-
-		studentDisciplinesKey := "2028:1:student_disciplines:123"
-
-		disciplineSemesterUpdatedAtKey := "2028:discipline_semester_updated_at:234"
-		disciplineSemesterUpdatedAtKeyStoredValue := "2" + strconv.Itoa(int(event.UpdatedAt.Unix()))
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
 
-		redisMock.MatchExpectationsInOrder(true)
+		scoreWriter := ScoreWriter{
+			scoresChangesFeedWriter: scoresChangesFeedWriter,
+		}
+		scoreWriter.setRedis(redis)
 
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
+		err := scoreWriter.write(&event)
 
-		// emulate stored is newer than current one - so no SET operation
-		redisMock.ExpectGet(disciplineSemesterUpdatedAtKey).SetVal(disciplineSemesterUpdatedAtKeyStoredValue)
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).RedisNil()
-		redisMock.ExpectTxPipeline()
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
 
-		redisMock.ExpectHSet(studentDisciplineScoresKey, lessonKey, IsAbsentScoreValue).SetVal(1)
+		scoresChangesFeedWriter.AssertNotCalled(t, "addToQueue")
+	})
 
-		redisMock.ExpectTxPipelineExec()
+	t.Run("delete score", func(t *testing.T) {
+		event := newScoreEvent()
+		event.LessonPart = 2
+		event.Value = 0
+		event.IsDeleted = true
 
-		redisMock.ExpectSIsMember(studentDisciplinesKey, uint(234)).SetVal(false)
-		redisMock.ExpectSAdd(studentDisciplinesKey, uint(234)).SetVal(1)
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), scriptKeys,
+			"150:2", "1", "0", "123", disciplineSemesterUpdatedAtExpectedValue, "234", "-999999", updatedAtExpectedValue,
+		).SetVal("-999999")
 
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
 		scoresChangesFeedWriter.On("addToQueue", event, events.ScoreValue{
 			Value:     0,
-			IsAbsent:  false,
-			IsDeleted: true,
+			IsAbsent:  true,
+			IsDeleted: false,
 		})
 
 		scoreWriter := ScoreWriter{
@@ -141,42 +158,24 @@ func TestWriteScore(t *testing.T) {
 
 		err := scoreWriter.write(&event)
 
-		assert.IsType(t, scoreWriter.getExpectedEventType(), &event)
-		assert.Equal(t, scoreWriter.getExpectedMessageKey(), events.ScoreEventName)
-
 		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 
-	t.Run("write not changed score", func(t *testing.T) {
-		event := events.ScoreEvent{
-			Id:           112233,
-			StudentId:    123,
-			LessonId:     150,
-			LessonPart:   1,
-			DisciplineId: 234,
-			Year:         2028,
-			Semester:     1,
-			ScoreValue: events.ScoreValue{
-				Value:     2.5,
-				IsAbsent:  false,
-				IsDeleted: false,
-			},
-			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
-			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
+	t.Run("script is not loaded", func(t *testing.T) {
+		event := newScoreEvent()
+		args := []interface{}{
+			"150:1", "0", "2.5", "123", disciplineSemesterUpdatedAtExpectedValue, "234", "-999999", updatedAtExpectedValue,
 		}
 
 		redis, redisMock := redismock.NewClientMock()
-
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:1"
-
 		redisMock.MatchExpectationsInOrder(true)
-
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).SetVal("2.5")
+		redisMock.ExpectEvalSha(writeScoreScript.Hash(), scriptKeys, args...).
+			SetErr(noScriptError{})
+		redisMock.ExpectEval(writeScoreScriptSource, scriptKeys, args...).SetVal("7")
 
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("addToQueue", event, events.ScoreValue{Value: 7})
 
 		scoreWriter := ScoreWriter{
 			scoresChangesFeedWriter: scoresChangesFeedWriter,
@@ -185,85 +184,72 @@ func TestWriteScore(t *testing.T) {
 
 		err := scoreWriter.write(&event)
 
-		assert.IsType(t, scoreWriter.getExpectedEventType(), &event)
-		assert.Equal(t, scoreWriter.getExpectedMessageKey(), events.ScoreEventName)
-
 		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
-
-		scoresChangesFeedWriter.AssertNotCalled(t, "addToQueue")
 	})
 
-	t.Run("write not changed absent", func(t *testing.T) {
-		event := events.ScoreEvent{
-			Id:           112233,
-			StudentId:    123,
-			LessonId:     150,
-			LessonPart:   1,
-			DisciplineId: 234,
-			Year:         2028,
-			Semester:     1,
-			ScoreValue: events.ScoreValue{
-				Value:     0,
-				IsAbsent:  true,
-				IsDeleted: false,
-			},
-			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
-			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
-		}
+	t.Run("skip stale score", func(t *testing.T) {
+		event := newScoreEvent()
+		out := &bytes.Buffer{}
 
 		redis, redisMock := redismock.NewClientMock()
-
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:1"
-
-		redisMock.MatchExpectationsInOrder(true)
-
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).SetVal(strconv.Itoa(int(IsAbsentScoreValue)))
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), scriptKeys,
+			"150:1", "0", "2.5", "123", disciplineSemesterUpdatedAtExpectedValue, "234", "-999999", updatedAtExpectedValue,
+		).SetVal(staleScoreWriteResult)
 
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
 
 		scoreWriter := ScoreWriter{
+			logger:                  newTestLogger(out),
 			scoresChangesFeedWriter: scoresChangesFeedWriter,
 		}
 		scoreWriter.setRedis(redis)
 
+		staleEventsCountBefore := staleScoreEventsCount.Get()
 		err := scoreWriter.write(&event)
 
 		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, staleEventsCountBefore+1, staleScoreEventsCount.Get())
+		assert.Contains(t, out.String(), "scoreId=112233 studentId=123 disciplineId=234 lessonId=150 lessonPart=1")
 
 		scoresChangesFeedWriter.AssertNotCalled(t, "addToQueue")
 	})
 
-	t.Run("write not changed deleted score", func(t *testing.T) {
-		event := events.ScoreEvent{
-			Id:           112233,
-			StudentId:    123,
-			LessonId:     150,
-			LessonPart:   1,
-			DisciplineId: 234,
-			Year:         2028,
-			Semester:     1,
-			ScoreValue: events.ScoreValue{
-				Value:     2.5,
-				IsAbsent:  false,
-				IsDeleted: true,
-			},
-			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
-			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
-		}
+	t.Run("write score without updated at", func(t *testing.T) {
+		event := newScoreEvent()
+		event.UpdatedAt = time.Time{}
 
 		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), scriptKeys,
+			"150:1", "0", "2.5", "123", "1"+strconv.Itoa(int(event.UpdatedAt.Unix())), "234", "-999999", "0",
+		).SetVal("")
 
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:1"
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("addToQueue", event, events.ScoreValue{IsDeleted: true})
 
-		redisMock.MatchExpectationsInOrder(true)
+		scoreWriter := ScoreWriter{
+			scoresChangesFeedWriter: scoresChangesFeedWriter,
+		}
+		scoreWriter.setRedis(redis)
+
+		err := scoreWriter.write(&event)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("write score error", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+		event := newScoreEvent()
 
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).RedisNil()
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), scriptKeys,
+			"150:1", "0", "2.5", "123", disciplineSemesterUpdatedAtExpectedValue, "234", "-999999", updatedAtExpectedValue,
+		).SetErr(expectedError)
 
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
 
@@ -274,227 +260,262 @@ func TestWriteScore(t *testing.T) {
 
 		err := scoreWriter.write(&event)
 
-		assert.IsType(t, scoreWriter.getExpectedEventType(), &event)
-		assert.Equal(t, scoreWriter.getExpectedMessageKey(), events.ScoreEventName)
-
-		assert.NoError(t, err)
+		assert.Error(t, err)
+		assert.Equal(t, expectedError, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 
 		scoresChangesFeedWriter.AssertNotCalled(t, "addToQueue")
 	})
+}
 
-	t.Run("delete score", func(t *testing.T) {
-		event := events.ScoreEvent{
+func TestWriteScoreScript(t *testing.T) {
+	ctx := context.Background()
+	scoresKey := "2028:1:scores:123:234"
+	totalsKey := "2028:1:totals:234"
+	studentDisciplinesKey := "2028:1:student_disciplines:123"
+	lessonStudentsKey := "2028:1:lesson_students:234:150"
+
+	newScoreEvent := func(value events.ScoreValue) *events.ScoreEvent {
+		return &events.ScoreEvent{
 			Id:           112233,
 			StudentId:    123,
 			LessonId:     150,
-			LessonPart:   2,
+			LessonPart:   1,
 			DisciplineId: 234,
 			Year:         2028,
 			Semester:     1,
-			ScoreValue: events.ScoreValue{
-				Value:     0,
-				IsAbsent:  false,
-				IsDeleted: true,
-			},
-			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
-			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
+			ScoreValue:   value,
+			UpdatedAt:    time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
+			SyncedAt:     time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
 		}
+	}
 
-		redis, redisMock := redismock.NewClientMock()
-
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:2"
+	t.Run("write not changed absent", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		server.HSet(scoresKey, "150:1", "-999999")
 
-		disciplineTotalsKey := "2028:1:totals:234"
-		studentDisciplinesKey := "2028:1:student_disciplines:123"
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoreWriter := ScoreWriter{redis: redisClient, scoresChangesFeedWriter: scoresChangesFeedWriter}
 
-		disciplineSemesterUpdatedAtKey := "2028:discipline_semester_updated_at:234"
-		disciplineSemesterUpdatedAtKeyExpectedValue := "1" + strconv.Itoa(int(event.UpdatedAt.Unix()))
+		err := scoreWriter.write(newScoreEvent(events.ScoreValue{IsAbsent: true}))
 
-		redisMock.MatchExpectationsInOrder(true)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"150:1": "-999999"}, redisClient.HGetAll(ctx, scoresKey).Val())
+		assert.False(t, server.Exists(totalsKey))
+		assert.False(t, server.Exists(studentDisciplinesKey))
+		scoresChangesFeedWriter.AssertNotCalled(t, "addToQueue")
+	})
 
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
+	t.Run("write not changed deleted score", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
 
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).SetVal("3.5")
-		redisMock.ExpectTxPipeline()
-		redisMock.ExpectSet(disciplineSemesterUpdatedAtKey, disciplineSemesterUpdatedAtKeyExpectedValue, 0).SetVal("OK")
-		redisMock.ExpectHDel(studentDisciplineScoresKey, lessonKey).SetVal(1)
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoreWriter := ScoreWriter{redis: redisClient, scoresChangesFeedWriter: scoresChangesFeedWriter}
 
-		redisMock.ExpectZIncrBy(disciplineTotalsKey, -3.5, "123").SetVal(1)
+		err := scoreWriter.write(newScoreEvent(events.ScoreValue{Value: 2.5, IsDeleted: true}))
 
-		redisMock.ExpectTxPipelineExec()
+		assert.NoError(t, err)
+		assert.False(t, server.Exists(scoresKey))
+		assert.False(t, server.Exists(totalsKey))
+		assert.False(t, server.Exists(studentDisciplinesKey))
+		assert.False(t, server.Exists(lessonStudentsKey))
+		scoresChangesFeedWriter.AssertNotCalled(t, "addToQueue")
+	})
 
-		redisMock.ExpectSIsMember(studentDisciplinesKey, uint(234)).SetVal(true)
+	t.Run("re-write (change) score", func(t *testing.T) {
+		_, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, scoresKey, "150:1", "7", "151:1", "-999999")
+		redisClient.ZAdd(ctx, totalsKey, redis.Z{Score: 7, Member: "123"})
 
+		event := newScoreEvent(events.ScoreValue{Value: 2.5})
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
-		scoresChangesFeedWriter.On("addToQueue", event, events.ScoreValue{
-			Value:     3.5,
-			IsAbsent:  false,
-			IsDeleted: false,
-		})
+		scoresChangesFeedWriter.On("addToQueue", *event, events.ScoreValue{Value: 7})
+		scoreWriter := ScoreWriter{redis: redisClient, scoresChangesFeedWriter: scoresChangesFeedWriter}
 
-		scoreWriter := ScoreWriter{
-			scoresChangesFeedWriter: scoresChangesFeedWriter,
-		}
-		scoreWriter.setRedis(redis)
-
-		err := scoreWriter.write(&event)
+		err := scoreWriter.write(event)
 
 		assert.NoError(t, err)
-		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, "2.5", redisClient.HGet(ctx, scoresKey, "150:1").Val())
+		assert.Equal(t, 2.5, redisClient.ZScore(ctx, totalsKey, "123").Val())
+		assert.Equal(t, []string{"234"}, redisClient.SMembers(ctx, studentDisciplinesKey).Val())
+		assert.Equal(t, []string{"123:1"}, redisClient.SMembers(ctx, lessonStudentsKey).Val())
+		assert.Equal(
+			t, "1"+strconv.Itoa(int(event.UpdatedAt.Unix())),
+			redisClient.Get(ctx, "2028:discipline_semester_updated_at:234").Val(),
+		)
+
+		// the same event again is not a change
+		err = scoreWriter.write(event)
+		assert.NoError(t, err)
+		scoresChangesFeedWriter.AssertNumberOfCalls(t, "addToQueue", 1)
 	})
 
-	t.Run("re-write (change) score", func(t *testing.T) {
-		event := events.ScoreEvent{
-			Id:           112233,
-			StudentId:    123,
-			LessonId:     150,
-			LessonPart:   1,
-			DisciplineId: 234,
-			Year:         2028,
-			Semester:     1,
-			ScoreValue: events.ScoreValue{
-				Value:     2.5,
-				IsAbsent:  false,
-				IsDeleted: false,
-			},
-			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
-			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
-		}
+	t.Run("delete the last score of discipline", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, scoresKey, "150:1", "3.5")
+		redisClient.ZAdd(ctx, totalsKey, redis.Z{Score: 3.5, Member: "123"})
+		redisClient.SAdd(ctx, studentDisciplinesKey, "234", "235")
+		redisClient.SAdd(ctx, lessonStudentsKey, "123:1", "124:1")
 
-		redis, redisMock := redismock.NewClientMock()
+		event := newScoreEvent(events.ScoreValue{IsDeleted: true})
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("addToQueue", *event, events.ScoreValue{Value: 3.5})
+		scoreWriter := ScoreWriter{redis: redisClient, scoresChangesFeedWriter: scoresChangesFeedWriter}
 
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:1"
+		err := scoreWriter.write(event)
 
-		disciplineTotalsKey := "2028:1:totals:234"
-		studentDisciplinesKey := "2028:1:student_disciplines:123"
+		assert.NoError(t, err)
+		assert.False(t, server.Exists(scoresKey))
+		assert.Equal(t, float64(0), redisClient.ZScore(ctx, totalsKey, "123").Val())
+		assert.Equal(t, []string{"235"}, redisClient.SMembers(ctx, studentDisciplinesKey).Val())
+		assert.Equal(t, []string{"124:1"}, redisClient.SMembers(ctx, lessonStudentsKey).Val())
+	})
 
-		disciplineSemesterUpdatedAtKey := "2028:discipline_semester_updated_at:234"
-		disciplineSemesterUpdatedAtKeyExpectedValue := "1" + strconv.Itoa(int(event.UpdatedAt.Unix()))
+	t.Run("error on read score", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.Set(ctx, scoresKey, "not a hash", 0)
 
-		redisMock.MatchExpectationsInOrder(true)
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoreWriter := ScoreWriter{redis: redisClient, scoresChangesFeedWriter: scoresChangesFeedWriter}
 
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
+		err := scoreWriter.write(newScoreEvent(events.ScoreValue{Value: 2.5}))
 
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).SetVal("7")
-		redisMock.ExpectTxPipeline()
-		redisMock.ExpectSet(disciplineSemesterUpdatedAtKey, disciplineSemesterUpdatedAtKeyExpectedValue, 0).SetVal("OK")
-		redisMock.ExpectHSet(studentDisciplineScoresKey, lessonKey, 2.5).SetVal(1)
+		assert.ErrorContains(t, err, "WRONGTYPE")
+		assert.False(t, server.Exists(totalsKey))
+		scoresChangesFeedWriter.AssertNotCalled(t, "addToQueue")
+	})
+}
 
-		redisMock.ExpectZIncrBy(disciplineTotalsKey, -4.5, "123").SetVal(1)
+func TestWriteScoresBatch(t *testing.T) {
+	firstEvent := events.ScoreEvent{
+		Id:           112233,
+		StudentId:    123,
+		LessonId:     150,
+		LessonPart:   1,
+		DisciplineId: 234,
+		Year:         2028,
+		Semester:     1,
+		ScoreValue: events.ScoreValue{
+			Value: 2.5,
+		},
+		UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
+		SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
+	}
+
+	secondEvent := firstEvent
+	secondEvent.Value = 3
+	secondEvent.UpdatedAt = time.Date(2028, time.Month(11), 12, 14, 40, 40, 0, time.Local)
+
+	notChangedEvent := firstEvent
+	notChangedEvent.Id = 112234
+	notChangedEvent.StudentId = 124
+	notChangedEvent.Value = 4
+
+	expectScripts := func(redisMock redismock.ClientMock) []*redismock.ExpectedCmd {
+		return []*redismock.ExpectedCmd{
+			redisMock.ExpectEvalSha(
+				writeScoreScript.Hash(), getWriteScoreScriptKeys(&firstEvent), getWriteScoreScriptArgs(&firstEvent)...,
+			),
+			redisMock.ExpectEvalSha(
+				writeScoreScript.Hash(), getWriteScoreScriptKeys(&secondEvent), getWriteScoreScriptArgs(&secondEvent)...,
+			),
+			redisMock.ExpectEvalSha(
+				writeScoreScript.Hash(), getWriteScoreScriptKeys(&notChangedEvent), getWriteScoreScriptArgs(&notChangedEvent)...,
+			),
+		}
+	}
 
-		redisMock.ExpectTxPipelineExec()
+	t.Run("write batch", func(t *testing.T) {
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
 
-		redisMock.ExpectSIsMember(studentDisciplinesKey, uint(234)).SetVal(false)
-		redisMock.ExpectSAdd(studentDisciplinesKey, uint(234)).SetVal(1)
+		expected := expectScripts(redisMock)
+		expected[0].SetVal("")
+		expected[1].SetVal("2.5")
+		expected[2].RedisNil()
 
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
-		scoresChangesFeedWriter.On("addToQueue", event, events.ScoreValue{
-			Value:     7,
-			IsAbsent:  false,
-			IsDeleted: false,
-		})
+		scoresChangesFeedWriter.On("addBatchToQueue", []events.ScoreChangedEvent{
+			{ScoreEvent: firstEvent, Previous: events.ScoreValue{IsDeleted: true}},
+			{ScoreEvent: secondEvent, Previous: events.ScoreValue{Value: 2.5}},
+		}).Once()
 
 		scoreWriter := ScoreWriter{
 			scoresChangesFeedWriter: scoresChangesFeedWriter,
 		}
 		scoreWriter.setRedis(redis)
 
-		err := scoreWriter.write(&event)
+		err := scoreWriter.writeBatch([]any{&firstEvent, &secondEvent, &notChangedEvent})
 
 		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 
-	t.Run("error on read score", func(t *testing.T) {
-		expectedError := errors.New("expected error")
-
-		event := events.ScoreEvent{
-			Id:           112233,
-			StudentId:    123,
-			LessonId:     150,
-			LessonPart:   1,
-			DisciplineId: 234,
-			Year:         2028,
-			Semester:     1,
-			ScoreValue: events.ScoreValue{
-				Value:     2.5,
-				IsAbsent:  false,
-				IsDeleted: false,
-			},
-			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
-			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
-		}
-
+	t.Run("write batch with stale event", func(t *testing.T) {
 		redis, redisMock := redismock.NewClientMock()
-
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:1"
-
 		redisMock.MatchExpectationsInOrder(true)
 
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).SetErr(expectedError)
+		expected := expectScripts(redisMock)
+		expected[0].SetVal("")
+		expected[1].SetVal(staleScoreWriteResult)
+		expected[2].RedisNil()
 
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("addBatchToQueue", []events.ScoreChangedEvent{
+			{ScoreEvent: firstEvent, Previous: events.ScoreValue{IsDeleted: true}},
+		}).Once()
 
 		scoreWriter := ScoreWriter{
 			scoresChangesFeedWriter: scoresChangesFeedWriter,
 		}
 		scoreWriter.setRedis(redis)
 
-		err := scoreWriter.write(&event)
-
-		assert.Error(t, err)
-		assert.Equal(t, expectedError, err)
+		err := scoreWriter.writeBatch([]any{&firstEvent, &secondEvent, &notChangedEvent})
 
+		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 	})
 
-	t.Run("write score error", func(t *testing.T) {
-		expectedError := errors.New("expected error")
-
-		event := events.ScoreEvent{
-			Id:           112233,
-			StudentId:    123,
-			LessonId:     150,
-			LessonPart:   1,
-			DisciplineId: 234,
-			Year:         2028,
-			Semester:     1,
-			ScoreValue: events.ScoreValue{
-				Value:     2.5,
-				IsAbsent:  false,
-				IsDeleted: false,
-			},
-			UpdatedAt: time.Date(2028, time.Month(11), 12, 14, 30, 40, 0, time.Local),
-			SyncedAt:  time.Date(2028, time.Month(11), 12, 14, 35, 13, 0, time.Local),
-		}
-
+	t.Run("script is not loaded", func(t *testing.T) {
 		redis, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
 
-		studentDisciplineScoresKey := "2028:1:scores:123:234"
-		lessonKey := "150:1"
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), getWriteScoreScriptKeys(&firstEvent), getWriteScoreScriptArgs(&firstEvent)...,
+		).SetErr(noScriptError{})
+		redisMock.ExpectScriptLoad(writeScoreScriptSource).SetVal(writeScoreScript.Hash())
 
-		disciplineTotalsKey := "2028:1:totals:234"
+		expected := expectScripts(redisMock)
+		expected[0].SetVal("")
+		expected[1].SetVal("2.5")
+		expected[2].RedisNil()
 
-		disciplineSemesterUpdatedAtKey := "2028:discipline_semester_updated_at:234"
-		disciplineSemesterUpdatedAtKeyExpectedValue := "1" + strconv.Itoa(int(event.UpdatedAt.Unix()))
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("addBatchToQueue", []events.ScoreChangedEvent{
+			{ScoreEvent: firstEvent, Previous: events.ScoreValue{IsDeleted: true}},
+			{ScoreEvent: secondEvent, Previous: events.ScoreValue{Value: 2.5}},
+		}).Once()
 
-		redisMock.MatchExpectationsInOrder(true)
+		scoreWriter := ScoreWriter{
+			scoresChangesFeedWriter: scoresChangesFeedWriter,
+		}
+		scoreWriter.setRedis(redis)
 
-		redisMock.ExpectWatch(studentDisciplineScoresKey)
+		err := scoreWriter.writeBatch([]any{&firstEvent, &secondEvent, &notChangedEvent})
 
-		redisMock.ExpectHGet(studentDisciplineScoresKey, lessonKey).RedisNil()
-		redisMock.ExpectTxPipeline()
-		redisMock.ExpectSet(disciplineSemesterUpdatedAtKey, disciplineSemesterUpdatedAtKeyExpectedValue, 0).SetVal("OK")
-		redisMock.ExpectHSet(studentDisciplineScoresKey, lessonKey, 2.5).SetVal(1)
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
 
-		redisMock.ExpectZIncrBy(disciplineTotalsKey, 2.5, "123").SetVal(1)
+	t.Run("write batch error", func(t *testing.T) {
+		expectedError := errors.New("expected error")
 
-		redisMock.ExpectTxPipelineExec().SetErr(expectedError)
+		redis, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
+
+		redisMock.ExpectEvalSha(
+			writeScoreScript.Hash(), getWriteScoreScriptKeys(&firstEvent), getWriteScoreScriptArgs(&firstEvent)...,
+		).SetErr(expectedError)
 
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
 
@@ -503,10 +524,17 @@ func TestWriteScore(t *testing.T) {
 		}
 		scoreWriter.setRedis(redis)
 
-		err := scoreWriter.write(&event)
+		err := scoreWriter.writeBatch([]any{&firstEvent, &secondEvent, &notChangedEvent})
 
-		assert.Error(t, err)
-		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, expectedError, err)
+		scoresChangesFeedWriter.AssertNotCalled(t, "addBatchToQueue")
 	})
+}
 
+type noScriptError struct{}
+
+func (noScriptError) Error() string {
+	return "NOSCRIPT No matching script. Please use EVAL."
 }
+
+func (noScriptError) RedisError() {}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// ScoresChangesFeedPendingKey
+/*
+ * Key of pending score changes of instance without INSTANCE_ID. Each instance restores all payloads of its key,
+ * so instances sharing one Redis must have unique and stable INSTANCE_ID: pending key is suffixed with it.
+ */
+const ScoresChangesFeedPendingKey = "scores_changes_feed_pending"
+
+// ScoresChangesFeedStorageInterface
+/*
+ * Durable copy of ScoresChangesFeedWriter queues. Payloads are stored when score change is queued
+ * and removed only after they were written into scores changes feed, so pending changes survive restart.
+ */
+type ScoresChangesFeedStorageInterface interface {
+	add(payloads [][]byte) error
+	remove(payloads [][]byte) error
+	load() ([][]byte, error)
+}
+
+type ScoresChangesFeedStorage struct {
+	redis redis.UniversalClient
+	key   string
+}
+
+func newScoresChangesFeedStorage(redis redis.UniversalClient, instanceId string) *ScoresChangesFeedStorage {
+	return &ScoresChangesFeedStorage{
+		redis: redis,
+		key:   getScoresChangesFeedPendingKey(instanceId),
+	}
+}
+
+func getScoresChangesFeedPendingKey(instanceId string) string {
+	if instanceId == "" {
+		return ScoresChangesFeedPendingKey
+	}
+
+	return ScoresChangesFeedPendingKey + ":" + instanceId
+}
+
+// add stores payloads with one ZADD; scores keep order of payloads for load
+func (storage *ScoresChangesFeedStorage) add(payloads [][]byte) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	now := time.Now().UnixMicro()
+	members := make([]redis.Z, len(payloads))
+	for i := range payloads {
+		members[i] = redis.Z{
+			Score:  float64(now + int64(i)),
+			Member: payloads[i],
+		}
+	}
+
+	return storage.redis.ZAdd(context.Background(), storage.key, members...).Err()
+}
+
+func (storage *ScoresChangesFeedStorage) remove(payloads [][]byte) error {
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(payloads))
+	for i := range payloads {
+		members[i] = payloads[i]
+	}
+
+	return storage.redis.ZRem(context.Background(), storage.key, members...).Err()
+}
+
+// load returns stored payloads in order they were added
+func (storage *ScoresChangesFeedStorage) load() ([][]byte, error) {
+	members, err := storage.redis.ZRange(context.Background(), storage.key, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	payloads := make([][]byte, len(members))
+	for i := range members {
+		payloads[i] = []byte(members[i])
+	}
+
+	return payloads, nil
+}
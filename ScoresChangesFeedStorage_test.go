@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestScoresChangesFeedStorage(t *testing.T) {
+	payload := []byte(`{"id":112233}`)
+
+	t.Run("add", func(t *testing.T) {
+		secondPayload := []byte(`{"id":112234}`)
+
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.CustomMatch(func(expected, actual []interface{}) error {
+			if len(actual) != 6 || actual[0] != "zadd" || actual[1] != ScoresChangesFeedPendingKey {
+				return errors.New("unexpected command")
+			}
+			assert.Equal(t, payload, actual[3])
+			assert.Equal(t, secondPayload, actual[5])
+			assert.Less(t, actual[2], actual[4], "order of payloads is kept by scores")
+			return nil
+		}).ExpectZAdd(ScoresChangesFeedPendingKey, redis.Z{Member: payload}, redis.Z{Member: secondPayload}).SetVal(2)
+
+		storage := newScoresChangesFeedStorage(redisClient, "")
+
+		assert.NoError(t, storage.add([][]byte{payload, secondPayload}))
+		assert.NoError(t, storage.add([][]byte{}))
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectZRem(ScoresChangesFeedPendingKey, payload, payload).SetVal(1)
+
+		storage := newScoresChangesFeedStorage(redisClient, "")
+
+		assert.NoError(t, storage.remove([][]byte{payload, payload}))
+		assert.NoError(t, storage.remove([][]byte{}))
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("load", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectZRange(ScoresChangesFeedPendingKey, 0, -1).SetVal([]string{string(payload)})
+
+		storage := newScoresChangesFeedStorage(redisClient, "")
+		payloads, err := storage.load()
+
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{payload}, payloads)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+	})
+
+	t.Run("load error", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectZRange(ScoresChangesFeedPendingKey, 0, -1).SetErr(expectedError)
+
+		storage := newScoresChangesFeedStorage(redisClient, "")
+		payloads, err := storage.load()
+
+		assert.Equal(t, expectedError, err)
+		assert.Nil(t, payloads)
+	})
+	t.Run("instances share redis", func(t *testing.T) {
+		secondPayload := []byte(`{"id":112234}`)
+		_, redisClient := newMiniRedisClient(t)
+
+		storage := newScoresChangesFeedStorage(redisClient, "storage-writer-0")
+		secondStorage := newScoresChangesFeedStorage(redisClient, "storage-writer-1")
+		assert.Equal(t, ScoresChangesFeedPendingKey+":storage-writer-0", storage.key)
+
+		assert.NoError(t, storage.add([][]byte{payload}))
+		assert.NoError(t, secondStorage.add([][]byte{secondPayload}))
+
+		payloads, err := storage.load()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{payload}, payloads)
+
+		assert.NoError(t, storage.remove([][]byte{payload}))
+
+		payloads, err = secondStorage.load()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]byte{secondPayload}, payloads)
+	})
+}
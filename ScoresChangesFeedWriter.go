@@ -3,10 +3,9 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/kneu-messenger-pigeon/events"
 	"github.com/segmentio/kafka-go"
-	"io"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -15,19 +14,34 @@ const DefaultScoresChangesFeedWriterCheckInterval = time.Second * 2
 
 const DefaultScoresChangesFeedWriterWaitingTimeout = time.Hour
 
+// DefaultScoresChangesFeedWriterWaitingCheckInterval
+/*
+ * Waiting events are released by lesson written notifications, polling of lessons existence is only a fallback
+ * (e.g. lost notification or lesson written by another instance without pub/sub).
+ */
+const DefaultScoresChangesFeedWriterWaitingCheckInterval = time.Second * 30
+
+const writtenLessonsBufferSize = 1000
+
 type ScoresChangesFeedWriterInterface interface {
 	execute(ctx context.Context)
 	addToQueue(event events.ScoreEvent, previousValue events.ScoreValue)
+	addBatchToQueue(changedEvents []events.ScoreChangedEvent)
 }
 
 type ScoresChangesFeedWriter struct {
-	out                 io.Writer
-	writer              events.WriterInterface
-	checkInterval       time.Duration
-	readyQueue          eventQueueMutex
-	waitingQueue        eventQueueMutex
-	lessonExistChecker  LessonExistCheckerInterface
-	lastCheckedLessonId uint
+	logger               *slog.Logger
+	writer               events.WriterInterface
+	checkInterval        time.Duration
+	waitingCheckInterval time.Duration
+	writtenLessons       chan lessonIdentifier
+	readyQueue           eventQueueMutex
+	waitingQueue         eventQueueMutex
+	lessonExistChecker   LessonExistCheckerInterface
+	storage              ScoresChangesFeedStorageInterface
+	health               componentHealth
+	waitingSince         sync.Map
+	lastCheckedLessonId  uint
 }
 
 type eventQueueMutex struct {
@@ -35,51 +49,116 @@ type eventQueueMutex struct {
 	mutex sync.Mutex
 }
 
-func NewScoresChangesFeedWriter(out io.Writer, writer events.WriterInterface, lessonExistChecker LessonExistCheckerInterface) *ScoresChangesFeedWriter {
+func NewScoresChangesFeedWriter(
+	logger *slog.Logger, writer events.WriterInterface,
+	lessonExistChecker LessonExistCheckerInterface, storage ScoresChangesFeedStorageInterface,
+) *ScoresChangesFeedWriter {
 	return &ScoresChangesFeedWriter{
-		out:                 out,
-		writer:              writer,
-		checkInterval:       DefaultScoresChangesFeedWriterCheckInterval,
-		readyQueue:          eventQueueMutex{queue: make([]*events.ScoreChangedEvent, 0)},
-		waitingQueue:        eventQueueMutex{queue: make([]*events.ScoreChangedEvent, 0)},
-		lessonExistChecker:  lessonExistChecker,
-		lastCheckedLessonId: 0,
+		logger:               logger,
+		writer:               writer,
+		checkInterval:        DefaultScoresChangesFeedWriterCheckInterval,
+		waitingCheckInterval: DefaultScoresChangesFeedWriterWaitingCheckInterval,
+		writtenLessons:       make(chan lessonIdentifier, writtenLessonsBufferSize),
+		readyQueue:           eventQueueMutex{queue: make([]*events.ScoreChangedEvent, 0)},
+		waitingQueue:         eventQueueMutex{queue: make([]*events.ScoreChangedEvent, 0)},
+		lessonExistChecker:   lessonExistChecker,
+		storage:              storage,
+		lastCheckedLessonId:  0,
 	}
 }
 
 func (writer *ScoresChangesFeedWriter) execute(ctx context.Context) {
+	writer.restore()
+
 	ticker := time.NewTicker(writer.checkInterval)
+	defer ticker.Stop()
 
-	continueLoop := true
-	for continueLoop {
+	lastWaitingCheck := time.Now()
+	for {
 		select {
 		case <-ticker.C:
+			if time.Since(lastWaitingCheck) >= writer.waitingCheckInterval {
+				writer.checkWaiting(false)
+				lastWaitingCheck = time.Now()
+			}
+			writer.writeEvents()
+			writer.updateQueueMetrics()
+
+		case lesson := <-writer.writtenLessons:
+			writer.releaseLesson(lesson)
+
 		case <-ctx.Done():
-			continueLoop = false
+			writer.drain()
+			writer.updateQueueMetrics()
+			return
+		}
+	}
+}
+
+// restore puts score changes that were not written before previous shutdown into waiting queue
+func (writer *ScoresChangesFeedWriter) restore() {
+	if writer.storage == nil {
+		return
+	}
+
+	payloads, err := writer.storage.load()
+	if err != nil {
+		writer.logger.Error("failed to restore pending score changes", "error", err)
+		return
+	}
+
+	var brokenPayloads [][]byte
+	writer.waitingQueue.mutex.Lock()
+	for _, payload := range payloads {
+		changedEvent := &events.ScoreChangedEvent{}
+		if json.Unmarshal(payload, changedEvent) == nil {
+			writer.waitingQueue.queue = append(writer.waitingQueue.queue, changedEvent)
+			writer.waitingSince.Store(changedEvent, time.Now())
+		} else {
+			brokenPayloads = append(brokenPayloads, payload)
 		}
+	}
+	writer.waitingQueue.mutex.Unlock()
 
-		writer.checkWaiting(!continueLoop)
-		writer.writeEvents()
+	if len(brokenPayloads) != 0 {
+		err = writer.storage.remove(brokenPayloads)
 	}
 
-	ticker.Stop()
+	logResult(
+		writer.logger, "restored pending score changes", err,
+		"count", len(payloads)-len(brokenPayloads), "broken", len(brokenPayloads),
+	)
 }
 
-func (writer *ScoresChangesFeedWriter) writeEvents() {
+// drain force-releases waiting queue and flushes ready events; called once on shutdown
+func (writer *ScoresChangesFeedWriter) drain() {
+	waitingCount := len(writer.waitingQueue.queue)
+	writer.checkWaiting(true)
+
+	readyCount := len(writer.readyQueue.queue)
+	writtenCount := writer.writeEvents()
+
+	writer.logger.Info(
+		"drain: scores changes feed flushed",
+		"released", waitingCount, "written", writtenCount, "ready", readyCount,
+	)
+}
+
+func (writer *ScoresChangesFeedWriter) writeEvents() int {
 	if len(writer.readyQueue.queue) == 0 {
-		return
+		return 0
 	}
 
-	fmt.Fprintf(writer.out, "Write %d score changes into scores changed feed... \n", len(writer.readyQueue.queue))
+	writer.logger.Debug("write score changes into scores changes feed", "count", len(writer.readyQueue.queue))
 
-	var payload []byte
 	queueLength := len(writer.readyQueue.queue)
 	messages := make([]kafka.Message, queueLength)
+	payloads := make([][]byte, queueLength)
 	for i := 0; i < queueLength; i++ {
-		payload, _ = json.Marshal(writer.readyQueue.queue[i])
+		payloads[i], _ = json.Marshal(writer.readyQueue.queue[i])
 		messages[i] = kafka.Message{
 			Key:   writer.readyQueue.queue[i].GetMessageKey(),
-			Value: payload,
+			Value: payloads[i],
 		}
 	}
 
@@ -89,8 +168,20 @@ func (writer *ScoresChangesFeedWriter) writeEvents() {
 	}
 
 	if err != nil {
-		fmt.Fprintf(writer.out, "Failed to push score changes events: %s\n", err)
+		writer.logger.Error("failed to push score changes events", "count", queueLength, "error", err)
+		writer.health.failed(err)
+		return 0
 	}
+	writer.health.succeeded()
+
+	if writer.storage != nil {
+		err = writer.storage.remove(payloads)
+		if err != nil {
+			writer.logger.Error("failed to remove written score changes from storage", "error", err)
+		}
+	}
+
+	return queueLength
 }
 
 func (writer *ScoresChangesFeedWriter) isEventReady(event *events.ScoreChangedEvent) bool {
@@ -102,8 +193,55 @@ func (writer *ScoresChangesFeedWriter) checkWaiting(force bool) {
 		return
 	}
 
+	var existingLessons map[lessonIdentifier]bool
+	if !force {
+		existingLessons = writer.getWaitingLessonsExistence()
+	}
+
 	syncAtDeadline := time.Now().Add(-DefaultScoresChangesFeedWriterWaitingTimeout)
 
+	writer.releaseWaiting(func(event *events.ScoreChangedEvent) bool {
+		return force || existingLessons[getEventLesson(event)] || event.SyncedAt.Before(syncAtDeadline)
+	})
+}
+
+// getWaitingLessonsExistence checks all lessons of waiting queue at once, each lesson only once
+func (writer *ScoresChangesFeedWriter) getWaitingLessonsExistence() map[lessonIdentifier]bool {
+	existingLessons := make(map[lessonIdentifier]bool)
+	var lessons []lessonIdentifier
+
+	queueLength := len(writer.waitingQueue.queue)
+	for i := 0; i < queueLength; i++ {
+		if writer.waitingQueue.queue[i] != nil {
+			lesson := getEventLesson(writer.waitingQueue.queue[i])
+			if _, exists := existingLessons[lesson]; !exists {
+				existingLessons[lesson] = false
+				lessons = append(lessons, lesson)
+			}
+		}
+	}
+
+	if len(lessons) != 0 {
+		for i, exists := range writer.lessonExistChecker.ExistsMany(lessons) {
+			existingLessons[lessons[i]] = exists
+		}
+	}
+
+	return existingLessons
+}
+
+// releaseLesson moves waiting events of written lesson into ready queue without lessons existence check
+func (writer *ScoresChangesFeedWriter) releaseLesson(lesson lessonIdentifier) {
+	writer.releaseWaiting(func(event *events.ScoreChangedEvent) bool {
+		return getEventLesson(event) == lesson
+	})
+}
+
+func (writer *ScoresChangesFeedWriter) releaseWaiting(isReleased func(event *events.ScoreChangedEvent) bool) {
+	if len(writer.waitingQueue.queue) == 0 {
+		return
+	}
+
 	var event *events.ScoreChangedEvent
 	queueLength := len(writer.waitingQueue.queue)
 
@@ -111,9 +249,12 @@ func (writer *ScoresChangesFeedWriter) checkWaiting(force bool) {
 	for i := 0; i < queueLength; i++ {
 		event = writer.waitingQueue.queue[i]
 		if event != nil {
-			if writer.isEventReady(event) || event.SyncedAt.Before(syncAtDeadline) || force {
+			if isReleased(event) {
 				writer.readyQueue.queue = append(writer.readyQueue.queue, event)
 				writer.waitingQueue.queue[i] = nil
+				if waitingSince, exists := writer.waitingSince.LoadAndDelete(event); exists {
+					scoresChangesFeedWaitingDuration.UpdateDuration(waitingSince.(time.Time))
+				}
 			}
 		}
 	}
@@ -133,22 +274,78 @@ func (writer *ScoresChangesFeedWriter) checkWaiting(force bool) {
 	}
 }
 
+// notifyLessonWritten
+/*
+ * Called by LessonWriter goroutines; waiting events are released by execute loop.
+ * When buffer is full notification is dropped and events will be released by fallback check.
+ */
+func (writer *ScoresChangesFeedWriter) notifyLessonWritten(lesson lessonIdentifier) {
+	select {
+	case writer.writtenLessons <- lesson:
+	default:
+	}
+}
+
 func (writer *ScoresChangesFeedWriter) addToQueue(event events.ScoreEvent, previousValue events.ScoreValue) {
-	changedEvent := events.ScoreChangedEvent{
-		ScoreEvent: event,
-		Previous:   previousValue,
+	writer.addBatchToQueue([]events.ScoreChangedEvent{
+		{
+			ScoreEvent: event,
+			Previous:   previousValue,
+		},
+	})
+}
+
+// addBatchToQueue
+/*
+ * Stores score changes of one written batch with single storage call and then puts them into queues.
+ * Changes are queued only after they are stored, so written events are never removed from storage before added.
+ */
+func (writer *ScoresChangesFeedWriter) addBatchToQueue(changedEvents []events.ScoreChangedEvent) {
+	if writer.storage != nil {
+		payloads := make([][]byte, len(changedEvents))
+		for i := range changedEvents {
+			payloads[i], _ = json.Marshal(&changedEvents[i])
+		}
+
+		err := writer.storage.add(payloads)
+		if err != nil {
+			writer.logger.Error("failed to store score changes", "count", len(payloads), "error", err)
+		}
 	}
 
-	if writer.isEventReady(&changedEvent) {
-		writer.readyQueue.append(&changedEvent)
-	} else {
-		writer.waitingQueue.append(&changedEvent)
+	for i := range changedEvents {
+		changedEvent := &changedEvents[i]
+		if writer.isEventReady(changedEvent) {
+			writer.readyQueue.append(changedEvent)
+		} else {
+			writer.waitingSince.Store(changedEvent, time.Now())
+			writer.waitingQueue.append(changedEvent)
+		}
+
+		if changedEvent.ScoreSource == events.Realtime {
+			realtimeScoresChangesCount.Inc()
+		} else if changedEvent.ScoreSource == events.Secondary {
+			secondaryScoresChangesCount.Inc()
+		}
 	}
+}
+
+func (writer *ScoresChangesFeedWriter) updateQueueMetrics() {
+	writer.readyQueue.mutex.Lock()
+	scoresChangesFeedReadyQueueLength.Set(float64(len(writer.readyQueue.queue)))
+	writer.readyQueue.mutex.Unlock()
+
+	writer.waitingQueue.mutex.Lock()
+	scoresChangesFeedWaitingQueueLength.Set(float64(len(writer.waitingQueue.queue)))
+	writer.waitingQueue.mutex.Unlock()
+}
 
-	if event.ScoreSource == events.Realtime {
-		realtimeScoresChangesCount.Inc()
-	} else if event.ScoreSource == events.Secondary {
-		secondaryScoresChangesCount.Inc()
+func getEventLesson(event *events.ScoreChangedEvent) lessonIdentifier {
+	return lessonIdentifier{
+		Year:         event.Year,
+		Semester:     event.Semester,
+		DisciplineId: event.DisciplineId,
+		LessonId:     event.LessonId,
 	}
 }
 
@@ -57,6 +57,34 @@ func TestScoresChangesFeedWriterAddToQueue(t *testing.T) {
 		assert.Equal(t, 1, len(scoresChangesFeedWriter.readyQueue.queue))
 		assert.Equal(t, uint64(1), secondaryScoresChangesCount.Get())
 	})
+
+	t.Run("batch", func(t *testing.T) {
+		readyEvent := events.ScoreChangedEvent{
+			ScoreEvent: events.ScoreEvent{Id: 112233, LessonId: 150, DisciplineId: 234, Year: 2028, Semester: 1},
+		}
+		waitingEvent := readyEvent
+		waitingEvent.Id = 112234
+		waitingEvent.LessonId = 151
+
+		readyPayload, _ := json.Marshal(&readyEvent)
+		waitingPayload, _ := json.Marshal(&waitingEvent)
+
+		lessonExistChecker := NewMockLessonExistCheckerInterface(t)
+		lessonExistChecker.On("Exists", 2028, uint8(1), uint(234), uint(150)).Return(true)
+		lessonExistChecker.On("Exists", 2028, uint8(1), uint(234), uint(151)).Return(false)
+
+		storage := NewMockScoresChangesFeedStorageInterface(t)
+		storage.On("add", [][]byte{readyPayload, waitingPayload}).Once().Return(nil)
+
+		scoresChangesFeedWriter := ScoresChangesFeedWriter{
+			lessonExistChecker: lessonExistChecker,
+			storage:            storage,
+		}
+		scoresChangesFeedWriter.addBatchToQueue([]events.ScoreChangedEvent{readyEvent, waitingEvent})
+
+		assert.Equal(t, []*events.ScoreChangedEvent{&readyEvent}, scoresChangesFeedWriter.readyQueue.queue)
+		assert.Equal(t, []*events.ScoreChangedEvent{&waitingEvent}, scoresChangesFeedWriter.waitingQueue.queue)
+	})
 }
 
 func TestScoresChangesFeedWriter(t *testing.T) {
@@ -113,7 +141,7 @@ func TestScoresChangesFeedWriter(t *testing.T) {
 			expectedEvent.DisciplineId, expectedEvent.LessonId,
 		).Return(true)
 
-		scoresChangesFeedWriter := NewScoresChangesFeedWriter(out, writer, lessonExistChecker)
+		scoresChangesFeedWriter := NewScoresChangesFeedWriter(newTestLogger(out), writer, lessonExistChecker, nil)
 		scoresChangesFeedWriter.checkInterval = time.Millisecond * 300
 
 		go scoresChangesFeedWriter.execute(ctx)
@@ -228,7 +256,7 @@ func TestScoresChangesFeedWriter(t *testing.T) {
 		).Return(true)
 
 		writer := mocks.NewWriterInterface(t)
-		scoresChangesFeedWriter := NewScoresChangesFeedWriter(out, writer, lessonExistChecker)
+		scoresChangesFeedWriter := NewScoresChangesFeedWriter(newTestLogger(out), writer, lessonExistChecker, nil)
 		scoresChangesFeedWriter.checkInterval = time.Millisecond * 300
 
 		expectedEventMessage := func(message kafka.Message) bool {
@@ -254,5 +282,250 @@ func TestScoresChangesFeedWriter(t *testing.T) {
 
 		assert.Equal(t, 1, len(savedQueue))
 		assert.Equal(t, expectedEvent, *savedQueue[0])
+		assert.Contains(t, out.String(), `msg="drain: scores changes feed flushed" released=0 written=0 ready=1`)
+
+		health, ready := scoresChangesFeedWriter.health.snapshot(time.Now(), time.Minute)
+		assert.True(t, ready)
+		assert.Equal(t, expectedError.Error(), health.LastError)
+		assert.NotNil(t, health.FailingSince)
+	})
+	t.Run("writeFeed - restore and persist queue", func(t *testing.T) {
+		restoredEvent := events.ScoreChangedEvent{
+			ScoreEvent: events.ScoreEvent{
+				Id:           112233,
+				StudentId:    123,
+				LessonId:     150,
+				LessonPart:   1,
+				DisciplineId: 234,
+				Year:         2028,
+				Semester:     1,
+				ScoreValue: events.ScoreValue{
+					Value: 2.5,
+				},
+				UpdatedAt: time.Date(2028, time.Month(11), 18, 14, 30, 40, 0, time.Local),
+				SyncedAt:  time.Date(2028, time.Month(11), 18, 14, 35, 13, 0, time.Local),
+			},
+			Previous: events.ScoreValue{
+				IsDeleted: true,
+			},
+		}
+		restoredPayload, _ := json.Marshal(&restoredEvent)
+		brokenPayload := []byte("{broken")
+
+		addedEvent := restoredEvent
+		addedEvent.Id = 112234
+		addedEvent.Value = 4
+		addedPayload, _ := json.Marshal(&addedEvent)
+
+		out := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lessonExistChecker := NewMockLessonExistCheckerInterface(t)
+		lessonExistChecker.On(
+			"Exists",
+			restoredEvent.Year, restoredEvent.Semester,
+			restoredEvent.DisciplineId, restoredEvent.LessonId,
+		).Return(true)
+
+		lessonExistChecker.On("ExistsMany", []lessonIdentifier{getEventLesson(&restoredEvent)}).Return([]bool{true})
+
+		storage := NewMockScoresChangesFeedStorageInterface(t)
+		storage.On("load").Once().Return([][]byte{restoredPayload, brokenPayload}, nil)
+		storage.On("remove", [][]byte{brokenPayload}).Once().Return(nil)
+		storage.On("add", [][]byte{addedPayload}).Once().Return(nil)
+		storage.On("remove", [][]byte{addedPayload, restoredPayload}).Once().Return(nil)
+
+		writer := mocks.NewWriterInterface(t)
+		writer.On("WriteMessages", matchContext, mock.Anything, mock.Anything).Once().Return(nil)
+
+		scoresChangesFeedWriter := NewScoresChangesFeedWriter(newTestLogger(out), writer, lessonExistChecker, storage)
+		scoresChangesFeedWriter.checkInterval = time.Millisecond * 50
+		scoresChangesFeedWriter.waitingCheckInterval = scoresChangesFeedWriter.checkInterval
+
+		scoresChangesFeedWriter.restore()
+		assert.Equal(t, 1, len(scoresChangesFeedWriter.waitingQueue.queue))
+		assert.Contains(t, out.String(), `msg="restored pending score changes" count=1 broken=1`)
+
+		storage.On("load").Return([][]byte{}, nil)
+		scoresChangesFeedWriter.addToQueue(addedEvent.ScoreEvent, addedEvent.Previous)
+
+		go scoresChangesFeedWriter.execute(ctx)
+		time.Sleep(scoresChangesFeedWriter.checkInterval * 3)
+		cancel()
+		time.Sleep(scoresChangesFeedWriter.checkInterval)
+
+		assert.Empty(t, scoresChangesFeedWriter.waitingQueue.queue)
+		assert.Empty(t, scoresChangesFeedWriter.readyQueue.queue)
 	})
+	t.Run("writeFeed - instances share redis", func(t *testing.T) {
+		scoreEvent := events.ScoreEvent{
+			Id:           112233,
+			StudentId:    123,
+			LessonId:     150,
+			LessonPart:   1,
+			DisciplineId: 234,
+			Year:         2028,
+			Semester:     1,
+			ScoreValue: events.ScoreValue{
+				Value: 2.5,
+			},
+		}
+		secondScoreEvent := scoreEvent
+		secondScoreEvent.Id = 112234
+
+		_, redisClient := newMiniRedisClient(t)
+
+		lessonExistChecker := NewMockLessonExistCheckerInterface(t)
+		lessonExistChecker.On(
+			"Exists", scoreEvent.Year, scoreEvent.Semester, scoreEvent.DisciplineId, scoreEvent.LessonId,
+		).Return(false)
+
+		newWriter := func(instanceId string) *ScoresChangesFeedWriter {
+			return NewScoresChangesFeedWriter(
+				newTestLogger(&bytes.Buffer{}), mocks.NewWriterInterface(t), lessonExistChecker,
+				newScoresChangesFeedStorage(redisClient, instanceId),
+			)
+		}
+
+		newWriter("storage-writer-0").addToQueue(scoreEvent, events.ScoreValue{})
+		newWriter("storage-writer-1").addToQueue(secondScoreEvent, events.ScoreValue{})
+
+		// restarted instance restores only own pending score changes
+		restartedWriter := newWriter("storage-writer-0")
+		restartedWriter.restore()
+		if assert.Len(t, restartedWriter.waitingQueue.queue, 1) {
+			assert.Equal(t, scoreEvent.Id, restartedWriter.waitingQueue.queue[0].Id)
+		}
+
+		secondRestartedWriter := newWriter("storage-writer-1")
+		secondRestartedWriter.restore()
+		if assert.Len(t, secondRestartedWriter.waitingQueue.queue, 1) {
+			assert.Equal(t, secondScoreEvent.Id, secondRestartedWriter.waitingQueue.queue[0].Id)
+		}
+	})
+
+	t.Run("writeFeed - release waiting on lesson written", func(t *testing.T) {
+		scoreEvent := events.ScoreEvent{
+			Id:           112233,
+			StudentId:    123,
+			LessonId:     150,
+			LessonPart:   1,
+			DisciplineId: 234,
+			Year:         2028,
+			Semester:     1,
+			ScoreValue: events.ScoreValue{
+				Value: 2.5,
+			},
+			UpdatedAt: time.Now(),
+			SyncedAt:  time.Now(),
+		}
+		otherLessonEvent := scoreEvent
+		otherLessonEvent.Id = 112234
+		otherLessonEvent.LessonId = 151
+
+		out := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		lessonExistChecker := NewMockLessonExistCheckerInterface(t)
+		lessonExistChecker.On("Exists", scoreEvent.Year, scoreEvent.Semester, scoreEvent.DisciplineId, scoreEvent.LessonId).Return(false)
+		lessonExistChecker.On("Exists", scoreEvent.Year, scoreEvent.Semester, scoreEvent.DisciplineId, otherLessonEvent.LessonId).Return(false)
+
+		receivedEvents := make(chan events.ScoreChangedEvent, 5)
+		writer := mocks.NewWriterInterface(t)
+		writer.On("WriteMessages", matchContext, mock.MatchedBy(func(message kafka.Message) bool {
+			var actualEvent events.ScoreChangedEvent
+			_ = json.Unmarshal(message.Value, &actualEvent)
+			receivedEvents <- actualEvent
+			return true
+		})).Return(nil)
+
+		scoresChangesFeedWriter := NewScoresChangesFeedWriter(newTestLogger(out), writer, lessonExistChecker, nil)
+		scoresChangesFeedWriter.checkInterval = time.Millisecond * 50
+		scoresChangesFeedWriter.waitingCheckInterval = time.Hour
+
+		scoresChangesFeedWriter.addToQueue(scoreEvent, events.ScoreValue{IsDeleted: true})
+		scoresChangesFeedWriter.addToQueue(otherLessonEvent, events.ScoreValue{IsDeleted: true})
+
+		done := make(chan struct{})
+		go func() {
+			scoresChangesFeedWriter.execute(ctx)
+			close(done)
+		}()
+
+		time.Sleep(scoresChangesFeedWriter.checkInterval * 2)
+		assert.Equal(t, 2, len(scoresChangesFeedWriter.waitingQueue.queue))
+		writer.AssertNotCalled(t, "WriteMessages")
+
+		scoresChangesFeedWriter.notifyLessonWritten(lessonIdentifier{
+			Year:         scoreEvent.Year,
+			Semester:     scoreEvent.Semester,
+			DisciplineId: scoreEvent.DisciplineId,
+			LessonId:     scoreEvent.LessonId,
+		})
+
+		select {
+		case receivedEvent := <-receivedEvents:
+			assert.Equal(t, scoreEvent.Id, receivedEvent.Id)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+
+		assert.Equal(t, 1, len(scoresChangesFeedWriter.waitingQueue.queue))
+		assert.Equal(t, otherLessonEvent.Id, scoresChangesFeedWriter.waitingQueue.queue[0].Id)
+
+		waitingDurationCount := getHistogramCount(scoresChangesFeedWaitingDuration)
+		cancel()
+		<-done
+		writer.AssertNumberOfCalls(t, "WriteMessages", 2)
+		assert.Equal(t, waitingDurationCount+1, getHistogramCount(scoresChangesFeedWaitingDuration))
+		assert.Equal(t, float64(0), scoresChangesFeedWaitingQueueLength.Get())
+		assert.Equal(t, float64(0), scoresChangesFeedReadyQueueLength.Get())
+	})
+}
+
+func TestScoresChangesFeedWriterCheckWaiting(t *testing.T) {
+	firstEvent := events.ScoreEvent{
+		Id:           112233,
+		LessonId:     150,
+		DisciplineId: 234,
+		Year:         2028,
+		Semester:     1,
+		SyncedAt:     time.Now(),
+	}
+	sameLessonEvent := firstEvent
+	sameLessonEvent.Id = 112234
+
+	otherLessonEvent := firstEvent
+	otherLessonEvent.Id = 112235
+	otherLessonEvent.LessonId = 151
+
+	expiredEvent := otherLessonEvent
+	expiredEvent.Id = 112236
+	expiredEvent.SyncedAt = time.Now().Add(-DefaultScoresChangesFeedWriterWaitingTimeout * 2)
+
+	lessonExistChecker := NewMockLessonExistCheckerInterface(t)
+	lessonExistChecker.On("Exists", 2028, uint8(1), uint(234), mock.Anything).Return(false)
+	lessonExistChecker.On("ExistsMany", []lessonIdentifier{
+		{Year: 2028, Semester: 1, DisciplineId: 234, LessonId: 150},
+		{Year: 2028, Semester: 1, DisciplineId: 234, LessonId: 151},
+	}).Once().Return([]bool{true, false})
+
+	scoresChangesFeedWriter := NewScoresChangesFeedWriter(newTestLogger(&bytes.Buffer{}), nil, lessonExistChecker, nil)
+	scoresChangesFeedWriter.addToQueue(firstEvent, events.ScoreValue{})
+	scoresChangesFeedWriter.addToQueue(otherLessonEvent, events.ScoreValue{})
+	scoresChangesFeedWriter.addToQueue(sameLessonEvent, events.ScoreValue{})
+	scoresChangesFeedWriter.addToQueue(expiredEvent, events.ScoreValue{})
+
+	scoresChangesFeedWriter.checkWaiting(false)
+
+	assert.Equal(t, 3, len(scoresChangesFeedWriter.readyQueue.queue))
+	assert.Equal(t, firstEvent.Id, scoresChangesFeedWriter.readyQueue.queue[0].Id)
+	assert.Equal(t, sameLessonEvent.Id, scoresChangesFeedWriter.readyQueue.queue[1].Id)
+	assert.Equal(t, expiredEvent.Id, scoresChangesFeedWriter.readyQueue.queue[2].Id)
+
+	scoresChangesFeedWriter.checkWaiting(true)
+	assert.Equal(t, 4, len(scoresChangesFeedWriter.readyQueue.queue))
+	assert.Empty(t, scoresChangesFeedWriter.waitingQueue.queue)
 }
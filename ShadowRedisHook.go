@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+const ShadowRedisQueueSize = 10000
+
+const ShadowRedisWriteTimeout = time.Second * 5
+
+// ShadowRedisHook
+/*
+ * Repeats every successful mutation of primary Redis on shadow Redis (e.g. during migration).
+ * Mutations are queued and written by execute goroutine in the same order, so writers and commits
+ * never wait for shadow. Shadow errors and mutations dropped on full queue are only logged and counted.
+ * Writer is not a part of connectors pool: it is stopped after the last mutation of primary Redis
+ * (connectors drain, feed writer flush and final BgSave), otherwise shadow misses them on every shutdown.
+ */
+type ShadowRedisHook struct {
+	logger     *slog.Logger
+	shadow     redis.UniversalClient
+	queue      chan [][]interface{}
+	stopWriter context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+func newShadowRedisHook(logger *slog.Logger, shadow redis.UniversalClient) *ShadowRedisHook {
+	return &ShadowRedisHook{
+		logger: logger,
+		shadow: shadow,
+		queue:  make(chan [][]interface{}, ShadowRedisQueueSize),
+	}
+}
+
+func (hook *ShadowRedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (hook *ShadowRedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if isShadowedCmd(cmd) {
+			hook.enqueue([][]interface{}{cmd.Args()})
+		}
+
+		return err
+	}
+}
+
+func (hook *ShadowRedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+
+		var batch [][]interface{}
+		for _, cmd := range cmds {
+			if isShadowedCmd(cmd) {
+				batch = append(batch, cmd.Args())
+			}
+		}
+		if len(batch) != 0 {
+			hook.enqueue(batch)
+		}
+
+		return err
+	}
+}
+
+func (hook *ShadowRedisHook) enqueue(batch [][]interface{}) {
+	select {
+	case hook.queue <- batch:
+	default:
+		shadowRedisDroppedCount.Add(len(batch))
+		hook.logger.Warn("shadow redis queue is full, mutations dropped", "count", len(batch))
+	}
+}
+
+// start runs shadow writer in background until stop
+func (hook *ShadowRedisHook) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	hook.stopWriter = cancel
+	hook.wg.Add(1)
+	go hook.execute(ctx, &hook.wg)
+}
+
+// stop writes queued mutations and waits for shadow writer; safe to call on nil receiver
+func (hook *ShadowRedisHook) stop() {
+	if hook == nil || hook.stopWriter == nil {
+		return
+	}
+
+	hook.stopWriter()
+	hook.wg.Wait()
+}
+
+func (hook *ShadowRedisHook) execute(ctx context.Context, wg *sync.WaitGroup) {
+	hook.logger.Info("shadow redis writer started")
+
+	for {
+		select {
+		case batch := <-hook.queue:
+			hook.write(batch)
+
+		case <-ctx.Done():
+			// primary Redis is not mutated anymore, write what is already queued
+			for len(hook.queue) != 0 {
+				hook.write(<-hook.queue)
+			}
+			wg.Done()
+			return
+		}
+	}
+}
+
+func (hook *ShadowRedisHook) write(batch [][]interface{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), ShadowRedisWriteTimeout)
+	defer cancel()
+
+	cmds := make([]*redis.Cmd, len(batch))
+	_, _ = hook.shadow.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, args := range batch {
+			cmds[i] = pipe.Do(ctx, args...)
+		}
+		return nil
+	})
+
+	for i, cmd := range cmds {
+		err := cmd.Err()
+		// shadow has no script in cache, repeat with source
+		if redis.HasErrorPrefix(err, "NOSCRIPT") {
+			if source, exists := redisScriptSources[getCmdScriptHash(cmd)]; exists {
+				args := append([]interface{}{"eval", source}, batch[i][2:]...)
+				err = hook.shadow.Do(ctx, args...).Err()
+			}
+		}
+
+		if err != nil && !errors.Is(err, redis.Nil) {
+			shadowRedisErrorsCount.Inc()
+			hook.logger.Warn("shadow redis write failed", "command", cmd.Name(), "error", err)
+		} else {
+			shadowRedisWritesCount.Inc()
+		}
+	}
+}
+
+// isShadowedCmd returns true for mutations succeeded on primary
+func isShadowedCmd(cmd redis.Cmder) bool {
+	name := cmd.Name()
+	if name == "publish" || (cmd.Err() != nil && !errors.Is(cmd.Err(), redis.Nil)) {
+		return false
+	}
+
+	return isRedisMutationCommand(name)
+}
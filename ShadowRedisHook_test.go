@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestShadowRedisHook(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("successful mutations are queued", func(t *testing.T) {
+		hook := newShadowRedisHook(newTestLogger(&bytes.Buffer{}), nil)
+
+		process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+			if cmd.Name() == "del" {
+				cmd.SetErr(errors.New("expected error"))
+				return cmd.Err()
+			}
+			return nil
+		})
+
+		assert.NoError(t, process(ctx, redis.NewIntCmd(ctx, "hset", "2030:1:lessons:15", "150", "payload")))
+		assert.NoError(t, process(ctx, redis.NewStringCmd(ctx, "hget", "2030:1:lessons:15", "150")))
+		assert.NoError(t, process(ctx, redis.NewIntCmd(ctx, "publish", "channel", "message")))
+		assert.NoError(t, process(ctx, redis.NewStatusCmd(ctx, "ping")))
+		assert.Error(t, process(ctx, redis.NewIntCmd(ctx, "del", "2030:1:lessons:15")))
+
+		processPipeline := hook.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+			cmds[1].SetErr(redis.Nil)
+			return nil
+		})
+		assert.NoError(t, processPipeline(ctx, []redis.Cmder{
+			redis.NewStatusCmd(ctx, "multi"),
+			redis.NewCmd(ctx, "evalsha", "hash", "1", "key"),
+			redis.NewStringCmd(ctx, "get", "key"),
+			redis.NewStringCmd(ctx, "getset", "lessonTypes", "new"),
+			redis.NewIntCmd(ctx, "lpush", "queue", "payload"),
+			redis.NewSliceCmd(ctx, "exec"),
+		}))
+
+		assert.Equal(t, 2, len(hook.queue))
+		assert.Equal(t, [][]interface{}{{"hset", "2030:1:lessons:15", "150", "payload"}}, <-hook.queue)
+		assert.Equal(t, [][]interface{}{
+			{"evalsha", "hash", "1", "key"},
+			{"getset", "lessonTypes", "new"},
+			{"lpush", "queue", "payload"},
+		}, <-hook.queue)
+	})
+
+	t.Run("full queue drops mutations", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		hook := newShadowRedisHook(newTestLogger(out), nil)
+		hook.queue = make(chan [][]interface{}, 1)
+		droppedCountBefore := shadowRedisDroppedCount.Get()
+
+		process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+			return nil
+		})
+		assert.NoError(t, process(ctx, redis.NewIntCmd(ctx, "del", "first")))
+		assert.NoError(t, process(ctx, redis.NewIntCmd(ctx, "del", "second")))
+
+		assert.Equal(t, 1, len(hook.queue))
+		assert.Equal(t, droppedCountBefore+1, shadowRedisDroppedCount.Get())
+		assert.Contains(t, out.String(), "shadow redis queue is full, mutations dropped")
+	})
+
+	t.Run("queued mutations are written to shadow", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		shadow, shadowMock := redismock.NewClientMock()
+		hook := newShadowRedisHook(newTestLogger(out), shadow)
+		scriptHash := getScriptHash(writeScoreScriptSource)
+
+		shadowMock.ExpectDo("hset", "2030:1:lessons:15", "150", "payload").SetVal(int64(1))
+		shadowMock.ExpectDo("evalsha", scriptHash, "1", "key", "arg").SetErr(noScriptError{})
+		shadowMock.ExpectDo("eval", writeScoreScriptSource, "1", "key", "arg").SetVal("")
+		shadowMock.ExpectDo("del", "key").SetErr(errors.New("expected error"))
+
+		writesCountBefore := shadowRedisWritesCount.Get()
+		errorsCountBefore := shadowRedisErrorsCount.Get()
+
+		hook.enqueue([][]interface{}{
+			{"hset", "2030:1:lessons:15", "150", "payload"},
+			{"evalsha", scriptHash, "1", "key", "arg"},
+		})
+		hook.enqueue([][]interface{}{{"del", "key"}})
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		hook.execute(cancelCtx, wg)
+
+		assert.NoError(t, shadowMock.ExpectationsWereMet())
+		assert.Equal(t, 0, len(hook.queue))
+		assert.Equal(t, writesCountBefore+2, shadowRedisWritesCount.Get())
+		assert.Equal(t, errorsCountBefore+1, shadowRedisErrorsCount.Get())
+		assert.Contains(t, out.String(), `msg="shadow redis write failed" command=del error="expected error"`)
+	})
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"strings"
+)
+
+// repairStudentDisciplinesScriptSource
+/*
+ * Adds discipline to student disciplines set when student has scores of discipline and removes it otherwise.
+ * Scores existence is checked inside Redis, so concurrent score write can't be lost between read and repair.
+ *
+ * KEYS: student disciplines, scores of checked disciplines
+ * ARGV: checked disciplines ids, in the same order as scores keys
+ */
+const repairStudentDisciplinesScriptSource = `
+local changed = 0
+for i, disciplineId in ipairs(ARGV) do
+	if redis.call('EXISTS', KEYS[i + 1]) == 1 then
+		changed = changed + redis.call('SADD', KEYS[1], disciplineId)
+	else
+		changed = changed + redis.call('SREM', KEYS[1], disciplineId)
+	end
+end
+return changed
+`
+
+var repairStudentDisciplinesScript = redis.NewScript(repairStudentDisciplinesScriptSource)
+
+type StudentDisciplinesVerifyResult struct {
+	students   int
+	mismatches int
+	repaired   int
+}
+
+// StudentDisciplinesVerifier
+/*
+ * Checks students disciplines sets against existing students scores hashes: set should contain
+ * exactly disciplines, in which student has scores. With repair option sets are rebuilt by Lua script.
+ */
+type StudentDisciplinesVerifier struct {
+	logger  *slog.Logger
+	redis   redis.UniversalClient
+	options VerifyOptions
+}
+
+// setMismatch contains members missed in set and extra set members
+type setMismatch struct {
+	key     string
+	missing []string
+	extra   []string
+}
+
+func (verifier *StudentDisciplinesVerifier) verify(ctx context.Context) (result StudentDisciplinesVerifyResult, err error) {
+	expected, err := verifier.collectStudentDisciplines(ctx)
+
+	reported := 0
+	keys := getSortedMapKeys(expected)
+	for start := 0; start < len(keys) && err == nil; start += verifierScanCount {
+		var mismatches []setMismatch
+		mismatches, err = compareVerifiedSets(ctx, verifier.redis, keys[start:min(start+verifierScanCount, len(keys))], expected)
+		result.mismatches += len(mismatches)
+
+		for _, mismatch := range mismatches {
+			if reported < verifier.options.maxReported {
+				reported++
+				verifier.logger.Warn(
+					"student disciplines mismatch",
+					"key", mismatch.key, "missing", mismatch.missing, "extra", mismatch.extra,
+				)
+			}
+
+			if err == nil && verifier.options.repair {
+				err = verifier.repair(ctx, mismatch)
+				if err == nil {
+					result.repaired++
+				}
+			}
+		}
+	}
+	result.students = len(keys)
+
+	studentDisciplinesMismatchesCount.Add(result.mismatches)
+	studentDisciplinesRepairedCount.Add(result.repaired)
+	verifier.logger.Info(
+		"student disciplines verified",
+		"students", result.students, "mismatches", result.mismatches, "repaired", result.repaired,
+	)
+
+	return result, err
+}
+
+// collectStudentDisciplines returns student disciplines keys with disciplines, in which student has scores
+func (verifier *StudentDisciplinesVerifier) collectStudentDisciplines(ctx context.Context) (map[string]map[string]bool, error) {
+	expected := make(map[string]map[string]bool)
+
+	err := scanVerifiedKeys(ctx, verifier.redis, verifier.options, "scores:*:*", func(key string, parts []string) {
+		// {year}:{semester}:scores:{student}:{discipline}
+		studentDisciplinesKey := fmt.Sprintf("%s:%s:student_disciplines:%s", parts[0], parts[1], parts[3])
+		if expected[studentDisciplinesKey] == nil {
+			expected[studentDisciplinesKey] = make(map[string]bool)
+		}
+		expected[studentDisciplinesKey][parts[4]] = true
+	})
+
+	if err == nil {
+		// sets of students without any scores should be empty
+		err = scanVerifiedKeys(ctx, verifier.redis, verifier.options, "student_disciplines:*", func(key string, _ []string) {
+			if expected[key] == nil {
+				expected[key] = make(map[string]bool)
+			}
+		})
+	}
+
+	return expected, err
+}
+
+// compareVerifiedSets reads sets of keys with one pipeline and returns their mismatches with expected members
+func compareVerifiedSets(
+	ctx context.Context, client redis.UniversalClient, keys []string, expected map[string]map[string]bool,
+) (mismatches []setMismatch, err error) {
+	cmds := make([]*redis.StringSliceCmd, len(keys))
+	_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.SMembers(ctx, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		actual := make(map[string]bool, len(cmds[i].Val()))
+		for _, member := range cmds[i].Val() {
+			actual[member] = true
+		}
+
+		mismatch := setMismatch{key: key}
+		for _, member := range getSortedMapKeys(expected[key]) {
+			if !actual[member] {
+				mismatch.missing = append(mismatch.missing, member)
+			}
+		}
+		for _, member := range getSortedMapKeys(actual) {
+			if !expected[key][member] {
+				mismatch.extra = append(mismatch.extra, member)
+			}
+		}
+
+		if len(mismatch.missing) != 0 || len(mismatch.extra) != 0 {
+			mismatches = append(mismatches, mismatch)
+		}
+	}
+
+	return mismatches, nil
+}
+
+func (verifier *StudentDisciplinesVerifier) repair(ctx context.Context, mismatch setMismatch) error {
+	// {year}:{semester}:student_disciplines:{student} -> {year}:{semester}:scores:{student}:{discipline}
+	parts := strings.Split(mismatch.key, ":")
+	disciplineIds := append(append([]string{}, mismatch.missing...), mismatch.extra...)
+
+	keys := []string{mismatch.key}
+	args := make([]interface{}, len(disciplineIds))
+	for i, disciplineId := range disciplineIds {
+		keys = append(keys, fmt.Sprintf("%s:%s:scores:%s:%s", parts[0], parts[1], parts[3], disciplineId))
+		args[i] = disciplineId
+	}
+
+	changed, err := repairStudentDisciplinesScript.Run(ctx, verifier.redis, keys, args...).Int()
+	if err == nil {
+		verifier.logger.Info("student disciplines repaired", "key", mismatch.key, "changed", changed)
+	}
+
+	return err
+}
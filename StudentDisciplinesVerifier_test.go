@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStudentDisciplinesVerifier(t *testing.T) {
+	ctx := context.Background()
+
+	expectScan := func(redisMock redismock.ClientMock) {
+		redisMock.ExpectScan(0, "2030:1:scores:*:*", verifierScanCount).SetVal(
+			[]string{"2030:1:scores:5:15", "2030:1:scores:5:16", "2030:1:scores:6:15"}, 0,
+		)
+		redisMock.ExpectScan(0, "2030:1:student_disciplines:*", verifierScanCount).SetVal(
+			[]string{"2030:1:student_disciplines:5", "2030:1:student_disciplines:7"}, 0,
+		)
+	}
+
+	t.Run("sets are consistent", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		expectScan(redisMock)
+
+		redisMock.ExpectSMembers("2030:1:student_disciplines:5").SetVal([]string{"16", "15"})
+		redisMock.ExpectSMembers("2030:1:student_disciplines:6").SetVal([]string{"15"})
+		redisMock.ExpectSMembers("2030:1:student_disciplines:7").SetVal([]string{})
+
+		out := &bytes.Buffer{}
+		verifier := &StudentDisciplinesVerifier{
+			logger:  newTestLogger(out),
+			redis:   redisClient,
+			options: VerifyOptions{year: 2030, semester: 1, maxReported: DefaultVerifyMaxReported},
+		}
+		result, err := verifier.verify(ctx)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, StudentDisciplinesVerifyResult{students: 3}, result)
+		assert.Contains(t, out.String(), `msg="student disciplines verified" students=3 mismatches=0 repaired=0`)
+	})
+
+	t.Run("mismatches are repaired", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		expectScan(redisMock)
+
+		redisMock.ExpectSMembers("2030:1:student_disciplines:5").SetVal([]string{"15", "17"})
+		redisMock.ExpectSMembers("2030:1:student_disciplines:6").SetVal([]string{})
+		redisMock.ExpectSMembers("2030:1:student_disciplines:7").SetVal([]string{"15"})
+
+		redisMock.ExpectEvalSha(
+			repairStudentDisciplinesScript.Hash(),
+			[]string{"2030:1:student_disciplines:5", "2030:1:scores:5:16", "2030:1:scores:5:17"}, "16", "17",
+		).SetVal(int64(2))
+		redisMock.ExpectEvalSha(
+			repairStudentDisciplinesScript.Hash(),
+			[]string{"2030:1:student_disciplines:6", "2030:1:scores:6:15"}, "15",
+		).SetVal(int64(1))
+		redisMock.ExpectEvalSha(
+			repairStudentDisciplinesScript.Hash(),
+			[]string{"2030:1:student_disciplines:7", "2030:1:scores:7:15"}, "15",
+		).SetVal(int64(1))
+
+		mismatchesCountBefore := studentDisciplinesMismatchesCount.Get()
+		repairedCountBefore := studentDisciplinesRepairedCount.Get()
+
+		out := &bytes.Buffer{}
+		verifier := &StudentDisciplinesVerifier{
+			logger:  newTestLogger(out),
+			redis:   redisClient,
+			options: VerifyOptions{year: 2030, semester: 1, repair: true, maxReported: 2},
+		}
+		result, err := verifier.verify(ctx)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, StudentDisciplinesVerifyResult{students: 3, mismatches: 3, repaired: 3}, result)
+		assert.Equal(t, mismatchesCountBefore+3, studentDisciplinesMismatchesCount.Get())
+		assert.Equal(t, repairedCountBefore+3, studentDisciplinesRepairedCount.Get())
+
+		assert.Contains(t, out.String(), `msg="student disciplines mismatch" key=2030:1:student_disciplines:5 missing=[16] extra=[17]`)
+		assert.Contains(t, out.String(), `msg="student disciplines mismatch" key=2030:1:student_disciplines:6 missing=[15] extra=[]`)
+		assert.NotContains(t, out.String(), `msg="student disciplines mismatch" key=2030:1:student_disciplines:7`)
+		assert.Contains(t, out.String(), `msg="student disciplines repaired" key=2030:1:student_disciplines:7 changed=1`)
+	})
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultVerifyMaxReported = 100
+
+const totalsScoreTolerance = 1e-6
+
+const verifierScanCount = 1000
+
+// repairStudentTotalScriptSource
+/*
+ * Recomputes student total of discipline from scores hash and stores it to discipline totals.
+ * Total is computed inside Redis, so concurrent score write can't be lost between read and repair.
+ *
+ * KEYS: student discipline scores, discipline totals
+ * ARGV: student id, absent score value
+ */
+const repairStudentTotalScriptSource = `
+local absentValue = tonumber(ARGV[2])
+local total = 0
+for _, value in ipairs(redis.call('HVALS', KEYS[1])) do
+	value = tonumber(value)
+	if value ~= absentValue then
+		total = total + value
+	end
+end
+redis.call('ZADD', KEYS[2], total, ARGV[1])
+return tostring(total)
+`
+
+var repairStudentTotalScript = redis.NewScript(repairStudentTotalScriptSource)
+
+type VerifyOptions struct {
+	year        int
+	semester    int
+	repair      bool
+	maxReported int
+	check       string
+}
+
+type TotalsVerifyResult struct {
+	disciplines int
+	students    int
+	mismatches  int
+	repaired    int
+}
+
+// TotalsVerifier
+/*
+ * Checks discipline totals sorted sets, which are maintained incrementally by ScoreWriter, against totals
+ * recomputed from students scores hashes (absent scores are not counted).
+ * Missed total member is equal to zero total. With repair option wrong members are rewritten by Lua script.
+ * Can be run once by verify command or periodically as service job with execute.
+ */
+type TotalsVerifier struct {
+	logger   *slog.Logger
+	redis    redis.UniversalClient
+	options  VerifyOptions
+	interval time.Duration
+}
+
+func (verifier *TotalsVerifier) execute(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	verifier.logger.Info("totals verifier started", "interval", verifier.interval, "repair", verifier.options.repair)
+
+	ticker := time.NewTicker(verifier.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_, err := verifier.verify(ctx)
+			if err != nil && ctx.Err() == nil {
+				verifier.logger.Error("totals verify failed", "error", err)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (verifier *TotalsVerifier) verify(ctx context.Context) (result TotalsVerifyResult, err error) {
+	totalsKeys, err := verifier.collectTotalsKeys(ctx)
+
+	reported := 0
+	for _, totalsKey := range getSortedMapKeys(totalsKeys) {
+		if err != nil {
+			break
+		}
+
+		var mismatches []totalsMismatch
+		mismatches, err = verifier.verifyDiscipline(ctx, totalsKey, totalsKeys[totalsKey])
+		result.disciplines++
+		result.students += len(totalsKeys[totalsKey])
+		result.mismatches += len(mismatches)
+
+		for _, mismatch := range mismatches {
+			if reported < verifier.options.maxReported {
+				reported++
+				verifier.logger.Warn(
+					"totals mismatch",
+					"key", totalsKey, "studentId", mismatch.studentId,
+					"expected", mismatch.expected, "actual", mismatch.actual, "isMissing", mismatch.isMissing,
+				)
+			}
+
+			if err == nil && verifier.options.repair {
+				err = verifier.repair(ctx, totalsKey, mismatch)
+				if err == nil {
+					result.repaired++
+				}
+			}
+		}
+	}
+
+	totalsMismatchesCount.Add(result.mismatches)
+	totalsRepairedCount.Add(result.repaired)
+	verifier.logger.Info(
+		"totals verified",
+		"disciplines", result.disciplines, "students", result.students,
+		"mismatches", result.mismatches, "repaired", result.repaired,
+	)
+
+	return result, err
+}
+
+// collectTotalsKeys returns discipline totals keys with scores keys of discipline students by student id
+func (verifier *TotalsVerifier) collectTotalsKeys(ctx context.Context) (map[string]map[string]string, error) {
+	totalsKeys := make(map[string]map[string]string)
+
+	err := scanVerifiedKeys(ctx, verifier.redis, verifier.options, "scores:*:*", func(key string, parts []string) {
+		// {year}:{semester}:scores:{student}:{discipline}
+		totalsKey := fmt.Sprintf("%s:%s:totals:%s", parts[0], parts[1], parts[4])
+		if totalsKeys[totalsKey] == nil {
+			totalsKeys[totalsKey] = make(map[string]string)
+		}
+		totalsKeys[totalsKey][parts[3]] = key
+	})
+
+	if err == nil {
+		// totals without any scores should contain only zero totals
+		err = scanVerifiedKeys(ctx, verifier.redis, verifier.options, "totals:*", func(key string, _ []string) {
+			if totalsKeys[key] == nil {
+				totalsKeys[key] = make(map[string]string)
+			}
+		})
+	}
+
+	return totalsKeys, err
+}
+
+type totalsMismatch struct {
+	studentId string
+	expected  float64
+	actual    float64
+	isMissing bool
+}
+
+func (verifier *TotalsVerifier) verifyDiscipline(
+	ctx context.Context, totalsKey string, scoresKeys map[string]string,
+) (mismatches []totalsMismatch, err error) {
+	studentIds := getSortedMapKeys(scoresKeys)
+	scoresCmds := make([]*redis.MapStringStringCmd, len(studentIds))
+	var totalsCmd *redis.ZSliceCmd
+
+	_, err = verifier.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, studentId := range studentIds {
+			scoresCmds[i] = pipe.HGetAll(ctx, scoresKeys[studentId])
+		}
+		totalsCmd = pipe.ZRangeWithScores(ctx, totalsKey, 0, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	actualTotals := make(map[string]float64, len(totalsCmd.Val()))
+	for _, member := range totalsCmd.Val() {
+		actualTotals[fmt.Sprint(member.Member)] = member.Score
+	}
+
+	for i, studentId := range studentIds {
+		expected := calculateScoresTotal(scoresCmds[i].Val())
+		actual, exists := actualTotals[studentId]
+		if (!exists && expected != 0) || math.Abs(expected-actual) > totalsScoreTolerance {
+			mismatches = append(mismatches, totalsMismatch{
+				studentId: studentId, expected: expected, actual: actual, isMissing: !exists,
+			})
+		}
+		delete(actualTotals, studentId)
+	}
+
+	// students without scores hash: all scores were deleted, total should be zero
+	for _, studentId := range getSortedMapKeys(actualTotals) {
+		if math.Abs(actualTotals[studentId]) > totalsScoreTolerance {
+			mismatches = append(mismatches, totalsMismatch{studentId: studentId, actual: actualTotals[studentId]})
+		}
+	}
+
+	return mismatches, nil
+}
+
+func (verifier *TotalsVerifier) repair(ctx context.Context, totalsKey string, mismatch totalsMismatch) error {
+	// {year}:{semester}:totals:{discipline} -> {year}:{semester}:scores:{student}:{discipline}
+	parts := strings.Split(totalsKey, ":")
+	scoresKey := fmt.Sprintf("%s:%s:scores:%s:%s", parts[0], parts[1], mismatch.studentId, parts[3])
+
+	total, err := repairStudentTotalScript.Run(
+		ctx, verifier.redis, []string{scoresKey, totalsKey},
+		mismatch.studentId, formatScoreStorageValue(IsAbsentScoreValue),
+	).Text()
+	if err == nil {
+		verifier.logger.Info("totals repaired", "key", totalsKey, "studentId", mismatch.studentId, "total", total)
+	}
+
+	return err
+}
+
+func calculateScoresTotal(scores map[string]string) float64 {
+	total := float64(0)
+	for _, storedValue := range scores {
+		value, err := strconv.ParseFloat(storedValue, 64)
+		if err == nil && value != IsAbsentScoreValue {
+			total += value
+		}
+	}
+
+	return total
+}
+
+// scanVerifiedKeys calls handle for keys "{year}:{semester}:{suffixPattern}" of verified year and semester
+func scanVerifiedKeys(
+	ctx context.Context, client redis.UniversalClient, options VerifyOptions, suffixPattern string,
+	handle func(key string, parts []string),
+) error {
+	year, semester := "*", "*"
+	if options.year != 0 {
+		year = strconv.Itoa(options.year)
+	}
+	if options.semester != 0 {
+		semester = strconv.Itoa(options.semester)
+	}
+
+	partsCount := strings.Count(suffixPattern, ":") + 3
+	iterator := client.Scan(ctx, 0, year+":"+semester+":"+suffixPattern, verifierScanCount).Iterator()
+	for iterator.Next(ctx) {
+		parts := strings.Split(iterator.Val(), ":")
+		if len(parts) == partsCount {
+			handle(iterator.Val(), parts)
+		}
+	}
+
+	return iterator.Err()
+}
+
+func getSortedMapKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
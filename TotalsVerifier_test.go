@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTotalsVerifier(t *testing.T) {
+	ctx := context.Background()
+
+	expectScan := func(redisMock redismock.ClientMock) {
+		redisMock.ExpectScan(0, "2030:1:scores:*:*", verifierScanCount).SetVal(
+			[]string{"2030:1:scores:5:15", "2030:1:scores:6:15"}, 0,
+		)
+		redisMock.ExpectScan(0, "2030:1:totals:*", verifierScanCount).SetVal(
+			[]string{"2030:1:totals:15", "2030:1:totals:16"}, 0,
+		)
+	}
+
+	t.Run("totals are consistent", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		expectScan(redisMock)
+
+		redisMock.ExpectHGetAll("2030:1:scores:5:15").SetVal(map[string]string{"150:1": "2.5", "151:1": "-999999"})
+		redisMock.ExpectHGetAll("2030:1:scores:6:15").SetVal(map[string]string{"150:1": "0.1", "151:1": "0.2"})
+		redisMock.ExpectZRangeWithScores("2030:1:totals:15", 0, -1).SetVal([]redis.Z{
+			{Score: 2.5, Member: "5"},
+			{Score: 0.30000000000000004, Member: "6"},
+			{Score: 0, Member: "7"},
+		})
+		redisMock.ExpectZRangeWithScores("2030:1:totals:16", 0, -1).SetVal([]redis.Z{{Score: 0, Member: "5"}})
+
+		out := &bytes.Buffer{}
+		verifier := &TotalsVerifier{
+			logger:  newTestLogger(out),
+			redis:   redisClient,
+			options: VerifyOptions{year: 2030, semester: 1, maxReported: DefaultVerifyMaxReported},
+		}
+		result, err := verifier.verify(ctx)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, TotalsVerifyResult{disciplines: 2, students: 2}, result)
+		assert.Contains(t, out.String(), `msg="totals verified" disciplines=2 students=2 mismatches=0 repaired=0`)
+	})
+
+	t.Run("mismatches are repaired", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		expectScan(redisMock)
+
+		redisMock.ExpectHGetAll("2030:1:scores:5:15").SetVal(map[string]string{"150:1": "2.5", "151:1": "1"})
+		redisMock.ExpectHGetAll("2030:1:scores:6:15").SetVal(map[string]string{"150:1": "3"})
+		redisMock.ExpectZRangeWithScores("2030:1:totals:15", 0, -1).SetVal([]redis.Z{{Score: 2.5, Member: "5"}})
+		redisMock.ExpectEvalSha(
+			repairStudentTotalScript.Hash(), []string{"2030:1:scores:5:15", "2030:1:totals:15"}, "5", "-999999",
+		).SetVal("3.5")
+		redisMock.ExpectEvalSha(
+			repairStudentTotalScript.Hash(), []string{"2030:1:scores:6:15", "2030:1:totals:15"}, "6", "-999999",
+		).SetVal("3")
+
+		redisMock.ExpectZRangeWithScores("2030:1:totals:16", 0, -1).SetVal([]redis.Z{{Score: 4, Member: "5"}})
+		redisMock.ExpectEvalSha(
+			repairStudentTotalScript.Hash(), []string{"2030:1:scores:5:16", "2030:1:totals:16"}, "5", "-999999",
+		).SetVal("0")
+
+		mismatchesCountBefore := totalsMismatchesCount.Get()
+		repairedCountBefore := totalsRepairedCount.Get()
+
+		out := &bytes.Buffer{}
+		verifier := &TotalsVerifier{
+			logger:  newTestLogger(out),
+			redis:   redisClient,
+			options: VerifyOptions{year: 2030, semester: 1, repair: true, maxReported: 2},
+		}
+		result, err := verifier.verify(ctx)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, TotalsVerifyResult{disciplines: 2, students: 2, mismatches: 3, repaired: 3}, result)
+		assert.Equal(t, mismatchesCountBefore+3, totalsMismatchesCount.Get())
+		assert.Equal(t, repairedCountBefore+3, totalsRepairedCount.Get())
+
+		assert.Contains(t, out.String(), `msg="totals mismatch" key=2030:1:totals:15 studentId=5 expected=3.5 actual=2.5 isMissing=false`)
+		assert.Contains(t, out.String(), `msg="totals mismatch" key=2030:1:totals:15 studentId=6 expected=3 actual=0 isMissing=true`)
+		assert.NotContains(t, out.String(), `msg="totals mismatch" key=2030:1:totals:16`)
+		assert.Contains(t, out.String(), `msg="totals repaired" key=2030:1:totals:16 studentId=5 total=0`)
+	})
+
+	t.Run("periodic execute", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.MatchExpectationsInOrder(true)
+		redisMock.ExpectScan(0, "*:*:scores:*:*", verifierScanCount).SetVal([]string{}, 0)
+		redisMock.ExpectScan(0, "*:*:totals:*", verifierScanCount).SetVal([]string{}, 0)
+
+		out := &bytes.Buffer{}
+		verifier := &TotalsVerifier{
+			logger:   newTestLogger(out),
+			redis:    redisClient,
+			options:  VerifyOptions{maxReported: DefaultVerifyMaxReported},
+			interval: time.Millisecond * 50,
+		}
+
+		cancelCtx, cancel := context.WithTimeout(ctx, time.Millisecond*80)
+		defer cancel()
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		verifier.execute(cancelCtx, wg)
+		wg.Wait()
+
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Contains(t, out.String(), `msg="totals verifier started"`)
+		assert.Contains(t, out.String(), `msg="totals verified" disciplines=0`)
+	})
+}
@@ -8,3 +8,12 @@ type WriterInterface interface {
 	getExpectedEventType() any
 	write(event any) error
 }
+
+// BatchWriterInterface
+/*
+ * Optional extension of WriterInterface: writes many events at once with pipelined Redis commands.
+ * Result in Redis should be the same as after calling write for each event in order.
+ */
+type BatchWriterInterface interface {
+	writeBatch(events []any) error
+}
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"github.com/kneu-messenger-pigeon/events"
 	"github.com/redis/go-redis/v9"
-	"io"
+	"log/slog"
 )
 
 // YearChangeWriter
@@ -15,7 +15,7 @@ import (
  * Purpose of this writer is to remove all records from Redis related to previous education year
  */
 type YearChangeWriter struct {
-	out                  io.Writer
+	logger               *slog.Logger
 	redis                redis.UniversalClient
 	isValidEducationYear func(int) bool
 }
@@ -26,7 +26,7 @@ func (writer *YearChangeWriter) setRedis(redis redis.UniversalClient) {
 
 func (writer *YearChangeWriter) getExpectedMessageKey() string {
 	// self check on init that we have correct installed writer
-	if writer.isValidEducationYear != nil && writer.out != nil {
+	if writer.isValidEducationYear != nil && writer.logger != nil {
 		return events.CurrentYearEventName
 	}
 
@@ -40,7 +40,7 @@ func (writer *YearChangeWriter) getExpectedEventType() any {
 func (writer *YearChangeWriter) write(s any) (err error) {
 	currentYear := s.(*events.CurrentYearEvent).Year
 	if !writer.isValidEducationYear(currentYear) {
-		fmt.Fprintf(writer.out, "Skip invalid education year: %d\n", currentYear)
+		writer.logger.Warn("skip invalid education year", "year", currentYear)
 		return nil
 	}
 
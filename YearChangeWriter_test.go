@@ -27,7 +27,7 @@ func TestYearChangeWriter(t *testing.T) {
 		redisMock.ExpectGet("currentYear").SetVal("2031")
 
 		yearChangeWriter := YearChangeWriter{
-			out:                  out,
+			logger:               newTestLogger(out),
 			isValidEducationYear: isValidEducationYearMockTrue,
 		}
 
@@ -56,7 +56,7 @@ func TestYearChangeWriter(t *testing.T) {
 		redisMock.ExpectSave().SetVal("OK")
 
 		yearChangeWriter := YearChangeWriter{
-			out:                  out,
+			logger:               newTestLogger(out),
 			isValidEducationYear: isValidEducationYearMockTrue,
 		}
 
@@ -93,7 +93,7 @@ func TestYearChangeWriter(t *testing.T) {
 		redisMock.ExpectSave().SetVal("OK")
 
 		yearChangeWriter := YearChangeWriter{
-			out:                  out,
+			logger:               newTestLogger(out),
 			isValidEducationYear: isValidEducationYearMockTrue,
 		}
 
@@ -126,7 +126,7 @@ func TestYearChangeWriter(t *testing.T) {
 		redisMock.ExpectDel("2030:something:213").SetErr(expectedError)
 
 		yearChangeWriter := YearChangeWriter{
-			out:                  out,
+			logger:               newTestLogger(out),
 			isValidEducationYear: isValidEducationYearMockTrue,
 		}
 
@@ -154,7 +154,7 @@ func TestYearChangeWriter(t *testing.T) {
 		redisMock.ExpectScan(0, "2030:*", 0).SetErr(expectedError)
 
 		yearChangeWriter := YearChangeWriter{
-			out:                  out,
+			logger:               newTestLogger(out),
 			isValidEducationYear: isValidEducationYearMockTrue,
 		}
 
@@ -177,7 +177,7 @@ func TestYearChangeWriter(t *testing.T) {
 		redisMock.MatchExpectationsInOrder(true)
 
 		yearChangeWriter := YearChangeWriter{
-			out:                  out,
+			logger:               newTestLogger(out),
 			isValidEducationYear: isValidEducationYearMockFalse,
 		}
 		yearChangeWriter.setRedis(redis)
@@ -186,14 +186,14 @@ func TestYearChangeWriter(t *testing.T) {
 		assert.NoError(t, err)
 		assert.NoError(t, redisMock.ExpectationsWereMet())
 
-		assert.Contains(t, out.String(), "Skip invalid education year: 2006")
+		assert.Contains(t, out.String(), `msg="skip invalid education year" year=2006`)
 	})
 
 	t.Run("Not set isValidEducationYear", func(t *testing.T) {
 		out := &bytes.Buffer{}
 
 		yearChangeWriter := YearChangeWriter{
-			out: out,
+			logger: newTestLogger(out),
 		}
 
 		assert.Empty(t, yearChangeWriter.getExpectedMessageKey())
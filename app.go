@@ -8,13 +8,54 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/segmentio/kafka-go"
 	"io"
+	"net/http"
 	"os"
 	"time"
 )
 
 const ExitCodeMainError = 1
 
-func runApp(out io.Writer) error {
+const (
+	CommandServe            = "serve"
+	CommandRebuild          = "rebuild"
+	CommandDryRun           = "dry-run"
+	CommandCompare          = "compare"
+	CommandVerify           = "verify"
+	CommandNormalizePreview = "normalize-preview"
+	CommandRenormalize      = "renormalize"
+	CommandNameOverride     = "name-override"
+)
+
+// runCommand runs the service by default or one of maintenance commands given as first argument
+func runCommand(out io.Writer, args []string) error {
+	command := CommandServe
+	if len(args) != 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case CommandServe:
+		return runApp(out)
+	case CommandRebuild:
+		return runRebuild(out, args)
+	case CommandDryRun:
+		return runDryRun(out, args)
+	case CommandCompare:
+		return runCompare(out, args)
+	case CommandVerify:
+		return runVerify(out, args)
+	case CommandNormalizePreview:
+		return runNormalizePreview(out, args)
+	case CommandRenormalize:
+		return runRenormalize(out, args)
+	case CommandNameOverride:
+		return runNameOverride(out, args)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func loadAppConfig() (Config, *redis.Options, error) {
 	var opt *redis.Options
 
 	envFilename := ""
@@ -26,186 +67,197 @@ func runApp(out io.Writer) error {
 	if err == nil {
 		opt, err = redis.ParseURL(config.redisDsn)
 	}
+
+	var nameRules *DisciplineNameRules
+	if err == nil {
+		nameRules, err = loadDisciplineNameRules(config.nameRulesFile)
+	}
+	if err == nil {
+		setDisciplineNameRules(nameRules)
+	}
+
+	return config, opt, err
+}
+
+func runApp(out io.Writer) error {
+	config, opt, err := loadAppConfig()
 	victoriaMetricsInit.InitMetrics("storage-writer")
 
 	if err != nil {
 		return err
 	}
 
+	logger := newLogger(out, config.logLevel, config.logFormat)
 	redisClient := redis.NewClient(opt)
-	groupId := "storage-writer"
 
+	var connectorsPool []ConnectorInterface
+	var shadowRedisHook *ShadowRedisHook
+	if config.redisShadowDsn != "" {
+		shadowOpt, err := redis.ParseURL(config.redisShadowDsn)
+		if err != nil {
+			return fmt.Errorf("invalid REDIS_SHADOW_DSN: %w", err)
+		}
+
+		shadowRedisClient := redis.NewClient(shadowOpt)
+		defer shadowRedisClient.Close()
+
+		shadowRedisHook = newShadowRedisHook(logger, shadowRedisClient)
+		redisClient.AddHook(shadowRedisHook)
+		shadowRedisHook.start()
+	}
+
+	lessonExistChecker := newLessonExistChecker(logger, redisClient)
 	scoresChangesFeedWriter := NewScoresChangesFeedWriter(
-		out,
+		logger,
 		&kafka.Writer{
 			Addr:     kafka.TCP(config.kafkaHost),
 			Topic:    events.ScoresChangesFeedTopic,
 			Balancer: &kafka.Murmur2Balancer{},
 		},
-		newLessonExistChecker(redisClient),
+		lessonExistChecker,
+		newScoresChangesFeedStorage(redisClient, config.instanceId),
 	)
 
-	scoreWriter := &ScoreWriter{
-		scoresChangesFeedWriter: scoresChangesFeedWriter,
+	var deadLetterWriter DeadLetterWriterInterface
+	if config.deadLetterTopic != "" {
+		deadLetterWriter = &DeadLetterWriter{
+			writer: &kafka.Writer{
+				Addr:     kafka.TCP(config.kafkaHost),
+				Topic:    config.deadLetterTopic,
+				Balancer: &kafka.Murmur2Balancer{},
+			},
+			maxAttempts: config.deadLetterAttempts,
+		}
 	}
 
-	scoreConnector1 := &KafkaToRedisConnector{
-		out:    out,
-		redis:  redisClient,
-		writer: scoreWriter,
-		reader: kafka.NewReader(
-			kafka.ReaderConfig{
-				Brokers:     []string{config.kafkaHost},
-				GroupID:     groupId,
-				Topic:       events.RawScoresTopic,
-				MinBytes:    10,
-				MaxBytes:    10e3,
-				MaxWait:     time.Second,
-				MaxAttempts: config.kafkaAttempts,
-				Dialer: &kafka.Dialer{
-					Timeout:   config.kafkaTimeout,
-					DualStack: kafka.DefaultDialer.DualStack,
-				},
-			},
-		),
-	}
-
-	scoreConnector2 := &KafkaToRedisConnector{
-		out:    out,
-		redis:  redisClient,
-		writer: scoreWriter,
-		reader: kafka.NewReader(
-			kafka.ReaderConfig{
-				Brokers:     []string{config.kafkaHost},
-				GroupID:     groupId,
-				Topic:       events.RawScoresTopic,
-				MinBytes:    10,
-				MaxBytes:    10e3,
-				MaxWait:     time.Second,
-				MaxAttempts: config.kafkaAttempts,
-				Dialer: &kafka.Dialer{
-					Timeout:   config.kafkaTimeout,
-					DualStack: kafka.DefaultDialer.DualStack,
-				},
-			},
-		),
-	}
-
-	lessonWriter := &LessonWriter{}
-
-	lessonConnector1 := &KafkaToRedisConnector{
-		out:    out,
-		redis:  redisClient,
-		writer: lessonWriter,
-		reader: kafka.NewReader(
-			kafka.ReaderConfig{
-				Brokers:     []string{config.kafkaHost},
-				GroupID:     groupId,
-				Topic:       events.RawLessonsTopic,
-				MinBytes:    10,
-				MaxBytes:    10e3,
-				MaxWait:     time.Second,
-				MaxAttempts: config.kafkaAttempts,
-				Dialer: &kafka.Dialer{
-					Timeout:   config.kafkaTimeout,
-					DualStack: kafka.DefaultDialer.DualStack,
-				},
-			},
-		),
-	}
-
-	lessonConnector2 := &KafkaToRedisConnector{
-		out:    out,
-		redis:  redisClient,
-		writer: lessonWriter,
-		reader: kafka.NewReader(
-			kafka.ReaderConfig{
-				Brokers:     []string{config.kafkaHost},
-				GroupID:     groupId,
-				Topic:       events.RawLessonsTopic,
-				MinBytes:    10,
-				MaxBytes:    10e3,
-				MaxWait:     time.Second,
-				MaxAttempts: config.kafkaAttempts,
-				Dialer: &kafka.Dialer{
-					Timeout:   config.kafkaTimeout,
-					DualStack: kafka.DefaultDialer.DualStack,
-				},
-			},
-		),
-	}
-
-	disciplineConnector := &KafkaToRedisConnector{
-		out:    out,
-		redis:  redisClient,
-		writer: &DisciplineWriter{},
-		reader: kafka.NewReader(
-			kafka.ReaderConfig{
-				Brokers:     []string{config.kafkaHost},
-				GroupID:     groupId,
-				Topic:       events.DisciplinesTopic,
-				MinBytes:    10,
-				MaxBytes:    10e3,
-				MaxWait:     time.Second,
-				MaxAttempts: config.kafkaAttempts,
-				Dialer: &kafka.Dialer{
-					Timeout:   config.kafkaTimeout,
-					DualStack: kafka.DefaultDialer.DualStack,
-				},
-			},
-		),
+	var lessonWrittenNotifier LessonWrittenNotifierInterface = scoresChangesFeedWriter
+	if config.lessonPubSub {
+		redisLessonWrittenNotifier := newRedisLessonWrittenNotifier(
+			logger, redisClient, scoresChangesFeedWriter, lessonExistChecker,
+		)
+		lessonWrittenNotifier = redisLessonWrittenNotifier
+		connectorsPool = append(connectorsPool, redisLessonWrittenNotifier)
 	}
 
-	metaEventsConnector := &KafkaToRedisMetaEventsConnector{
-		out:   out,
-		redis: redisClient,
-		currentYearWriter: &YearChangeWriter{
-			out:                  out,
-			isValidEducationYear: isValidEducationYear,
-		},
-		lessonTypesListWriter: &LessonTypesListWriter{},
-		reader: kafka.NewReader(
-			kafka.ReaderConfig{
-				Brokers:     []string{config.kafkaHost},
-				GroupID:     groupId,
-				Topic:       events.MetaEventsTopic,
-				MinBytes:    10,
-				MaxBytes:    10e3,
-				MaxWait:     time.Second,
-				MaxAttempts: config.kafkaAttempts,
-				Dialer: &kafka.Dialer{
-					Timeout:   config.kafkaTimeout,
-					DualStack: kafka.DefaultDialer.DualStack,
-				},
-			},
-		),
+	connectorsFactory := newConnectorsFactory(
+		logger, redisClient, scoresChangesFeedWriter, lessonExistChecker, lessonWrittenNotifier, deadLetterWriter,
+		config.deletedScoresFeed,
+	)
+
+	var disciplineNamesRenormalizer *DisciplineNamesRenormalizer
+	if config.renormalizeNames {
+		disciplineNamesRenormalizer = newDisciplineNamesRenormalizer(logger, redisClient)
+		connectorsPool = append(connectorsPool, disciplineNamesRenormalizer)
+	}
+
+	if config.nameRulesFile != "" {
+		connectorsPool = append(
+			connectorsPool,
+			newDisciplineNameRulesReloader(logger, config.nameRulesFile, disciplineNamesRenormalizer),
+		)
+	}
+
+	if config.verifyInterval != 0 {
+		connectorsPool = append(connectorsPool, &TotalsVerifier{
+			logger:   logger,
+			redis:    redisClient,
+			options:  VerifyOptions{repair: config.verifyRepair, maxReported: DefaultVerifyMaxReported},
+			interval: config.verifyInterval,
+		})
+	}
+
+	var readers []*kafka.Reader
+	var connectorStatuses []*ConnectorStatus
+
+	for _, entry := range config.topology {
+		for i := 0; i < entry.Readers; i++ {
+			reader := newTopologyKafkaReader(config, entry)
+			readers = append(readers, reader)
+			name := fmt.Sprintf("%s#%d", entry.Writer, i)
+			lagTracker := newConsumerLagTracker(
+				logger, name, entry.Topic, config.lagWarnThreshold, config.lagWarnDuration, reader,
+			)
+			status := newConnectorStatus(name, entry.Topic, lagTracker)
+			connectorStatuses = append(connectorStatuses, status)
+
+			connectorsPool = append(
+				connectorsPool,
+				connectorsFactory.newConnector(entry, reader, status, newConnectorMetrics(name, entry.Writer)),
+				lagTracker,
+			)
+		}
 	}
 
 	eventLoop := EventLoop{
-		connectorsPool: [ConnectorPoolSize]ConnectorInterface{
-			scoreConnector1,
-			scoreConnector2,
-			lessonConnector1,
-			lessonConnector2,
-			disciplineConnector,
-			metaEventsConnector,
-		},
+		logger:                  logger,
+		connectorsPool:          connectorsPool,
 		scoresChangesFeedWriter: scoresChangesFeedWriter,
+		shutdownTimeout:         config.shutdownTimeout,
+	}
+
+	var httpServer *http.Server
+	if config.httpListenAddr != "" {
+		healthServer := &HealthServer{
+			redis:                   redisClient,
+			connectors:              connectorStatuses,
+			scoresChangesFeedHealth: &scoresChangesFeedWriter.health,
+			readinessThreshold:      config.readinessThreshold,
+		}
+		httpServer = &http.Server{
+			Addr:              config.httpListenAddr,
+			Handler:           healthServer.handler(),
+			ReadHeaderTimeout: time.Second * 5,
+		}
+		go func() {
+			err := httpServer.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error("http server error", "addr", config.httpListenAddr, "error", err)
+			}
+		}()
 	}
 
 	defer func() {
-		redisClient.BgSave(context.Background())
-		_ = redisClient.Close()
+		if httpServer != nil {
+			_ = httpServer.Shutdown(context.Background())
+		}
 
-		_ = scoreConnector1.reader.Close()
-		_ = scoreConnector2.reader.Close()
-		_ = lessonConnector1.reader.Close()
-		_ = disciplineConnector.reader.Close()
+		closeRedis(redisClient, shadowRedisHook)
+
+		for _, reader := range readers {
+			_ = reader.Close()
+		}
 	}()
 	eventLoop.execute()
 	return nil
 }
 
+// closeRedis saves primary Redis and closes client; shadow writer is stopped after BgSave, the last mutation
+func closeRedis(redisClient *redis.Client, shadowRedisHook *ShadowRedisHook) {
+	redisClient.BgSave(context.Background())
+	shadowRedisHook.stop()
+	_ = redisClient.Close()
+}
+
+func newTopologyKafkaReader(config Config, entry ConnectorTopologyEntry) *kafka.Reader {
+	return kafka.NewReader(newTopologyKafkaReaderConfig(config, entry))
+}
+
+func newTopologyKafkaReaderConfig(config Config, entry ConnectorTopologyEntry) kafka.ReaderConfig {
+	return kafka.ReaderConfig{
+		Brokers:     []string{config.kafkaHost},
+		GroupID:     entry.GroupId,
+		Topic:       entry.Topic,
+		MinBytes:    entry.MinBytes,
+		MaxBytes:    entry.MaxBytes,
+		MaxWait:     time.Duration(entry.MaxWait),
+		MaxAttempts: config.kafkaAttempts,
+		Dialer: &kafka.Dialer{
+			Timeout:   config.kafkaTimeout,
+			DualStack: kafka.DefaultDialer.DualStack,
+		},
+	}
+}
+
 func handleExitError(errStream io.Writer, err error) int {
 	if err != nil {
 		_, _ = fmt.Fprintln(errStream, err)
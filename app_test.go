@@ -2,10 +2,13 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	victoriaMetricsInit "github.com/kneu-messenger-pigeon/victoria-metrics-init"
+	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
+	"net/http"
 	"os"
 	"runtime"
 	"strconv"
@@ -24,6 +27,8 @@ func TestRunApp(t *testing.T) {
 		_ = os.Setenv("KAFKA_HOST", MOCK_KAFKA_SERVER_ADDR)
 		_ = os.Setenv("REDIS_DSN", expectedConfig.redisDsn)
 		_ = os.Setenv("KAFKA_TIMEOUT", strconv.Itoa(int(expectedConfig.kafkaTimeout.Seconds())))
+		_ = os.Setenv("HTTP_LISTEN_ADDR", "127.0.0.1:18080")
+		defer os.Unsetenv("HTTP_LISTEN_ADDR")
 
 		var out bytes.Buffer
 
@@ -36,7 +41,7 @@ func TestRunApp(t *testing.T) {
 
 		runtime.Gosched()
 
-		expectedStartedMessageCount := ConnectorPoolSize + 2
+		expectedStartedMessageCount := getDefaultConnectorTopology().getReadersCount() + 2
 		maxEndTime := time.Now().Add(time.Second * 30)
 
 		ticker := time.NewTicker(time.Second)
@@ -60,6 +65,12 @@ func TestRunApp(t *testing.T) {
 		}
 		ticker.Stop()
 
+		healthzResponse, err := http.Get("http://127.0.0.1:18080/healthz")
+		if assert.NoError(t, err) {
+			_ = healthzResponse.Body.Close()
+			assert.Equal(t, http.StatusOK, healthzResponse.StatusCode)
+		}
+
 		_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
 		runtime.Gosched()
 		time.Sleep(time.Second)
@@ -70,12 +81,12 @@ func TestRunApp(t *testing.T) {
 		outputString := out.String()
 		fmt.Println(outputString)
 
-		assert.Contains(t, outputString, "*main.KafkaToRedisMetaEventsConnector connector started")
-		assert.Contains(t, outputString, "*main.LessonWriter connector started")
-		assert.Contains(t, outputString, "*main.DisciplineWriter connector started")
-		assert.Contains(t, outputString, "*main.ScoreWriter connector started")
-		assert.Equal(t, 2, strings.Count(outputString, "*main.LessonWriter connector started"))
-		assert.Equal(t, 2, strings.Count(outputString, "*main.ScoreWriter connector started"))
+		assert.Contains(t, outputString, `msg="connector started" connector=*main.KafkaToRedisMetaEventsConnector`)
+		assert.Contains(t, outputString, `msg="connector started" connector=*main.LessonWriter`)
+		assert.Contains(t, outputString, `msg="connector started" connector=*main.DisciplineWriter`)
+		assert.Contains(t, outputString, `msg="connector started" connector=*main.ScoreWriter`)
+		assert.Equal(t, 2, strings.Count(outputString, `msg="connector started" connector=*main.LessonWriter`))
+		assert.Equal(t, 2, strings.Count(outputString, `msg="connector started" connector=*main.ScoreWriter`))
 		assert.False(t, running)
 
 		assert.Equal(t, "storage-writer", victoriaMetricsInit.LastInstance)
@@ -128,6 +139,11 @@ func TestRunApp(t *testing.T) {
 	})
 }
 
+func TestRunCommand(t *testing.T) {
+	err := runCommand(&bytes.Buffer{}, []string{"unknown"})
+	assert.EqualError(t, err, `unknown command "unknown"`)
+}
+
 func TestHandleExitError(t *testing.T) {
 	t.Run("Handle exit error", func(t *testing.T) {
 		var actualExitCode int
@@ -155,3 +171,35 @@ func TestHandleExitError(t *testing.T) {
 		}
 	})
 }
+
+func TestCloseRedis(t *testing.T) {
+	t.Run("shadow writer is stopped after the last mutation", func(t *testing.T) {
+		_, redisClient := newMiniRedisClient(t)
+		shadowServer, shadowRedisClient := newMiniRedisClient(t)
+
+		shadowRedisHook := newShadowRedisHook(newTestLogger(&bytes.Buffer{}), shadowRedisClient)
+		redisClient.AddHook(shadowRedisHook)
+		shadowRedisHook.start()
+
+		// e.g. last commits of draining connectors, queued right before shutdown
+		for i := 0; i < 100; i++ {
+			redisClient.HSet(context.Background(), "2030:1:lessons:15", strconv.Itoa(i), "payload")
+		}
+
+		closeRedis(redisClient, shadowRedisHook)
+
+		shadowKeys, _ := shadowServer.HKeys("2030:1:lessons:15")
+		assert.Len(t, shadowKeys, 100)
+		assert.Empty(t, shadowRedisHook.queue)
+		assert.ErrorIs(t, redisClient.Ping(context.Background()).Err(), redis.ErrClosed)
+	})
+
+	t.Run("without shadow", func(t *testing.T) {
+		_, redisClient := newMiniRedisClient(t)
+
+		assert.NotPanics(t, func() {
+			closeRedis(redisClient, nil)
+		})
+		assert.ErrorIs(t, redisClient.Ping(context.Background()).Err(), redis.ErrClosed)
+	})
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+)
+
+const DefaultCompareSampleSize = 1000
+
+const DefaultCompareMaxReported = 100
+
+const compareMissingValue = "(nil)"
+
+type CompareOptions struct {
+	sampleSize  int
+	maxReported int
+	year        int
+}
+
+type compareKeyKind struct {
+	name    string
+	pattern string
+	isZSet  bool
+}
+
+var compareKeyKinds = []compareKeyKind{
+	{name: "scores", pattern: "%s:*:scores:*:*"},
+	{name: "totals", pattern: "%s:*:totals:*", isZSet: true},
+	{name: "disciplines", pattern: "%s:discipline:*"},
+}
+
+type CompareKindResult struct {
+	checked   int
+	different int
+	missing   int
+}
+
+// runCompare
+/*
+ * Compares samples of scores hashes, totals sorted sets and discipline hashes of primary Redis with shadow Redis.
+ * Up to -sample keys of each kind are sampled uniformly from all keys of primary.
+ * Returns error when any difference is found.
+ */
+func runCompare(out io.Writer, args []string) error {
+	options, err := parseCompareOptions(args, out)
+	if err != nil {
+		return err
+	}
+
+	config, opt, err := loadAppConfig()
+	if err == nil && config.redisShadowDsn == "" {
+		err = errors.New("empty REDIS_SHADOW_DSN")
+	}
+	var shadowOpt *redis.Options
+	if err == nil {
+		shadowOpt, err = redis.ParseURL(config.redisShadowDsn)
+	}
+	if err != nil {
+		return err
+	}
+
+	primary := redis.NewClient(opt)
+	defer primary.Close()
+	shadow := redis.NewClient(shadowOpt)
+	defer shadow.Close()
+
+	comparator := &RedisComparator{
+		logger:      newLogger(out, config.logLevel, config.logFormat),
+		primary:     primary,
+		shadow:      shadow,
+		maxReported: options.maxReported,
+	}
+
+	return comparator.compare(context.Background(), options)
+}
+
+func parseCompareOptions(args []string, out io.Writer) (CompareOptions, error) {
+	options := CompareOptions{}
+
+	flagSet := flag.NewFlagSet(CommandCompare, flag.ContinueOnError)
+	flagSet.SetOutput(out)
+	flagSet.IntVar(&options.sampleSize, "sample", DefaultCompareSampleSize, "max count of compared keys of each kind")
+	flagSet.IntVar(&options.maxReported, "max-report", DefaultCompareMaxReported, "max count of logged differences")
+	flagSet.IntVar(&options.year, "year", 0, "compare keys of education year only")
+
+	err := flagSet.Parse(args)
+	if err == nil && options.sampleSize < 1 {
+		err = errors.New("-sample should be positive")
+	}
+
+	return options, err
+}
+
+type RedisComparator struct {
+	logger      *slog.Logger
+	primary     redis.UniversalClient
+	shadow      redis.UniversalClient
+	maxReported int
+	reported    int
+}
+
+func (comparator *RedisComparator) compare(ctx context.Context, options CompareOptions) error {
+	yearPattern := "*"
+	if options.year != 0 {
+		yearPattern = strconv.Itoa(options.year)
+	}
+
+	differentCount := 0
+	for _, kind := range compareKeyKinds {
+		result, err := comparator.compareKind(ctx, kind, fmt.Sprintf(kind.pattern, yearPattern), options.sampleSize)
+		if err != nil {
+			return fmt.Errorf("compare %s: %w", kind.name, err)
+		}
+
+		differentCount += result.different
+		comparator.logger.Info(
+			"compare: summary",
+			"kind", kind.name, "checked", result.checked, "different", result.different, "missingOnShadow", result.missing,
+		)
+	}
+
+	if differentCount != 0 {
+		return fmt.Errorf("found %d different keys", differentCount)
+	}
+
+	return nil
+}
+
+func (comparator *RedisComparator) compareKind(
+	ctx context.Context, kind compareKeyKind, pattern string, sampleSize int,
+) (result CompareKindResult, err error) {
+	keys, err := sampleKeys(ctx, comparator.primary, pattern, sampleSize)
+	if err != nil {
+		return result, err
+	}
+
+	for _, key := range keys {
+		var primaryValues, shadowValues map[string]string
+		primaryValues, err = comparator.load(ctx, comparator.primary, kind, key)
+		if err == nil {
+			shadowValues, err = comparator.load(ctx, comparator.shadow, kind, key)
+		}
+		if err != nil {
+			return result, err
+		}
+
+		result.checked++
+		if len(shadowValues) == 0 && len(primaryValues) != 0 {
+			result.missing++
+		}
+		if comparator.diff(kind, key, primaryValues, shadowValues) {
+			result.different++
+		}
+	}
+
+	return result, nil
+}
+
+// sampleKeys
+/*
+ * Returns up to sampleSize keys matching pattern, chosen uniformly with reservoir sampling over full SCAN,
+ * so sample isn't biased to keys, which SCAN returns first (e.g. the same disciplines on every run).
+ */
+func sampleKeys(ctx context.Context, client redis.UniversalClient, pattern string, sampleSize int) ([]string, error) {
+	keys := make([]string, 0, sampleSize)
+	seen := 0
+
+	iterator := client.Scan(ctx, 0, pattern, int64(sampleSize)).Iterator()
+	for iterator.Next(ctx) {
+		seen++
+		if len(keys) < sampleSize {
+			keys = append(keys, iterator.Val())
+		} else if i := rand.IntN(seen); i < sampleSize {
+			keys[i] = iterator.Val()
+		}
+	}
+
+	return keys, iterator.Err()
+}
+
+// load returns hash fields or sorted set members with scores
+func (comparator *RedisComparator) load(
+	ctx context.Context, client redis.UniversalClient, kind compareKeyKind, key string,
+) (map[string]string, error) {
+	if !kind.isZSet {
+		return client.HGetAll(ctx, key).Result()
+	}
+
+	members, err := client.ZRangeWithScores(ctx, key, 0, -1).Result()
+	values := make(map[string]string, len(members))
+	for _, member := range members {
+		values[fmt.Sprint(member.Member)] = strconv.FormatFloat(member.Score, 'f', -1, 64)
+	}
+
+	return values, err
+}
+
+func (comparator *RedisComparator) diff(kind compareKeyKind, key string, primaryValues, shadowValues map[string]string) bool {
+	isDifferent := false
+	report := func(field string, primaryValue string, shadowValue string) {
+		isDifferent = true
+		if comparator.reported < comparator.maxReported {
+			comparator.reported++
+			comparator.logger.Warn(
+				"compare: difference",
+				"kind", kind.name, "key", key, "field", field, "primary", primaryValue, "shadow", shadowValue,
+			)
+		}
+	}
+
+	for field, primaryValue := range primaryValues {
+		shadowValue, exists := shadowValues[field]
+		if !exists {
+			shadowValue = compareMissingValue
+		}
+		if shadowValue != primaryValue {
+			report(field, primaryValue, shadowValue)
+		}
+	}
+	for field, shadowValue := range shadowValues {
+		if _, exists := primaryValues[field]; !exists {
+			report(field, compareMissingValue, shadowValue)
+		}
+	}
+
+	return isDifferent
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRedisComparator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no differences", func(t *testing.T) {
+		primary, primaryMock := redismock.NewClientMock()
+		shadow, shadowMock := redismock.NewClientMock()
+
+		primaryMock.ExpectScan(0, "2030:*:scores:*:*", 10).SetVal([]string{"2030:1:scores:5:15"}, 0)
+		primaryMock.ExpectHGetAll("2030:1:scores:5:15").SetVal(map[string]string{"150:1": "3"})
+		shadowMock.ExpectHGetAll("2030:1:scores:5:15").SetVal(map[string]string{"150:1": "3"})
+
+		primaryMock.ExpectScan(0, "2030:*:totals:*", 10).SetVal([]string{"2030:1:totals:15"}, 0)
+		primaryMock.ExpectZRangeWithScores("2030:1:totals:15", 0, -1).SetVal([]redis.Z{{Score: 2.5, Member: "5"}})
+		shadowMock.ExpectZRangeWithScores("2030:1:totals:15", 0, -1).SetVal([]redis.Z{{Score: 2.5, Member: "5"}})
+
+		primaryMock.ExpectScan(0, "2030:discipline:*", 10).SetVal([]string{}, 0)
+
+		out := &bytes.Buffer{}
+		comparator := &RedisComparator{logger: newTestLogger(out), primary: primary, shadow: shadow, maxReported: 10}
+		err := comparator.compare(ctx, CompareOptions{sampleSize: 10, year: 2030})
+
+		assert.NoError(t, err)
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+		assert.NoError(t, shadowMock.ExpectationsWereMet())
+		assert.Contains(t, out.String(), `msg="compare: summary" kind=scores checked=1 different=0 missingOnShadow=0`)
+		assert.Contains(t, out.String(), `msg="compare: summary" kind=totals checked=1 different=0 missingOnShadow=0`)
+		assert.Contains(t, out.String(), `msg="compare: summary" kind=disciplines checked=0 different=0 missingOnShadow=0`)
+	})
+
+	t.Run("differences are reported", func(t *testing.T) {
+		primary, primaryMock := redismock.NewClientMock()
+		shadow, shadowMock := redismock.NewClientMock()
+
+		primaryMock.ExpectScan(0, "*:*:scores:*:*", 2).SetVal([]string{"2030:1:scores:5:15", "2030:1:scores:6:15"}, 0)
+		primaryMock.ExpectHGetAll("2030:1:scores:5:15").SetVal(map[string]string{"150:1": "3"})
+		shadowMock.ExpectHGetAll("2030:1:scores:5:15").SetVal(map[string]string{"150:1": "2", "151:1": "1"})
+		primaryMock.ExpectHGetAll("2030:1:scores:6:15").SetVal(map[string]string{"150:1": "4"})
+		shadowMock.ExpectHGetAll("2030:1:scores:6:15").SetVal(map[string]string{})
+
+		primaryMock.ExpectScan(0, "*:*:totals:*", 2).SetVal([]string{"2030:1:totals:15"}, 0)
+		primaryMock.ExpectZRangeWithScores("2030:1:totals:15", 0, -1).SetVal([]redis.Z{{Score: 2.5, Member: "5"}})
+		shadowMock.ExpectZRangeWithScores("2030:1:totals:15", 0, -1).SetVal([]redis.Z{{Score: 3, Member: "5"}})
+
+		primaryMock.ExpectScan(0, "*:discipline:*", 2).SetVal([]string{}, 0)
+
+		out := &bytes.Buffer{}
+		comparator := &RedisComparator{logger: newTestLogger(out), primary: primary, shadow: shadow, maxReported: 3}
+		err := comparator.compare(ctx, CompareOptions{sampleSize: 2})
+
+		assert.EqualError(t, err, "found 3 different keys")
+		assert.NoError(t, primaryMock.ExpectationsWereMet())
+		assert.NoError(t, shadowMock.ExpectationsWereMet())
+
+		assert.Contains(t, out.String(), `msg="compare: difference" kind=scores key=2030:1:scores:5:15 field=150:1 primary=3 shadow=2`)
+		assert.Contains(t, out.String(), `msg="compare: difference" kind=scores key=2030:1:scores:5:15 field=151:1 primary=(nil) shadow=1`)
+		assert.Contains(t, out.String(), `msg="compare: difference" kind=scores key=2030:1:scores:6:15 field=150:1 primary=4 shadow=(nil)`)
+		assert.NotContains(t, out.String(), `kind=totals key=`)
+		assert.Contains(t, out.String(), `msg="compare: summary" kind=scores checked=2 different=2 missingOnShadow=1`)
+		assert.Contains(t, out.String(), `msg="compare: summary" kind=totals checked=1 different=1 missingOnShadow=0`)
+	})
+
+	t.Run("keys are sampled from all matched keys", func(t *testing.T) {
+		server, client := newMiniRedisClient(t)
+		for discipline := 0; discipline < 50; discipline++ {
+			server.Set(fmt.Sprintf("2030:discipline:%d", discipline), "name")
+		}
+		server.Set("2031:discipline:1", "name")
+
+		sampled := make(map[string]bool)
+		for i := 0; i < 20; i++ {
+			keys, err := sampleKeys(ctx, client, "2030:discipline:*", 5)
+			assert.NoError(t, err)
+			assert.Len(t, keys, 5)
+			for _, key := range keys {
+				sampled[key] = true
+			}
+		}
+
+		assert.Greater(t, len(sampled), 5)
+		assert.NotContains(t, sampled, "2031:discipline:1")
+	})
+
+	t.Run("parse options", func(t *testing.T) {
+		options, err := parseCompareOptions([]string{"-sample", "5", "-year", "2030"}, &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Equal(t, CompareOptions{sampleSize: 5, maxReported: DefaultCompareMaxReported, year: 2030}, options)
+
+		_, err = parseCompareOptions([]string{"-sample", "0"}, &bytes.Buffer{})
+		assert.EqualError(t, err, "-sample should be positive")
+	})
+}
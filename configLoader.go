@@ -4,16 +4,35 @@ import (
 	"errors"
 	"fmt"
 	"github.com/joho/godotenv"
+	"log/slog"
 	"os"
 	"strconv"
 	"time"
 )
 
 type Config struct {
-	redisDsn      string
-	kafkaHost     string
-	kafkaTimeout  time.Duration
-	kafkaAttempts int
+	redisDsn           string
+	redisShadowDsn     string
+	kafkaHost          string
+	kafkaTimeout       time.Duration
+	kafkaAttempts      int
+	deadLetterTopic    string
+	deadLetterAttempts int
+	topology           ConnectorTopology
+	shutdownTimeout    time.Duration
+	lessonPubSub       bool
+	logLevel           slog.Level
+	logFormat          string
+	httpListenAddr     string
+	readinessThreshold time.Duration
+	lagWarnThreshold   int64
+	lagWarnDuration    time.Duration
+	verifyInterval     time.Duration
+	verifyRepair       bool
+	deletedScoresFeed  bool
+	nameRulesFile      string
+	renormalizeNames   bool
+	instanceId         string
 }
 
 func loadConfig(envFilename string) (Config, error) {
@@ -34,11 +53,82 @@ func loadConfig(envFilename string) (Config, error) {
 		kafkaAttempts = 0
 	}
 
+	deadLetterAttempts, err := strconv.Atoi(os.Getenv("KAFKA_DEAD_LETTER_ATTEMPTS"))
+	if deadLetterAttempts < 1 || err != nil {
+		deadLetterAttempts = DefaultDeadLetterAttempts
+	}
+
+	shutdownTimeout, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT"))
+	if shutdownTimeout < 1 || err != nil {
+		shutdownTimeout = int(DefaultShutdownTimeout.Seconds())
+	}
+
+	readinessThreshold, err := strconv.Atoi(os.Getenv("READINESS_THRESHOLD"))
+	if readinessThreshold < 1 || err != nil {
+		readinessThreshold = int(DefaultReadinessThreshold.Seconds())
+	}
+
+	lagWarnThreshold, err := strconv.ParseInt(os.Getenv("CONSUMER_LAG_WARN_THRESHOLD"), 10, 64)
+	if lagWarnThreshold < 1 || err != nil {
+		lagWarnThreshold = DefaultConsumerLagWarnThreshold
+	}
+
+	lagWarnDuration, err := strconv.Atoi(os.Getenv("CONSUMER_LAG_WARN_DURATION"))
+	if lagWarnDuration < 1 || err != nil {
+		lagWarnDuration = int(DefaultConsumerLagWarnDuration.Seconds())
+	}
+
+	verifyInterval, err := strconv.Atoi(os.Getenv("TOTALS_VERIFY_INTERVAL"))
+	if verifyInterval < 1 || err != nil {
+		verifyInterval = 0
+	}
+
+	verifyRepair, _ := strconv.ParseBool(os.Getenv("TOTALS_VERIFY_REPAIR"))
+
+	lessonPubSub, _ := strconv.ParseBool(os.Getenv("LESSON_WRITTEN_PUBSUB"))
+
+	deletedScoresFeed, _ := strconv.ParseBool(os.Getenv("DELETED_LESSON_SCORES_FEED"))
+
+	renormalizeNames, _ := strconv.ParseBool(os.Getenv("DISCIPLINE_NAMES_RENORMALIZE"))
+
+	logLevel, err := parseLogLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	logFormat, err := parseLogFormat(os.Getenv("LOG_FORMAT"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	topology, err := loadConnectorTopology(os.Getenv("CONNECTORS_TOPOLOGY_FILE"))
+	if err != nil {
+		return Config{}, err
+	}
+
 	config := Config{
-		redisDsn:      os.Getenv("REDIS_DSN"),
-		kafkaHost:     os.Getenv("KAFKA_HOST"),
-		kafkaTimeout:  time.Second * time.Duration(kafkaTimeout),
-		kafkaAttempts: kafkaAttempts,
+		redisDsn:           os.Getenv("REDIS_DSN"),
+		redisShadowDsn:     os.Getenv("REDIS_SHADOW_DSN"),
+		kafkaHost:          os.Getenv("KAFKA_HOST"),
+		kafkaTimeout:       time.Second * time.Duration(kafkaTimeout),
+		kafkaAttempts:      kafkaAttempts,
+		deadLetterTopic:    os.Getenv("KAFKA_DEAD_LETTER_TOPIC"),
+		deadLetterAttempts: deadLetterAttempts,
+		topology:           topology,
+		shutdownTimeout:    time.Second * time.Duration(shutdownTimeout),
+		lessonPubSub:       lessonPubSub,
+		logLevel:           logLevel,
+		logFormat:          logFormat,
+		httpListenAddr:     os.Getenv("HTTP_LISTEN_ADDR"),
+		readinessThreshold: time.Second * time.Duration(readinessThreshold),
+		lagWarnThreshold:   lagWarnThreshold,
+		lagWarnDuration:    time.Second * time.Duration(lagWarnDuration),
+		verifyInterval:     time.Second * time.Duration(verifyInterval),
+		verifyRepair:       verifyRepair,
+		deletedScoresFeed:  deletedScoresFeed,
+		nameRulesFile:      os.Getenv("DISCIPLINE_NAME_RULES_FILE"),
+		renormalizeNames:   renormalizeNames,
+		instanceId:         os.Getenv("INSTANCE_ID"),
 	}
 
 	if config.kafkaHost == "" {
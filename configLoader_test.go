@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"log/slog"
 	"os"
 	"strconv"
 	"testing"
@@ -10,17 +11,52 @@ import (
 )
 
 var expectedConfig = Config{
-	redisDsn:      "REDIS:6379",
-	kafkaHost:     "KAFKA:9999",
-	kafkaTimeout:  time.Second * 10,
-	kafkaAttempts: 0,
+	redisDsn:           "REDIS:6379",
+	redisShadowDsn:     "redis://SHADOW-REDIS:6379",
+	kafkaHost:          "KAFKA:9999",
+	kafkaTimeout:       time.Second * 10,
+	kafkaAttempts:      0,
+	deadLetterTopic:    "storage-writer-dead-letter",
+	deadLetterAttempts: 5,
+	topology:           getDefaultConnectorTopology(),
+	shutdownTimeout:    time.Second * 45,
+	lessonPubSub:       true,
+	logLevel:           slog.LevelWarn,
+	logFormat:          LogFormatJson,
+	httpListenAddr:     ":8080",
+	readinessThreshold: time.Second * 90,
+	lagWarnThreshold:   500,
+	lagWarnDuration:    time.Second * 120,
+	verifyInterval:     time.Second * 3600,
+	verifyRepair:       true,
+	deletedScoresFeed:  true,
+	nameRulesFile:      "discipline-name-rules.yaml",
+	renormalizeNames:   true,
+	instanceId:         "storage-writer-1",
 }
 
 func TestLoadConfigFromEnvVars(t *testing.T) {
 	t.Run("FromEnvVars", func(t *testing.T) {
 		_ = os.Setenv("REDIS_DSN", expectedConfig.redisDsn)
+		_ = os.Setenv("REDIS_SHADOW_DSN", expectedConfig.redisShadowDsn)
 		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
 		_ = os.Setenv("KAFKA_TIMEOUT", strconv.Itoa(int(expectedConfig.kafkaTimeout.Seconds())))
+		_ = os.Setenv("KAFKA_DEAD_LETTER_TOPIC", expectedConfig.deadLetterTopic)
+		_ = os.Setenv("KAFKA_DEAD_LETTER_ATTEMPTS", strconv.Itoa(expectedConfig.deadLetterAttempts))
+		_ = os.Setenv("SHUTDOWN_TIMEOUT", strconv.Itoa(int(expectedConfig.shutdownTimeout.Seconds())))
+		_ = os.Setenv("LESSON_WRITTEN_PUBSUB", strconv.FormatBool(expectedConfig.lessonPubSub))
+		_ = os.Setenv("LOG_LEVEL", expectedConfig.logLevel.String())
+		_ = os.Setenv("LOG_FORMAT", expectedConfig.logFormat)
+		_ = os.Setenv("HTTP_LISTEN_ADDR", expectedConfig.httpListenAddr)
+		_ = os.Setenv("READINESS_THRESHOLD", strconv.Itoa(int(expectedConfig.readinessThreshold.Seconds())))
+		_ = os.Setenv("CONSUMER_LAG_WARN_THRESHOLD", strconv.FormatInt(expectedConfig.lagWarnThreshold, 10))
+		_ = os.Setenv("CONSUMER_LAG_WARN_DURATION", strconv.Itoa(int(expectedConfig.lagWarnDuration.Seconds())))
+		_ = os.Setenv("TOTALS_VERIFY_INTERVAL", strconv.Itoa(int(expectedConfig.verifyInterval.Seconds())))
+		_ = os.Setenv("TOTALS_VERIFY_REPAIR", strconv.FormatBool(expectedConfig.verifyRepair))
+		_ = os.Setenv("DELETED_LESSON_SCORES_FEED", strconv.FormatBool(expectedConfig.deletedScoresFeed))
+		_ = os.Setenv("DISCIPLINE_NAME_RULES_FILE", expectedConfig.nameRulesFile)
+		_ = os.Setenv("DISCIPLINE_NAMES_RENORMALIZE", strconv.FormatBool(expectedConfig.renormalizeNames))
+		_ = os.Setenv("INSTANCE_ID", expectedConfig.instanceId)
 
 		config, err := loadConfig("")
 
@@ -34,6 +70,23 @@ func TestLoadConfigFromEnvVars(t *testing.T) {
 
 		envFileContent += fmt.Sprintf("KAFKA_HOST=%s\n", expectedConfig.kafkaHost)
 		envFileContent += fmt.Sprintf("REDIS_DSN=%s\n", expectedConfig.redisDsn)
+		envFileContent += fmt.Sprintf("REDIS_SHADOW_DSN=%s\n", expectedConfig.redisShadowDsn)
+		envFileContent += fmt.Sprintf("KAFKA_DEAD_LETTER_TOPIC=%s\n", expectedConfig.deadLetterTopic)
+		envFileContent += fmt.Sprintf("KAFKA_DEAD_LETTER_ATTEMPTS=%d\n", expectedConfig.deadLetterAttempts)
+		envFileContent += fmt.Sprintf("SHUTDOWN_TIMEOUT=%d\n", int(expectedConfig.shutdownTimeout.Seconds()))
+		envFileContent += fmt.Sprintf("LESSON_WRITTEN_PUBSUB=%t\n", expectedConfig.lessonPubSub)
+		envFileContent += fmt.Sprintf("LOG_LEVEL=%s\n", expectedConfig.logLevel)
+		envFileContent += fmt.Sprintf("LOG_FORMAT=%s\n", expectedConfig.logFormat)
+		envFileContent += fmt.Sprintf("HTTP_LISTEN_ADDR=%s\n", expectedConfig.httpListenAddr)
+		envFileContent += fmt.Sprintf("READINESS_THRESHOLD=%d\n", int(expectedConfig.readinessThreshold.Seconds()))
+		envFileContent += fmt.Sprintf("CONSUMER_LAG_WARN_THRESHOLD=%d\n", expectedConfig.lagWarnThreshold)
+		envFileContent += fmt.Sprintf("CONSUMER_LAG_WARN_DURATION=%d\n", int(expectedConfig.lagWarnDuration.Seconds()))
+		envFileContent += fmt.Sprintf("TOTALS_VERIFY_INTERVAL=%d\n", int(expectedConfig.verifyInterval.Seconds()))
+		envFileContent += fmt.Sprintf("TOTALS_VERIFY_REPAIR=%t\n", expectedConfig.verifyRepair)
+		envFileContent += fmt.Sprintf("DELETED_LESSON_SCORES_FEED=%t\n", expectedConfig.deletedScoresFeed)
+		envFileContent += fmt.Sprintf("DISCIPLINE_NAME_RULES_FILE=%s\n", expectedConfig.nameRulesFile)
+		envFileContent += fmt.Sprintf("DISCIPLINE_NAMES_RENORMALIZE=%t\n", expectedConfig.renormalizeNames)
+		envFileContent += fmt.Sprintf("INSTANCE_ID=%s\n", expectedConfig.instanceId)
 
 		testEnvFilename := "TestLoadConfigFromFile.env"
 		err := os.WriteFile(testEnvFilename, []byte(envFileContent), 0644)
@@ -47,6 +100,93 @@ func TestLoadConfigFromEnvVars(t *testing.T) {
 		assert.Equalf(t, expectedConfig, config, "Expected for %v, actual: %v", expectedConfig, config)
 	})
 
+	t.Run("DefaultDeadLetterAttempts", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("KAFKA_DEAD_LETTER_TOPIC", "")
+		_ = os.Setenv("KAFKA_DEAD_LETTER_ATTEMPTS", "")
+
+		config, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Empty(t, config.deadLetterTopic)
+		assert.Equal(t, DefaultDeadLetterAttempts, config.deadLetterAttempts)
+	})
+
+	t.Run("WrongLogConfig", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+
+		_ = os.Setenv("LOG_LEVEL", "verbose")
+		_, err := loadConfig("")
+		assert.ErrorContains(t, err, "invalid LOG_LEVEL")
+
+		_ = os.Setenv("LOG_LEVEL", "")
+		_ = os.Setenv("LOG_FORMAT", "xml")
+		_, err = loadConfig("")
+		assert.ErrorContains(t, err, "invalid LOG_FORMAT")
+
+		_ = os.Setenv("LOG_FORMAT", "")
+		config, err := loadConfig("")
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultLogLevel, config.logLevel)
+		assert.Equal(t, LogFormatText, config.logFormat)
+	})
+
+	t.Run("DefaultShutdownTimeout", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("SHUTDOWN_TIMEOUT", "")
+
+		config, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultShutdownTimeout, config.shutdownTimeout)
+	})
+
+	t.Run("DefaultReadinessThreshold", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("READINESS_THRESHOLD", "")
+
+		config, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultReadinessThreshold, config.readinessThreshold)
+	})
+
+	t.Run("DefaultConsumerLagWarn", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("CONSUMER_LAG_WARN_THRESHOLD", "")
+		_ = os.Setenv("CONSUMER_LAG_WARN_DURATION", "")
+
+		config, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultConsumerLagWarnThreshold, config.lagWarnThreshold)
+		assert.Equal(t, DefaultConsumerLagWarnDuration, config.lagWarnDuration)
+	})
+
+	t.Run("DefaultTotalsVerify", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("TOTALS_VERIFY_INTERVAL", "")
+		_ = os.Setenv("TOTALS_VERIFY_REPAIR", "")
+
+		config, err := loadConfig("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), config.verifyInterval)
+		assert.False(t, config.verifyRepair)
+	})
+
+	t.Run("WrongTopologyFile", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("CONNECTORS_TOPOLOGY_FILE", "not-exists-topology.json")
+		defer os.Unsetenv("CONNECTORS_TOPOLOGY_FILE")
+
+		config, err := loadConfig("")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "Error loading connectors topology not-exists-topology.json")
+		assert.Empty(t, config.kafkaHost)
+	})
+
 	t.Run("EmptyConfig", func(t *testing.T) {
 		_ = os.Setenv("REDIS_DSN", "")
 		_ = os.Setenv("KAFKA_HOST", "")
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/kneu-messenger-pigeon/events"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"log/slog"
+)
+
+const DefaultDryRunGroupId = DefaultTopologyGroupId + "-dry-run"
+
+type DryRunOptions struct {
+	groupId       string
+	fromBeginning bool
+}
+
+// runDryRun
+/*
+ * Runs connectors against live topics with DryRunRedisHook: writers read Redis as usual,
+ * but planned mutations are only logged. Kafka offsets are not committed, dead-letter and scores changes feed
+ * are not produced. Separate consumer group is used, so the service consumers are not affected.
+ */
+func runDryRun(out io.Writer, args []string) error {
+	options, err := parseDryRunOptions(args, out)
+	if err != nil {
+		return err
+	}
+
+	config, opt, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+
+	logger := newLogger(out, config.logLevel, config.logFormat)
+	redisClient := redis.NewClient(opt)
+	redisClient.AddHook(newDryRunRedisHook(logger))
+	defer redisClient.Close()
+
+	suppressedFeedWriter := &SuppressedScoresChangesFeedWriter{}
+	connectorsFactory := newConnectorsFactory(
+		logger, redisClient, suppressedFeedWriter, newLessonExistChecker(logger, redisClient), suppressedFeedWriter, nil, true,
+	)
+
+	var connectorsPool []ConnectorInterface
+	for _, entry := range config.topology {
+		entry.GroupId = options.groupId
+		readerConfig := newTopologyKafkaReaderConfig(config, entry)
+		if !options.fromBeginning {
+			readerConfig.StartOffset = kafka.LastOffset
+		}
+
+		for i := 0; i < entry.Readers; i++ {
+			kafkaReader := kafka.NewReader(readerConfig)
+			defer kafkaReader.Close()
+
+			reader := &dryRunReader{ReaderInterface: kafkaReader, logger: logger}
+			connectorsPool = append(connectorsPool, connectorsFactory.newConnector(entry, reader, nil, nil))
+		}
+	}
+
+	logger.Info("dry-run started", "groupId", options.groupId, "fromBeginning", options.fromBeginning)
+	eventLoop := EventLoop{
+		logger:                  logger,
+		connectorsPool:          connectorsPool,
+		scoresChangesFeedWriter: suppressedFeedWriter,
+		shutdownTimeout:         config.shutdownTimeout,
+	}
+	eventLoop.execute()
+	logger.Info("dry-run finished", "suppressedScoresChanges", suppressedFeedWriter.count.Load())
+
+	return nil
+}
+
+func parseDryRunOptions(args []string, out io.Writer) (DryRunOptions, error) {
+	options := DryRunOptions{}
+
+	flagSet := flag.NewFlagSet(CommandDryRun, flag.ContinueOnError)
+	flagSet.SetOutput(out)
+	flagSet.StringVar(&options.groupId, "group", DefaultDryRunGroupId, "consumer group id")
+	flagSet.BoolVar(&options.fromBeginning, "from-beginning", false, "read topics from the beginning instead of new messages")
+
+	err := flagSet.Parse(args)
+	if err == nil && (options.groupId == "" || options.groupId == DefaultTopologyGroupId) {
+		err = errors.New("dry-run requires separate consumer group id")
+	}
+	if err == nil && flagSet.NArg() != 0 {
+		err = fmt.Errorf("unexpected arguments: %v", flagSet.Args())
+	}
+
+	return options, err
+}
+
+// dryRunReader doesn't commit offsets, so dry-run group always starts from the same position
+type dryRunReader struct {
+	events.ReaderInterface
+	logger *slog.Logger
+}
+
+func (reader *dryRunReader) CommitMessages(_ context.Context, messages ...kafka.Message) error {
+	if len(messages) != 0 {
+		last := messages[len(messages)-1]
+		reader.logger.Info(
+			"dry-run: skip commit messages",
+			"count", len(messages), "topic", last.Topic, "partition", last.Partition, "offset", last.Offset,
+		)
+	}
+
+	return nil
+}
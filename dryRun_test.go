@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/kneu-messenger-pigeon/events/mocks"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseDryRunOptions(t *testing.T) {
+	options, err := parseDryRunOptions([]string{}, &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultDryRunGroupId, options.groupId)
+	assert.False(t, options.fromBeginning)
+
+	options, err = parseDryRunOptions([]string{"-group", "storage-writer-check", "-from-beginning"}, &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, "storage-writer-check", options.groupId)
+	assert.True(t, options.fromBeginning)
+
+	_, err = parseDryRunOptions([]string{"-group", DefaultTopologyGroupId}, &bytes.Buffer{})
+	assert.EqualError(t, err, "dry-run requires separate consumer group id")
+
+	_, err = parseDryRunOptions([]string{"scores"}, &bytes.Buffer{})
+	assert.EqualError(t, err, "unexpected arguments: [scores]")
+}
+
+func TestDryRunReader(t *testing.T) {
+	out := &bytes.Buffer{}
+	kafkaReader := mocks.NewReaderInterface(t)
+	reader := &dryRunReader{ReaderInterface: kafkaReader, logger: newTestLogger(out)}
+
+	err := reader.CommitMessages(
+		context.Background(),
+		kafka.Message{Topic: "raw-scores", Partition: 1, Offset: 10},
+		kafka.Message{Topic: "raw-scores", Partition: 1, Offset: 11},
+	)
+
+	assert.NoError(t, err)
+	kafkaReader.AssertNotCalled(t, "CommitMessages")
+	assert.Contains(t, out.String(), `msg="dry-run: skip commit messages" count=2 topic=raw-scores partition=1 offset=11`)
+}
+
+func TestRunDryRun(t *testing.T) {
+	err := runCommand(&bytes.Buffer{}, []string{CommandDryRun, "-group", ""})
+	assert.EqualError(t, err, "dry-run requires separate consumer group id")
+}
@@ -2,38 +2,89 @@ package main
 
 import (
 	"context"
-	"io"
+	"log/slog"
 	"os/signal"
 	"runtime"
 	"sync"
 	"syscall"
+	"time"
 )
 
-const ConnectorPoolSize = 6
+const DefaultShutdownTimeout = time.Second * 30
 
 type EventLoop struct {
-	out                     io.Writer
-	connectorsPool          [ConnectorPoolSize]ConnectorInterface
+	logger                  *slog.Logger
+	connectorsPool          []ConnectorInterface
 	scoresChangesFeedWriter ScoresChangesFeedWriterInterface
+	shutdownTimeout         time.Duration
 }
 
+// execute
+/*
+ * Runs connectors and scores changes feed writer until termination signal.
+ * Shutdown is ordered: connectors stop fetching and commit already written messages first,
+ * only then the feed writer releases waiting queue and flushes, so it receives the last score changes.
+ */
 func (eventLoop *EventLoop) execute() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	defer stop()
 
-	wg := &sync.WaitGroup{}
+	feedWriterCtx, feedWriterStop := context.WithCancel(context.Background())
+	defer feedWriterStop()
 
-	wg.Add(1)
+	feedWriterDone := make(chan struct{})
 	go func() {
-		eventLoop.scoresChangesFeedWriter.execute(ctx)
-		wg.Done()
+		eventLoop.scoresChangesFeedWriter.execute(feedWriterCtx)
+		close(feedWriterDone)
 	}()
 
+	connectorsDone := make(chan struct{})
+	wg := &sync.WaitGroup{}
 	wg.Add(len(eventLoop.connectorsPool))
 	for _, connector := range eventLoop.connectorsPool {
 		go connector.execute(ctx, wg)
 	}
+	go func() {
+		wg.Wait()
+		close(connectorsDone)
+	}()
 
 	runtime.Gosched()
-	wg.Wait()
+	<-ctx.Done()
+
+	shutdownTimeout := eventLoop.shutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+	shutdownStartedAt := time.Now()
+	deadlineCtx, deadlineCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer deadlineCancel()
+
+	eventLoop.logger.Info("shutdown: drain connectors", "count", len(eventLoop.connectorsPool), "timeout", shutdownTimeout)
+
+	// feed writer is flushed even after connectors missed the deadline, both share the same deadline
+	connectorsDrained := waitUntilDeadline(connectorsDone, deadlineCtx.Done())
+	feedWriterStop()
+	feedWriterFlushed := waitUntilDeadline(feedWriterDone, deadlineCtx.Done())
+
+	eventLoop.logger.Info(
+		"shutdown finished",
+		"duration", time.Since(shutdownStartedAt).Round(time.Millisecond),
+		"connectorsDrained", connectorsDrained, "scoresChangesFeedFlushed", feedWriterFlushed,
+	)
+}
+
+func waitUntilDeadline(done <-chan struct{}, deadline <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-deadline:
+		// done is preferred when both are ready, e.g. feed writer stopped right after the deadline of connectors
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}
 }
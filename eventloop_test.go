@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"sync"
 	"syscall"
@@ -21,15 +22,16 @@ func TestEventLoopExecute(t *testing.T) {
 		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
 		scoresChangesFeedWriter.On("execute", matchContext).Return().Once()
 
-		connector.On("execute", matchContext, matchWaitGroup).Return().Times(ConnectorPoolSize)
+		connectorsCount := 6
+		connector.On("execute", matchContext, matchWaitGroup).Return().Times(connectorsCount)
 
-		connectorPool := [ConnectorPoolSize]ConnectorInterface{}
-		for i := 0; i < ConnectorPoolSize; i++ {
+		connectorPool := make([]ConnectorInterface, connectorsCount)
+		for i := 0; i < connectorsCount; i++ {
 			connectorPool[i] = connector
 		}
 
 		eventloop := EventLoop{
-			out:                     out,
+			logger:                  newTestLogger(out),
 			connectorsPool:          connectorPool,
 			scoresChangesFeedWriter: scoresChangesFeedWriter,
 		}
@@ -42,5 +44,62 @@ func TestEventLoopExecute(t *testing.T) {
 
 		connector.AssertExpectations(t)
 		scoresChangesFeedWriter.AssertExpectations(t)
+		assert.Contains(t, out.String(), "connectorsDrained=true scoresChangesFeedFlushed=true")
+	})
+
+	t.Run("EventLoop shutdown deadline", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		matchContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+		// connector never calls wg.Done(), so drain could not finish
+		matchWaitGroup := mock.MatchedBy(func(wg *sync.WaitGroup) bool { return true })
+
+		connector := NewMockConnectorInterface(t)
+		connector.On("execute", matchContext, matchWaitGroup).Return().Once()
+
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("execute", matchContext).Return().Once()
+
+		eventloop := EventLoop{
+			logger:                  newTestLogger(out),
+			connectorsPool:          []ConnectorInterface{connector},
+			scoresChangesFeedWriter: scoresChangesFeedWriter,
+			shutdownTimeout:         time.Millisecond * 50,
+		}
+
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		}()
+		eventloop.execute()
+
+		assert.Contains(t, out.String(), "connectorsDrained=false scoresChangesFeedFlushed=true")
+	})
+
+	t.Run("EventLoop shutdown deadline of feed writer", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		matchContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+		matchWaitGroup := mock.MatchedBy(func(wg *sync.WaitGroup) bool { return true })
+
+		connector := NewMockConnectorInterface(t)
+		connector.On("execute", matchContext, matchWaitGroup).Return().Once()
+
+		// feed writer flush takes longer than shutdown timeout
+		scoresChangesFeedWriter := NewMockScoresChangesFeedWriterInterface(t)
+		scoresChangesFeedWriter.On("execute", matchContext).Return().Once().WaitUntil(time.After(time.Second))
+
+		eventloop := EventLoop{
+			logger:                  newTestLogger(out),
+			connectorsPool:          []ConnectorInterface{connector},
+			scoresChangesFeedWriter: scoresChangesFeedWriter,
+			shutdownTimeout:         time.Millisecond * 50,
+		}
+
+		go func() {
+			time.Sleep(time.Millisecond * 10)
+			_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		}()
+		eventloop.execute()
+
+		assert.Contains(t, out.String(), "connectorsDrained=false scoresChangesFeedFlushed=false")
 	})
 }
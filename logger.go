@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	LogFormatText = "text"
+	LogFormatJson = "json"
+)
+
+const DefaultLogLevel = slog.LevelInfo
+
+func newLogger(out io.Writer, level slog.Leveler, format string) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == LogFormatJson {
+		return slog.New(slog.NewJSONHandler(out, options))
+	}
+
+	return slog.New(slog.NewTextHandler(out, options))
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	if level == "" {
+		return DefaultLogLevel, nil
+	}
+
+	var parsed slog.Level
+	err := parsed.UnmarshalText([]byte(level))
+	if err != nil {
+		return DefaultLogLevel, fmt.Errorf("invalid LOG_LEVEL %q: %w", level, err)
+	}
+
+	return parsed, nil
+}
+
+func parseLogFormat(format string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", LogFormatText:
+		return LogFormatText, nil
+	case LogFormatJson:
+		return LogFormatJson, nil
+	}
+
+	return LogFormatText, fmt.Errorf("invalid LOG_FORMAT %q: expected %s or %s", format, LogFormatText, LogFormatJson)
+}
+
+// getTypeName returns short type name to use as logger field, e.g. "*main.ScoreWriter"
+func getTypeName(value any) string {
+	return fmt.Sprintf("%T", value)
+}
+
+// logResult logs message with info level, or with error level and error field when err is not nil
+func logResult(logger *slog.Logger, msg string, err error, args ...any) {
+	if err != nil {
+		logger.Error(msg, append(args, "error", err)...)
+	} else {
+		logger.Info(msg, args...)
+	}
+}
+
+// messageLogArgs returns log fields which identify Kafka message
+func messageLogArgs(message kafka.Message) []any {
+	return []any{
+		"topic", message.Topic, "partition", message.Partition, "offset", message.Offset, "key", string(message.Key),
+	}
+}
+
+// batchLogArgs returns log fields with size and offsets range of Kafka messages batch
+func batchLogArgs(messages []kafka.Message) []any {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	firstOffset, lastOffset := messages[0].Offset, messages[0].Offset
+	for _, message := range messages[1:] {
+		firstOffset = min(firstOffset, message.Offset)
+		lastOffset = max(lastOffset, message.Offset)
+	}
+
+	return []any{"batchSize", len(messages), "firstOffset", firstOffset, "lastOffset", lastOffset}
+}
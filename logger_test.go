@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func newTestLogger(out io.Writer) *slog.Logger {
+	return newLogger(out, slog.LevelDebug, LogFormatText)
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		logger := newLogger(out, slog.LevelInfo, LogFormatText)
+
+		logger.Debug("debug message")
+		logger.Info("info message", "disciplineId", 234)
+
+		assert.NotContains(t, out.String(), "debug message")
+		assert.Contains(t, out.String(), `level=INFO msg="info message" disciplineId=234`)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		logger := newLogger(out, slog.LevelWarn, LogFormatJson)
+
+		logger.Info("info message")
+		logResult(logger, "commit messages", errors.New("expected error"), "count", 2)
+
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+		assert.Equal(t, "ERROR", record["level"])
+		assert.Equal(t, "commit messages", record["msg"])
+		assert.Equal(t, float64(2), record["count"])
+		assert.Equal(t, "expected error", record["error"])
+	})
+}
+
+func TestParseLogConfig(t *testing.T) {
+	level, err := parseLogLevel("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultLogLevel, level)
+
+	level, err = parseLogLevel("debug")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+
+	_, err = parseLogLevel("verbose")
+	assert.Error(t, err)
+
+	format, err := parseLogFormat("")
+	assert.NoError(t, err)
+	assert.Equal(t, LogFormatText, format)
+
+	format, err = parseLogFormat("JSON")
+	assert.NoError(t, err)
+	assert.Equal(t, LogFormatJson, format)
+
+	_, err = parseLogFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestBatchLogArgs(t *testing.T) {
+	assert.Nil(t, batchLogArgs(nil))
+	assert.Equal(
+		t, []any{"batchSize", 3, "firstOffset", int64(10), "lastOffset", int64(14)},
+		batchLogArgs([]kafka.Message{{Offset: 12}, {Offset: 10}, {Offset: 14}}),
+	)
+}
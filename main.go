@@ -5,5 +5,5 @@ package main
 import "os"
 
 func main() {
-	os.Exit(handleExitError(os.Stderr, runApp(os.Stdout)))
+	os.Exit(handleExitError(os.Stderr, runCommand(os.Stdout, os.Args[1:])))
 }
@@ -1,9 +1,124 @@
 package main
 
-import "github.com/VictoriaMetrics/metrics"
+import (
+	"fmt"
+	"github.com/VictoriaMetrics/metrics"
+	"time"
+)
 
 var (
 	realtimeScoresChangesCount = metrics.NewCounter(`scores__changes_count{source="realtime"}`)
 
 	secondaryScoresChangesCount = metrics.NewCounter(`scores__changes_count{source="secondary"}`)
+
+	staleScoreEventsCount = metrics.NewCounter(`scores__stale_events_count`)
+
+	lessonsExistCacheHitCount = metrics.NewCounter(`lessons_exist_cache_count{result="hit"}`)
+
+	lessonsExistCacheMissCount = metrics.NewCounter(`lessons_exist_cache_count{result="miss"}`)
+
+	lessonsExistCheckErrorsCount = metrics.NewCounter(`lessons_exist_check__errors_count`)
+
+	cascadeDeletedScoresCount = metrics.NewCounter(`scores__cascade_deleted_count`)
+
+	lessonTypesListBgSaveCount = metrics.NewCounter(`redis__bgsave_count{writer="lesson-types-list"}`)
+
+	scoresChangesFeedReadyQueueLength = metrics.NewGauge(`scores_changes_feed__queue_length{queue="ready"}`, nil)
+
+	scoresChangesFeedWaitingQueueLength = metrics.NewGauge(`scores_changes_feed__queue_length{queue="waiting"}`, nil)
+
+	scoresChangesFeedWaitingDuration = metrics.NewHistogram(`scores_changes_feed__waiting_duration_seconds`)
+
+	shadowRedisWritesCount = metrics.NewCounter(`redis_shadow__writes_count`)
+
+	shadowRedisErrorsCount = metrics.NewCounter(`redis_shadow__errors_count`)
+
+	shadowRedisDroppedCount = metrics.NewCounter(`redis_shadow__dropped_count`)
+
+	totalsMismatchesCount = metrics.NewCounter(`verifier__mismatches_count{check="totals"}`)
+
+	totalsRepairedCount = metrics.NewCounter(`verifier__repaired_count{check="totals"}`)
+
+	studentDisciplinesMismatchesCount = metrics.NewCounter(`verifier__mismatches_count{check="student-disciplines"}`)
+
+	studentDisciplinesRepairedCount = metrics.NewCounter(`verifier__repaired_count{check="student-disciplines"}`)
+
+	lessonStudentsMismatchesCount = metrics.NewCounter(`verifier__mismatches_count{check="lesson-students"}`)
+
+	lessonStudentsRepairedCount = metrics.NewCounter(`verifier__repaired_count{check="lesson-students"}`)
+
+	disciplineNamesRenormalizedCount = metrics.NewCounter(`discipline_names__renormalized_count`)
 )
+
+// ConnectorMetrics
+/*
+ * Processing metrics of one connector, labeled by connector name and topology writer.
+ * Methods are safe to call on nil receiver, so connectors created without metrics just skip them.
+ */
+type ConnectorMetrics struct {
+	fetchedCount    *metrics.Counter
+	writtenCount    *metrics.Counter
+	skippedCount    *metrics.Counter
+	failedCount     *metrics.Counter
+	bgSaveCount     *metrics.Counter
+	commitBatchSize *metrics.Histogram
+	writeDuration   *metrics.Histogram
+}
+
+func newConnectorMetrics(connector string, writer string) *ConnectorMetrics {
+	labels := fmt.Sprintf(`connector=%q,writer=%q`, connector, writer)
+
+	return &ConnectorMetrics{
+		fetchedCount:    metrics.GetOrCreateCounter(`connector__fetched_messages_count{` + labels + `}`),
+		writtenCount:    metrics.GetOrCreateCounter(`connector__written_messages_count{` + labels + `}`),
+		skippedCount:    metrics.GetOrCreateCounter(`connector__skipped_messages_count{` + labels + `}`),
+		failedCount:     metrics.GetOrCreateCounter(`connector__failed_messages_count{` + labels + `}`),
+		bgSaveCount:     metrics.GetOrCreateCounter(`redis__bgsave_count{` + labels + `}`),
+		commitBatchSize: metrics.GetOrCreateHistogram(`connector__commit_batch_size{` + labels + `}`),
+		writeDuration:   metrics.GetOrCreateHistogram(`connector__write_duration_seconds{` + labels + `}`),
+	}
+}
+
+func (connectorMetrics *ConnectorMetrics) fetched(count int) {
+	if connectorMetrics != nil {
+		connectorMetrics.fetchedCount.Add(count)
+	}
+}
+
+func (connectorMetrics *ConnectorMetrics) written(count int) {
+	if connectorMetrics != nil {
+		connectorMetrics.writtenCount.Add(count)
+	}
+}
+
+// skipped counts messages with unexpected key, which are committed without write
+func (connectorMetrics *ConnectorMetrics) skipped(count int) {
+	if connectorMetrics != nil {
+		connectorMetrics.skippedCount.Add(count)
+	}
+}
+
+// failed counts messages failed to write, including moved to dead-letter topic
+func (connectorMetrics *ConnectorMetrics) failed(count int) {
+	if connectorMetrics != nil {
+		connectorMetrics.failedCount.Add(count)
+	}
+}
+
+func (connectorMetrics *ConnectorMetrics) bgSaved() {
+	if connectorMetrics != nil {
+		connectorMetrics.bgSaveCount.Inc()
+	}
+}
+
+func (connectorMetrics *ConnectorMetrics) committed(count int) {
+	if connectorMetrics != nil {
+		connectorMetrics.commitBatchSize.Update(float64(count))
+	}
+}
+
+func (connectorMetrics *ConnectorMetrics) writeFinished(startedAt time.Time) {
+	if connectorMetrics != nil {
+		connectorMetrics.writeDuration.UpdateDuration(startedAt)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestConnectorMetrics(t *testing.T) {
+	t.Run("nil receiver", func(t *testing.T) {
+		var connectorMetrics *ConnectorMetrics
+
+		assert.NotPanics(t, func() {
+			connectorMetrics.fetched(1)
+			connectorMetrics.written(1)
+			connectorMetrics.skipped(1)
+			connectorMetrics.failed(1)
+			connectorMetrics.bgSaved()
+			connectorMetrics.committed(1)
+			connectorMetrics.writeFinished(time.Now())
+		})
+	})
+
+	t.Run("same labels share metrics", func(t *testing.T) {
+		first := newConnectorMetrics("test-shared#0", TopologyLessonsWriter)
+		second := newConnectorMetrics("test-shared#0", TopologyLessonsWriter)
+		other := newConnectorMetrics("test-shared#1", TopologyLessonsWriter)
+
+		first.written(2)
+		second.written(3)
+		other.written(1)
+
+		assert.Equal(t, uint64(5), first.writtenCount.Get())
+		assert.Equal(t, uint64(1), other.writtenCount.Get())
+	})
+}
+
+func getHistogramCount(histogram *metrics.Histogram) (count uint64) {
+	histogram.VisitNonZeroBuckets(func(_ string, bucketCount uint64) {
+		count += bucketCount
+	})
+	return count
+}
@@ -23,6 +23,32 @@ func (_m *MockLessonExistCheckerInterface) Exists(year int, semester uint8, disc
 	return r0
 }
 
+// ExistsMany provides a mock function with given fields: lessons
+func (_m *MockLessonExistCheckerInterface) ExistsMany(lessons []lessonIdentifier) []bool {
+	ret := _m.Called(lessons)
+
+	var r0 []bool
+	if rf, ok := ret.Get(0).(func([]lessonIdentifier) []bool); ok {
+		r0 = rf(lessons)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bool)
+		}
+	}
+
+	return r0
+}
+
+// Forget provides a mock function with given fields: lesson
+func (_m *MockLessonExistCheckerInterface) Forget(lesson lessonIdentifier) {
+	_m.Called(lesson)
+}
+
+// Remember provides a mock function with given fields: lesson
+func (_m *MockLessonExistCheckerInterface) Remember(lesson lessonIdentifier) {
+	_m.Called(lesson)
+}
+
 type mockConstructorTestingTNewMockLessonExistCheckerInterface interface {
 	mock.TestingT
 	Cleanup(func())
@@ -14,6 +14,11 @@ type MockScoresChangesFeedWriterInterface struct {
 	mock.Mock
 }
 
+// addBatchToQueue provides a mock function with given fields: changedEvents
+func (_m *MockScoresChangesFeedWriterInterface) addBatchToQueue(changedEvents []events.ScoreChangedEvent) {
+	_m.Called(changedEvents)
+}
+
 // addToQueue provides a mock function with given fields: event, previousValue
 func (_m *MockScoresChangesFeedWriterInterface) addToQueue(event events.ScoreEvent, previousValue events.ScoreValue) {
 	_m.Called(event, previousValue)
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"testing"
+)
+
+// newMiniRedisClient returns client of in-memory Redis server, which executes Lua scripts,
+// so scripts logic is tested, not only arguments they are called with
+func newMiniRedisClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	return server, client
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"strings"
+)
+
+const (
+	NameOverrideActionSet    = "set"
+	NameOverrideActionRemove = "remove"
+	NameOverrideActionList   = "list"
+)
+
+type NameOverrideOptions struct {
+	action string
+	year   int
+	id     uint
+	name   string
+}
+
+// runNameOverride
+/*
+ * Manages manual discipline name overrides:
+ *   name-override set -year 2030 -id 200 -name "Фінанси підприємств"
+ *   name-override remove -year 2030 -id 200
+ *   name-override list -year 2030
+ */
+func runNameOverride(out io.Writer, args []string) error {
+	options, err := parseNameOverrideOptions(args, out)
+	if err != nil {
+		return err
+	}
+
+	_, opt, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+
+	redisClient := redis.NewClient(opt)
+	defer redisClient.Close()
+
+	return executeNameOverride(context.Background(), out, &DisciplineNameOverrides{redis: redisClient}, options)
+}
+
+func executeNameOverride(
+	ctx context.Context, out io.Writer, overrides *DisciplineNameOverrides, options NameOverrideOptions,
+) error {
+	switch options.action {
+	case NameOverrideActionSet:
+		err := overrides.set(ctx, options.year, options.id, options.name)
+		if err == nil {
+			_, _ = fmt.Fprintf(out, "%d:%d: name is set to %q\n", options.year, options.id, options.name)
+		}
+		return err
+
+	case NameOverrideActionRemove:
+		isRemoved, err := overrides.remove(ctx, options.year, options.id)
+		if err == nil && !isRemoved {
+			err = fmt.Errorf("%d:%d: name override not found", options.year, options.id)
+		}
+		if err == nil {
+			_, _ = fmt.Fprintf(out, "%d:%d: name override is removed\n", options.year, options.id)
+		}
+		return err
+
+	default:
+		list, err := overrides.list(ctx, options.year)
+		for _, override := range list {
+			_, _ = fmt.Fprintf(out, "%d\t%q\t(origName: %q)\n", override.id, override.name, override.origName)
+		}
+		return err
+	}
+}
+
+func parseNameOverrideOptions(args []string, out io.Writer) (NameOverrideOptions, error) {
+	options := NameOverrideOptions{}
+	if len(args) == 0 {
+		return options, errors.New(
+			"action is required: " + NameOverrideActionSet + ", " + NameOverrideActionRemove + " or " + NameOverrideActionList,
+		)
+	}
+	options.action, args = args[0], args[1:]
+
+	flagSet := flag.NewFlagSet(CommandNameOverride+" "+options.action, flag.ContinueOnError)
+	flagSet.SetOutput(out)
+	flagSet.IntVar(&options.year, "year", 0, "education year")
+	flagSet.UintVar(&options.id, "id", 0, "discipline id")
+	flagSet.StringVar(&options.name, "name", "", "discipline name")
+
+	err := flagSet.Parse(args)
+	options.name = strings.TrimSpace(options.name)
+	if err == nil && options.action != NameOverrideActionSet &&
+		options.action != NameOverrideActionRemove && options.action != NameOverrideActionList {
+		err = fmt.Errorf("unknown action %q", options.action)
+	}
+	if err == nil && options.year == 0 {
+		err = errors.New("-year is required")
+	}
+	if err == nil && options.action != NameOverrideActionList && options.id == 0 {
+		err = errors.New("-id is required")
+	}
+	if err == nil && options.action == NameOverrideActionSet && options.name == "" {
+		err = errors.New("-name is required")
+	}
+	if err == nil && flagSet.NArg() != 0 {
+		err = fmt.Errorf("unexpected arguments: %v", flagSet.Args())
+	}
+
+	return options, err
+}
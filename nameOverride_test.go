@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestParseNameOverrideOptions(t *testing.T) {
+	t.Run("actions", func(t *testing.T) {
+		options, err := parseNameOverrideOptions(
+			[]string{NameOverrideActionSet, "-year", "2030", "-id", "200", "-name", " Фінанси "}, &bytes.Buffer{},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, NameOverrideOptions{action: NameOverrideActionSet, year: 2030, id: 200, name: "Фінанси"}, options)
+
+		options, err = parseNameOverrideOptions([]string{NameOverrideActionRemove, "-year", "2030", "-id", "200"}, &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Equal(t, NameOverrideOptions{action: NameOverrideActionRemove, year: 2030, id: 200}, options)
+
+		options, err = parseNameOverrideOptions([]string{NameOverrideActionList, "-year", "2030"}, &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Equal(t, NameOverrideOptions{action: NameOverrideActionList, year: 2030}, options)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		invalidArgs := map[string][]string{
+			"action is required: set, remove or list": {},
+			`unknown action "drop"`:                   {"drop", "-year", "2030"},
+			"-year is required":                       {NameOverrideActionList},
+			"-id is required":                         {NameOverrideActionRemove, "-year", "2030"},
+			"-name is required":                       {NameOverrideActionSet, "-year", "2030", "-id", "200", "-name", " "},
+			"unexpected arguments: [200]":             {NameOverrideActionList, "-year", "2030", "200"},
+			`invalid value "-200" for flag -id`:       {NameOverrideActionRemove, "-year", "2030", "-id", "-200"},
+			"flag provided but not defined: -force":   {NameOverrideActionRemove, "-force"},
+		}
+		for expectedError, args := range invalidArgs {
+			_, err := parseNameOverrideOptions(args, &bytes.Buffer{})
+			assert.ErrorContains(t, err, expectedError)
+		}
+	})
+}
+
+func TestExecuteNameOverride(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("list", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectHGetAll("2030:discipline_name_overrides").SetVal(map[string]string{
+			"210": "Контролінг (ФЕУ)",
+			"200": "Фінанси та кредит",
+			"foo": "Маркетинг",
+		})
+		redisMock.ExpectHMGet("2030:discipline:200", "origName").SetVal([]interface{}{"Фінанси, 5 сем."})
+		redisMock.ExpectHMGet("2030:discipline:210", "origName").SetVal([]interface{}{nil})
+
+		out := &bytes.Buffer{}
+		err := executeNameOverride(
+			ctx, out, &DisciplineNameOverrides{redis: redisClient},
+			NameOverrideOptions{action: NameOverrideActionList, year: 2030},
+		)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(
+			t,
+			"200\t\"Фінанси та кредит\"\t(origName: \"Фінанси, 5 сем.\")\n"+
+				"210\t\"Контролінг (ФЕУ)\"\t(origName: \"\")\n",
+			out.String(),
+		)
+	})
+
+	t.Run("empty list", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectHGetAll("2030:discipline_name_overrides").SetVal(map[string]string{})
+
+		out := &bytes.Buffer{}
+		err := executeNameOverride(
+			ctx, out, &DisciplineNameOverrides{redis: redisClient},
+			NameOverrideOptions{action: NameOverrideActionList, year: 2030},
+		)
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("list error", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectHGetAll("2030:discipline_name_overrides").SetErr(assert.AnError)
+
+		out := &bytes.Buffer{}
+		err := executeNameOverride(
+			ctx, out, &DisciplineNameOverrides{redis: redisClient},
+			NameOverrideOptions{action: NameOverrideActionList, year: 2030},
+		)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Empty(t, out.String())
+	})
+}
+
+func TestRunNameOverride(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalid options", func(t *testing.T) {
+		err := runCommand(&bytes.Buffer{}, []string{CommandNameOverride, NameOverrideActionList})
+		assert.EqualError(t, err, "-year is required")
+	})
+
+	t.Run("wrong redis driver", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("REDIS_DSN", "//")
+		defer os.Unsetenv("REDIS_DSN")
+
+		err := runCommand(&bytes.Buffer{}, []string{CommandNameOverride, NameOverrideActionList, "-year", "2030"})
+		assert.EqualError(t, err, "redis: invalid URL scheme: ")
+	})
+
+	t.Run("set, list and remove", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(
+			ctx, "2030:discipline:200", "origName", "Фінанси, 5 сем.", "name", "Фінанси",
+			"searchTokens", "фі фін фіна фінан фінанс фінанси",
+		)
+		redisClient.SAdd(ctx, "2030:discipline_search:фінанси", "200")
+
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("REDIS_DSN", "redis://"+server.Addr())
+		defer os.Unsetenv("REDIS_DSN")
+
+		out := &bytes.Buffer{}
+		err := runCommand(out, []string{
+			CommandNameOverride, NameOverrideActionSet, "-year", "2030", "-id", "200", "-name", "Кредит",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "Кредит", redisClient.HGet(ctx, "2030:discipline:200", "name").Val())
+		assert.False(t, server.Exists("2030:discipline_search:фінанси"))
+		assert.Equal(t, []string{"200"}, redisClient.SMembers(ctx, "2030:discipline_search:кредит").Val())
+
+		err = runCommand(out, []string{CommandNameOverride, NameOverrideActionList, "-year", "2030"})
+		assert.NoError(t, err)
+
+		err = runCommand(out, []string{CommandNameOverride, NameOverrideActionRemove, "-year", "2030", "-id", "200"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Фінанси", redisClient.HGet(ctx, "2030:discipline:200", "name").Val())
+		assert.False(t, server.Exists("2030:discipline_search:кредит"))
+		assert.Equal(t, []string{"200"}, redisClient.SMembers(ctx, "2030:discipline_search:фінанси").Val())
+
+		err = runCommand(out, []string{CommandNameOverride, NameOverrideActionRemove, "-year", "2030", "-id", "200"})
+		assert.EqualError(t, err, "2030:200: name override not found")
+
+		assert.Equal(
+			t,
+			"2030:200: name is set to \"Кредит\"\n"+
+				"200\t\"Кредит\"\t(origName: \"Фінанси, 5 сем.\")\n"+
+				"2030:200: name override is removed\n",
+			out.String(),
+		)
+	})
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/kneu-messenger-pigeon/events"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const DefaultNormalizePreviewLimit = 100
+
+type NormalizePreviewOptions struct {
+	rulesFile        string
+	currentRulesFile string
+	inputFile        string
+	year             int
+	limit            int
+}
+
+// runNormalizePreview
+/*
+ * Shows impact of candidate discipline name rules: normalizes discipline names with current and candidate rules
+ * and prints changed names, names normalized to empty string and different names collapsed into the same name.
+ * Names are read from -input file (one name per line or NDJSON DisciplineEvent) or from Redis discipline hashes.
+ */
+func runNormalizePreview(out io.Writer, args []string) error {
+	options, err := parseNormalizePreviewOptions(args, out)
+	if err != nil {
+		return err
+	}
+
+	candidateRules, err := loadDisciplineNameRules(options.rulesFile)
+	var currentRules *DisciplineNameRules
+	if err == nil {
+		currentRules, err = loadDisciplineNameRules(options.currentRulesFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if options.inputFile != "" {
+		names, err = readDisciplineNamesFile(options.inputFile)
+	} else {
+		names, err = loadStoredDisciplineNames(options.year)
+	}
+	if err != nil {
+		return err
+	}
+
+	buildNormalizePreview(names, currentRules, candidateRules).write(out, options.limit)
+	return nil
+}
+
+func parseNormalizePreviewOptions(args []string, out io.Writer) (NormalizePreviewOptions, error) {
+	options := NormalizePreviewOptions{}
+
+	flagSet := flag.NewFlagSet(CommandNormalizePreview, flag.ContinueOnError)
+	flagSet.SetOutput(out)
+	flagSet.StringVar(&options.rulesFile, "rules", "", "candidate discipline name rules file")
+	flagSet.StringVar(
+		&options.currentRulesFile, "current-rules", os.Getenv("DISCIPLINE_NAME_RULES_FILE"),
+		"current discipline name rules file, embedded default rules when empty",
+	)
+	flagSet.StringVar(&options.inputFile, "input", "", "file with discipline names or NDJSON discipline events, Redis when empty")
+	flagSet.IntVar(&options.year, "year", 0, "read disciplines of education year only from Redis")
+	flagSet.IntVar(&options.limit, "limit", DefaultNormalizePreviewLimit, "max count of printed names of each section")
+
+	err := flagSet.Parse(args)
+	if err == nil && options.rulesFile == "" {
+		err = errors.New("-rules is required")
+	}
+	if err == nil && flagSet.NArg() != 0 {
+		err = fmt.Errorf("unexpected arguments: %v", flagSet.Args())
+	}
+
+	return options, err
+}
+
+// readDisciplineNamesFile reads unique not empty names; lines starting with "{" are parsed as DisciplineEvent
+func readDisciplineNamesFile(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return readDisciplineNames(file)
+}
+
+func readDisciplineNames(reader io.Reader) ([]string, error) {
+	var names []string
+	uniqueNames := make(map[string]bool)
+
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		name := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(strings.TrimSpace(name), "{") {
+			event := events.DisciplineEvent{}
+			err := json.Unmarshal([]byte(name), &event)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			name = event.Name
+		}
+
+		if strings.TrimSpace(name) != "" && !uniqueNames[name] {
+			uniqueNames[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return names, scanner.Err()
+}
+
+func loadStoredDisciplineNames(year int) ([]string, error) {
+	_, opt, err := loadAppConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	redisClient := redis.NewClient(opt)
+	defer redisClient.Close()
+
+	yearPattern := "*"
+	if year != 0 {
+		yearPattern = strconv.Itoa(year)
+	}
+
+	return scanDisciplineOrigNames(context.Background(), redisClient, yearPattern+":discipline:*")
+}
+
+func scanDisciplineOrigNames(ctx context.Context, client redis.UniversalClient, pattern string) ([]string, error) {
+	var keys []string
+	iterator := client.Scan(ctx, 0, pattern, verifierScanCount).Iterator()
+	for iterator.Next(ctx) {
+		keys = append(keys, iterator.Val())
+	}
+	if iterator.Err() != nil {
+		return nil, iterator.Err()
+	}
+
+	cmds := make([]*redis.SliceCmd, len(keys))
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.HMGet(ctx, key, "origName")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	uniqueNames := make(map[string]bool)
+	for _, cmd := range cmds {
+		if name, _ := cmd.Val()[0].(string); name != "" && !uniqueNames[name] {
+			uniqueNames[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return names, nil
+}
+
+type normalizedName struct {
+	name      string
+	current   string
+	candidate string
+}
+
+type NormalizePreviewReport struct {
+	total     int
+	changed   []normalizedName
+	empty     []normalizedName
+	collapsed map[string][]string
+}
+
+func buildNormalizePreview(names []string, currentRules, candidateRules *DisciplineNameRules) NormalizePreviewReport {
+	report := NormalizePreviewReport{total: len(names), collapsed: make(map[string][]string)}
+	candidateSources := make(map[string]map[string][]string)
+
+	for _, name := range names {
+		normalized := normalizedName{name: name, current: currentRules.clear(name), candidate: candidateRules.clear(name)}
+		if normalized.current != normalized.candidate {
+			report.changed = append(report.changed, normalized)
+		}
+		if normalized.candidate == "" {
+			report.empty = append(report.empty, normalized)
+			continue
+		}
+
+		if candidateSources[normalized.candidate] == nil {
+			candidateSources[normalized.candidate] = make(map[string][]string)
+		}
+		candidateSources[normalized.candidate][normalized.current] = append(
+			candidateSources[normalized.candidate][normalized.current], name,
+		)
+	}
+
+	// collapsed are names, which are different with current rules and equal with candidate rules
+	for candidate, currentNames := range candidateSources {
+		if len(currentNames) > 1 {
+			for _, current := range getSortedMapKeys(currentNames) {
+				report.collapsed[candidate] = append(report.collapsed[candidate], currentNames[current]...)
+			}
+		}
+	}
+
+	sortNormalizedNames := func(names []normalizedName) {
+		sort.Slice(names, func(i, j int) bool {
+			return names[i].name < names[j].name
+		})
+	}
+	sortNormalizedNames(report.changed)
+	sortNormalizedNames(report.empty)
+
+	return report
+}
+
+func (report NormalizePreviewReport) write(out io.Writer, limit int) {
+	_, _ = fmt.Fprintf(
+		out, "names: %d, changed: %d, empty: %d, collapsed: %d\n",
+		report.total, len(report.changed), len(report.empty), len(report.collapsed),
+	)
+
+	_, _ = fmt.Fprintf(out, "\nchanged names:\n")
+	for i, normalized := range report.changed {
+		if i == limit {
+			_, _ = fmt.Fprintf(out, "  ... %d more\n", len(report.changed)-limit)
+			break
+		}
+		_, _ = fmt.Fprintf(out, "  %q\n    - %q\n    + %q\n", normalized.name, normalized.current, normalized.candidate)
+	}
+
+	_, _ = fmt.Fprintf(out, "\nempty names:\n")
+	for i, normalized := range report.empty {
+		if i == limit {
+			_, _ = fmt.Fprintf(out, "  ... %d more\n", len(report.empty)-limit)
+			break
+		}
+		_, _ = fmt.Fprintf(out, "  %q\n", normalized.name)
+	}
+
+	_, _ = fmt.Fprintf(out, "\ncollapsed names:\n")
+	for i, candidate := range getSortedMapKeys(report.collapsed) {
+		if i == limit {
+			_, _ = fmt.Fprintf(out, "  ... %d more\n", len(report.collapsed)-limit)
+			break
+		}
+		_, _ = fmt.Fprintf(out, "  %q\n", candidate)
+		for _, name := range report.collapsed[candidate] {
+			_, _ = fmt.Fprintf(out, "    <- %q\n", name)
+		}
+	}
+}
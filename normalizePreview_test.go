@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePreview(t *testing.T) {
+	t.Run("read names", func(t *testing.T) {
+		names, err := readDisciplineNames(strings.NewReader(
+			"Фінанси, 5 сем.\r\n" +
+				"\n" +
+				`{"Id":200,"Name":"Контролінг, 6 сем., ФЕУ","Year":2030}` + "\n" +
+				"Фінанси, 5 сем.\n",
+		))
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Фінанси, 5 сем.", "Контролінг, 6 сем., ФЕУ"}, names)
+
+		_, err = readDisciplineNames(strings.NewReader("Фінанси\n{broken\n"))
+		assert.ErrorContains(t, err, "line 2:")
+	})
+
+	t.Run("scan stored names", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectScan(0, "2030:discipline:*", verifierScanCount).SetVal(
+			[]string{"2030:discipline:200", "2030:discipline:210", "2030:discipline:220"}, 0,
+		)
+		redisMock.ExpectHMGet("2030:discipline:200", "origName").SetVal([]interface{}{"Фінанси, 5 сем."})
+		redisMock.ExpectHMGet("2030:discipline:210", "origName").SetVal([]interface{}{nil})
+		redisMock.ExpectHMGet("2030:discipline:220", "origName").SetVal([]interface{}{"Контролінг, 6 сем., ФЕУ"})
+
+		names, err := scanDisciplineOrigNames(context.Background(), redisClient, "2030:discipline:*")
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, []string{"Фінанси, 5 сем.", "Контролінг, 6 сем., ФЕУ"}, names)
+	})
+
+	t.Run("report", func(t *testing.T) {
+		currentRules, _ := loadDisciplineNameRules("")
+		candidateRules, err := parseDisciplineNameRules([]byte(`
+replace:
+  - {from: 'Тренінг', to: ''}
+remove:
+  - '\s*\(.*\)'
+  - ',\s*\d\s+сем\.'
+capitalize: first
+`))
+		assert.NoError(t, err)
+
+		report := buildNormalizePreview(
+			[]string{"Фінанси (базовий)", "Фінанси, 5 сем.", "Тренінг", "Контролінг", "Контролінг (ФЕУ)"},
+			currentRules, candidateRules,
+		)
+
+		assert.Equal(t, 5, report.total)
+		assert.Equal(t, []normalizedName{{name: "Тренінг", current: "Тренінг", candidate: ""}}, report.changed)
+		assert.Equal(t, []normalizedName{{name: "Тренінг", current: "Тренінг", candidate: ""}}, report.empty)
+		assert.Empty(t, report.collapsed)
+
+		report = buildNormalizePreview(
+			[]string{"Фінанси (базовий)", "Фінанси, 5 сем.", "Фінанси", "Фінанси-2"},
+			candidateRules, currentRules,
+		)
+		assert.Equal(t, map[string][]string{
+			"Фінанси": {"Фінанси (базовий)", "Фінанси, 5 сем.", "Фінанси", "Фінанси-2"},
+		}, report.collapsed)
+		assert.Equal(t, []normalizedName{{name: "Фінанси-2", current: "Фінанси-2", candidate: "Фінанси"}}, report.changed)
+
+		out := &bytes.Buffer{}
+		report.write(out, 1)
+		assert.Equal(
+			t,
+			"names: 4, changed: 1, empty: 0, collapsed: 1\n"+
+				"\nchanged names:\n  \"Фінанси-2\"\n    - \"Фінанси-2\"\n    + \"Фінанси\"\n"+
+				"\nempty names:\n"+
+				"\ncollapsed names:\n  \"Фінанси\"\n"+
+				"    <- \"Фінанси (базовий)\"\n    <- \"Фінанси, 5 сем.\"\n    <- \"Фінанси\"\n    <- \"Фінанси-2\"\n",
+			out.String(),
+		)
+
+		out = &bytes.Buffer{}
+		buildNormalizePreview([]string{"Тренінг", "ТренінгТренінг"}, currentRules, candidateRules).write(out, 1)
+		assert.Contains(t, out.String(), "\nempty names:\n  \"Тренінг\"\n  ... 1 more\n")
+	})
+
+	t.Run("run with input file", func(t *testing.T) {
+		dir := t.TempDir()
+		_ = os.WriteFile(dir+"/rules.yaml", []byte("remove: ['\\s*\\(.*\\)']\ncapitalize: first"), 0644)
+		_ = os.WriteFile(dir+"/names.txt", []byte("фінанси (базовий)\nФінанси, 5 сем.\n"), 0644)
+
+		out := &bytes.Buffer{}
+		err := runCommand(out, []string{
+			CommandNormalizePreview, "-rules", dir + "/rules.yaml", "-current-rules", "", "-input", dir + "/names.txt",
+		})
+
+		assert.NoError(t, err)
+		assert.Contains(t, out.String(), "names: 2, changed: 1, empty: 0, collapsed: 0\n")
+		assert.Contains(t, out.String(), "\"Фінанси, 5 сем.\"\n    - \"Фінанси\"\n    + \"Фінанси, 5 сем.\"\n")
+	})
+
+	t.Run("parse options", func(t *testing.T) {
+		_, err := parseNormalizePreviewOptions([]string{}, &bytes.Buffer{})
+		assert.EqualError(t, err, "-rules is required")
+
+		options, err := parseNormalizePreviewOptions([]string{"-rules", "rules.yaml", "-year", "2030"}, &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Equal(t, "rules.yaml", options.rulesFile)
+		assert.Equal(t, 2030, options.year)
+		assert.Equal(t, DefaultNormalizePreviewLimit, options.limit)
+	})
+}
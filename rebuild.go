@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/kneu-messenger-pigeon/events"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"log/slog"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const DefaultRebuildIdleTimeout = time.Second * 30
+
+const DefaultRebuildProgressInterval = time.Second * 10
+
+type RebuildOptions struct {
+	since            time.Time
+	groupId          string
+	notify           bool
+	idleTimeout      time.Duration
+	progressInterval time.Duration
+}
+
+// runRebuild
+/*
+ * Repopulates Redis by replaying topology topics from the beginning with a separate throwaway consumer group.
+ * Messages are written through the usual connectors and writers; messages older than -since are fetched but skipped.
+ * Topic is finished when its readers get no messages during idle timeout.
+ * Scores changes feed is suppressed unless -notify is given, so students are not notified again.
+ */
+func runRebuild(out io.Writer, args []string) error {
+	options, err := parseRebuildOptions(args, out)
+	if err != nil {
+		return err
+	}
+
+	config, opt, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+
+	logger := newLogger(out, config.logLevel, config.logFormat)
+	redisClient := redis.NewClient(opt)
+	defer redisClient.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	lessonExistChecker := newLessonExistChecker(logger, redisClient)
+	suppressedFeedWriter := &SuppressedScoresChangesFeedWriter{}
+	var scoresChangesFeedWriter ScoresChangesFeedWriterInterface = suppressedFeedWriter
+	var lessonWrittenNotifier LessonWrittenNotifierInterface = suppressedFeedWriter
+	if options.notify {
+		feedWriter := newRebuildScoresChangesFeedWriter(logger, config, lessonExistChecker)
+		scoresChangesFeedWriter, lessonWrittenNotifier = feedWriter, feedWriter
+	}
+
+	// messages failed on live service are already in dead-letter topic
+	connectorsFactory := newConnectorsFactory(
+		logger, redisClient, scoresChangesFeedWriter, lessonExistChecker, lessonWrittenNotifier, nil, true,
+	)
+
+	logger.Info(
+		"rebuild started",
+		"groupId", options.groupId, "since", options.since, "notify", options.notify, "idleTimeout", options.idleTimeout,
+	)
+	startedAt := time.Now()
+
+	feedWriterCtx, feedWriterStop := context.WithCancel(context.Background())
+	defer feedWriterStop()
+	feedWriterDone := make(chan struct{})
+	go func() {
+		scoresChangesFeedWriter.execute(feedWriterCtx)
+		close(feedWriterDone)
+	}()
+
+	var progresses []*RebuildTopicProgress
+	wg := &sync.WaitGroup{}
+	for _, entry := range config.topology {
+		entry.GroupId = options.groupId
+		progress := newRebuildTopicProgress(entry.Topic, entry.Readers)
+		progresses = append(progresses, progress)
+
+		for i := 0; i < entry.Readers; i++ {
+			kafkaReader := newTopologyKafkaReader(config, entry)
+			defer kafkaReader.Close()
+
+			connectorCtx, connectorCancel := context.WithCancel(ctx)
+			defer connectorCancel()
+			reader := &rebuildReader{
+				ReaderInterface: kafkaReader,
+				since:           options.since,
+				idleTimeout:     options.idleTimeout,
+				progress:        progress,
+				finish:          connectorCancel,
+			}
+
+			wg.Add(1)
+			go connectorsFactory.newConnector(entry, reader, nil, nil).execute(connectorCtx, wg)
+		}
+	}
+
+	connectorsDone := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(connectorsDone)
+	}()
+
+	ticker := time.NewTicker(options.progressInterval)
+	for isRunning := true; isRunning; {
+		select {
+		case <-ticker.C:
+			for _, progress := range progresses {
+				progress.log(logger, "rebuild progress")
+			}
+		case <-connectorsDone:
+			isRunning = false
+		}
+	}
+	ticker.Stop()
+
+	feedWriterStop()
+	<-feedWriterDone
+
+	for _, progress := range progresses {
+		progress.log(logger, "rebuild topic finished")
+	}
+	err = redisClient.BgSave(context.Background()).Err()
+	if err != nil && err.Error() == RedisBackgroundSaveInProgress {
+		err = nil
+	}
+	if err == nil && ctx.Err() != nil {
+		err = errors.New("rebuild interrupted")
+	}
+
+	logResult(
+		logger, "rebuild finished", err,
+		"duration", time.Since(startedAt).Round(time.Second), "suppressedScoresChanges", suppressedFeedWriter.count.Load(),
+	)
+
+	return err
+}
+
+func parseRebuildOptions(args []string, out io.Writer) (RebuildOptions, error) {
+	var since string
+	options := RebuildOptions{}
+
+	flagSet := flag.NewFlagSet(CommandRebuild, flag.ContinueOnError)
+	flagSet.SetOutput(out)
+	flagSet.StringVar(&since, "since", "", "skip messages older than timestamp (RFC 3339 or YYYY-MM-DD)")
+	flagSet.StringVar(
+		&options.groupId, "group", fmt.Sprintf("%s-rebuild-%d", DefaultTopologyGroupId, time.Now().Unix()),
+		"throwaway consumer group id",
+	)
+	flagSet.BoolVar(&options.notify, "notify", false, "write score changes into scores changes feed")
+	flagSet.DurationVar(&options.idleTimeout, "idle-timeout", DefaultRebuildIdleTimeout, "topic is finished after no messages during")
+	flagSet.DurationVar(&options.progressInterval, "progress-interval", DefaultRebuildProgressInterval, "interval of progress reports")
+
+	err := flagSet.Parse(args)
+	if err != nil {
+		return options, err
+	}
+
+	if since != "" {
+		options.since, err = time.Parse(time.RFC3339, since)
+		if err != nil {
+			options.since, err = time.ParseInLocation(time.DateOnly, since, time.Local)
+		}
+		if err != nil {
+			return options, fmt.Errorf("invalid -since %q: expected RFC 3339 timestamp or YYYY-MM-DD date", since)
+		}
+	}
+
+	if options.groupId == "" || options.groupId == DefaultTopologyGroupId {
+		return options, errors.New("rebuild requires separate consumer group id")
+	}
+	if options.idleTimeout <= 0 || options.progressInterval <= 0 {
+		return options, errors.New("-idle-timeout and -progress-interval should be positive")
+	}
+
+	return options, nil
+}
+
+// rebuildReader
+/*
+ * Wraps Kafka reader of rebuild connector: skips messages older than since, counts progress
+ * and finishes connector (cancels its context) when no messages are fetched during idle timeout.
+ */
+type rebuildReader struct {
+	events.ReaderInterface
+	since       time.Time
+	idleTimeout time.Duration
+	progress    *RebuildTopicProgress
+	finish      context.CancelFunc
+	isFinished  bool
+}
+
+func (reader *rebuildReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		idleCtx, idleCancel := context.WithTimeout(ctx, reader.idleTimeout)
+		message, err := reader.ReaderInterface.FetchMessage(idleCtx)
+		isIdle := err != nil && ctx.Err() == nil && errors.Is(idleCtx.Err(), context.DeadlineExceeded)
+		idleCancel()
+
+		if isIdle {
+			if !reader.isFinished {
+				reader.isFinished = true
+				reader.progress.readerFinished()
+			}
+			reader.finish()
+			return kafka.Message{}, context.Canceled
+		}
+		if err != nil {
+			return message, err
+		}
+
+		isSkipped := message.Time.Before(reader.since)
+		reader.progress.fetched(message, isSkipped)
+		if !isSkipped {
+			return message, nil
+		}
+	}
+}
+
+type RebuildTopicProgress struct {
+	topic           string
+	readers         int
+	mutex           sync.Mutex
+	fetchedCount    int
+	skippedCount    int
+	finishedReaders int
+	remaining       map[int]int64
+}
+
+func newRebuildTopicProgress(topic string, readers int) *RebuildTopicProgress {
+	return &RebuildTopicProgress{
+		topic:     topic,
+		readers:   readers,
+		remaining: make(map[int]int64),
+	}
+}
+
+func (progress *RebuildTopicProgress) fetched(message kafka.Message, isSkipped bool) {
+	progress.mutex.Lock()
+	progress.fetchedCount++
+	if isSkipped {
+		progress.skippedCount++
+	}
+	if message.HighWaterMark > 0 {
+		progress.remaining[message.Partition] = max(message.HighWaterMark-message.Offset-1, 0)
+	}
+	progress.mutex.Unlock()
+}
+
+func (progress *RebuildTopicProgress) readerFinished() {
+	progress.mutex.Lock()
+	progress.finishedReaders++
+	progress.mutex.Unlock()
+}
+
+func (progress *RebuildTopicProgress) log(logger *slog.Logger, msg string) {
+	progress.mutex.Lock()
+	defer progress.mutex.Unlock()
+
+	remaining := int64(0)
+	for _, partitionRemaining := range progress.remaining {
+		remaining += partitionRemaining
+	}
+
+	logger.Info(
+		msg,
+		"topic", progress.topic, "fetched", progress.fetchedCount, "skipped", progress.skippedCount,
+		"remaining", remaining, "finished", progress.finishedReaders == progress.readers,
+	)
+}
+
+// newRebuildScoresChangesFeedWriter
+/*
+ * Returns scores changes feed writer without pending changes storage: storage key is shared with running service,
+ * so rebuild would send again pending changes of the service on start and both processes would remove
+ * changes of each other. Changes pending on rebuild crash are lost.
+ */
+func newRebuildScoresChangesFeedWriter(
+	logger *slog.Logger, config Config, lessonExistChecker LessonExistCheckerInterface,
+) *ScoresChangesFeedWriter {
+	return NewScoresChangesFeedWriter(
+		logger,
+		&kafka.Writer{
+			Addr:     kafka.TCP(config.kafkaHost),
+			Topic:    events.ScoresChangesFeedTopic,
+			Balancer: &kafka.Murmur2Balancer{},
+		},
+		lessonExistChecker,
+		nil,
+	)
+}
+
+// SuppressedScoresChangesFeedWriter counts score changes instead of writing them into scores changes feed
+type SuppressedScoresChangesFeedWriter struct {
+	count atomic.Int64
+}
+
+func (writer *SuppressedScoresChangesFeedWriter) execute(ctx context.Context) {
+	<-ctx.Done()
+}
+
+func (writer *SuppressedScoresChangesFeedWriter) addToQueue(events.ScoreEvent, events.ScoreValue) {
+	writer.count.Add(1)
+}
+
+func (writer *SuppressedScoresChangesFeedWriter) addBatchToQueue(changedEvents []events.ScoreChangedEvent) {
+	writer.count.Add(int64(len(changedEvents)))
+}
+
+func (writer *SuppressedScoresChangesFeedWriter) notifyLessonWritten(lessonIdentifier) {}
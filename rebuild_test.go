@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/kneu-messenger-pigeon/events"
+	"github.com/kneu-messenger-pigeon/events/mocks"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRebuildOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		options, err := parseRebuildOptions([]string{}, &bytes.Buffer{})
+
+		assert.NoError(t, err)
+		assert.True(t, options.since.IsZero())
+		assert.True(t, strings.HasPrefix(options.groupId, DefaultTopologyGroupId+"-rebuild-"))
+		assert.False(t, options.notify)
+		assert.Equal(t, DefaultRebuildIdleTimeout, options.idleTimeout)
+		assert.Equal(t, DefaultRebuildProgressInterval, options.progressInterval)
+	})
+
+	t.Run("all options", func(t *testing.T) {
+		options, err := parseRebuildOptions([]string{
+			"-since", "2023-09-01T10:00:00Z", "-group", "storage-writer-restore", "-notify",
+			"-idle-timeout", "1m", "-progress-interval", "5s",
+		}, &bytes.Buffer{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC), options.since.UTC())
+		assert.Equal(t, "storage-writer-restore", options.groupId)
+		assert.True(t, options.notify)
+		assert.Equal(t, time.Minute, options.idleTimeout)
+		assert.Equal(t, time.Second*5, options.progressInterval)
+	})
+
+	t.Run("since date", func(t *testing.T) {
+		options, err := parseRebuildOptions([]string{"-since", "2023-09-01"}, &bytes.Buffer{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 9, 1, 0, 0, 0, 0, time.Local), options.since)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := parseRebuildOptions([]string{"-since", "yesterday"}, &bytes.Buffer{})
+		assert.ErrorContains(t, err, `invalid -since "yesterday"`)
+
+		_, err = parseRebuildOptions([]string{"-group", DefaultTopologyGroupId}, &bytes.Buffer{})
+		assert.EqualError(t, err, "rebuild requires separate consumer group id")
+
+		_, err = parseRebuildOptions([]string{"-idle-timeout", "0s"}, &bytes.Buffer{})
+		assert.Error(t, err)
+
+		out := &bytes.Buffer{}
+		_, err = parseRebuildOptions([]string{"-unknown"}, out)
+		assert.Error(t, err)
+		assert.Contains(t, out.String(), "flag provided but not defined: -unknown")
+	})
+}
+
+func TestRebuildReader(t *testing.T) {
+	matchContext := mock.MatchedBy(func(ctx context.Context) bool { return true })
+	waitForContext := func(ctx context.Context) (kafka.Message, error) {
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+
+	t.Run("skip old messages and finish when idle", func(t *testing.T) {
+		since := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+		oldMessage := kafka.Message{Partition: 0, Offset: 1, HighWaterMark: 10, Time: since.Add(-time.Hour)}
+		newMessage := kafka.Message{Partition: 0, Offset: 2, HighWaterMark: 10, Time: since.Add(time.Hour)}
+
+		kafkaReader := mocks.NewReaderInterface(t)
+		kafkaReader.On("FetchMessage", matchContext).Once().Return(oldMessage, nil)
+		kafkaReader.On("FetchMessage", matchContext).Once().Return(newMessage, nil)
+		kafkaReader.On("FetchMessage", matchContext).Once().Return(waitForContext)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		progress := newRebuildTopicProgress(events.RawScoresTopic, 1)
+		reader := &rebuildReader{
+			ReaderInterface: kafkaReader,
+			since:           since,
+			idleTimeout:     time.Millisecond * 20,
+			progress:        progress,
+			finish:          cancel,
+		}
+
+		message, err := reader.FetchMessage(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, newMessage, message)
+
+		_, err = reader.FetchMessage(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+		out := &bytes.Buffer{}
+		progress.log(newTestLogger(out), "rebuild topic finished")
+		assert.Contains(
+			t, out.String(),
+			`msg="rebuild topic finished" topic=raw-scores fetched=2 skipped=1 remaining=7 finished=true`,
+		)
+	})
+
+	t.Run("parent context is not idle", func(t *testing.T) {
+		kafkaReader := mocks.NewReaderInterface(t)
+		kafkaReader.On("FetchMessage", matchContext).Once().Return(waitForContext)
+
+		finished := false
+		reader := &rebuildReader{
+			ReaderInterface: kafkaReader,
+			idleTimeout:     time.Minute,
+			progress:        newRebuildTopicProgress(events.RawScoresTopic, 1),
+			finish:          func() { finished = true },
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+
+		_, err := reader.FetchMessage(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.False(t, finished)
+	})
+
+	t.Run("fetch error", func(t *testing.T) {
+		expectedError := errors.New("expected error")
+
+		kafkaReader := mocks.NewReaderInterface(t)
+		kafkaReader.On("FetchMessage", matchContext).Once().Return(kafka.Message{}, expectedError)
+
+		reader := &rebuildReader{
+			ReaderInterface: kafkaReader,
+			idleTimeout:     time.Minute,
+			progress:        newRebuildTopicProgress(events.RawScoresTopic, 1),
+			finish:          func() {},
+		}
+
+		_, err := reader.FetchMessage(context.Background())
+		assert.Equal(t, expectedError, err)
+	})
+}
+
+func TestSuppressedScoresChangesFeedWriter(t *testing.T) {
+	writer := &SuppressedScoresChangesFeedWriter{}
+	writer.addToQueue(events.ScoreEvent{Id: 1}, events.ScoreValue{})
+	writer.addToQueue(events.ScoreEvent{Id: 2}, events.ScoreValue{})
+	writer.notifyLessonWritten(lessonIdentifier{LessonId: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	writer.execute(ctx)
+
+	assert.Equal(t, int64(2), writer.count.Load())
+}
+
+func TestNewRebuildScoresChangesFeedWriter(t *testing.T) {
+	lessonExistChecker := NewMockLessonExistCheckerInterface(t)
+	lessonExistChecker.On("Exists", 2028, uint8(1), uint(234), uint(150)).Return(true)
+
+	feedWriter := newRebuildScoresChangesFeedWriter(newTestLogger(&bytes.Buffer{}), expectedConfig, lessonExistChecker)
+
+	// pending changes of the running service are not restored and not removed
+	assert.Nil(t, feedWriter.storage)
+	assert.Equal(t, events.ScoresChangesFeedTopic, feedWriter.writer.(*kafka.Writer).Topic)
+
+	feedWriter.restore()
+	feedWriter.addToQueue(events.ScoreEvent{LessonId: 150, DisciplineId: 234, Year: 2028, Semester: 1}, events.ScoreValue{})
+	assert.Len(t, feedWriter.readyQueue.queue, 1)
+	assert.Empty(t, feedWriter.waitingQueue.queue)
+}
+
+func TestRunRebuild(t *testing.T) {
+	t.Run("invalid options", func(t *testing.T) {
+		err := runCommand(&bytes.Buffer{}, []string{CommandRebuild, "-since", "yesterday"})
+		assert.ErrorContains(t, err, "invalid -since")
+	})
+
+	t.Run("wrong redis driver", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("REDIS_DSN", "//")
+		defer os.Unsetenv("REDIS_DSN")
+
+		err := runCommand(&bytes.Buffer{}, []string{CommandRebuild})
+		assert.EqualError(t, err, "redis: invalid URL scheme: ")
+	})
+}
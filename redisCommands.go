@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisReadOnlyCommands are commands, which only read Redis data
+var redisReadOnlyCommands = map[string]bool{
+	"get": true, "mget": true, "strlen": true, "getrange": true,
+	"exists": true, "type": true, "ttl": true, "pttl": true, "scan": true, "keys": true, "randomkey": true,
+	"hget": true, "hmget": true, "hgetall": true, "hexists": true, "hkeys": true, "hvals": true, "hlen": true,
+	"hstrlen": true, "hscan": true,
+	"smembers": true, "sismember": true, "smismember": true, "scard": true, "srandmember": true,
+	"sinter": true, "sunion": true, "sdiff": true, "sscan": true,
+	"zrange": true, "zrangebyscore": true, "zrevrange": true, "zrevrangebyscore": true, "zrangebylex": true,
+	"zrank": true, "zrevrank": true, "zscore": true, "zmscore": true, "zcard": true, "zcount": true, "zscan": true,
+	"lrange": true, "llen": true, "lindex": true,
+}
+
+// redisControlCommands are connection, transaction and server info commands, which don't change Redis data
+var redisControlCommands = map[string]bool{
+	"ping": true, "echo": true, "hello": true, "auth": true, "select": true, "client": true,
+	"multi": true, "exec": true, "discard": true, "watch": true, "unwatch": true,
+	"info": true, "lastsave": true, "dbsize": true, "time": true, "command": true,
+}
+
+// isRedisMutationCommand
+/*
+ * Returns true for every command, which is neither read-only nor control command, so new or unknown command
+ * is treated as mutation by dry-run and shadow hooks instead of being executed on (or missed for) shadow.
+ */
+func isRedisMutationCommand(name string) bool {
+	return !redisReadOnlyCommands[name] && !redisControlCommands[name]
+}
+
+// redisScriptSources contains sources of scripts called by writers, by SHA1
+var redisScriptSources = map[string]string{
+	getScriptHash(writeScoreScriptSource):                  writeScoreScriptSource,
+	getScriptHash(repairStudentTotalScriptSource):          repairStudentTotalScriptSource,
+	getScriptHash(repairStudentDisciplinesScriptSource):    repairStudentDisciplinesScriptSource,
+	getScriptHash(repairLessonStudentsScriptSource):        repairLessonStudentsScriptSource,
+	getScriptHash(deleteLessonScoresScriptSource):          deleteLessonScoresScriptSource,
+	getScriptHash(renormalizeDisciplineNameScriptSource):   renormalizeDisciplineNameScriptSource,
+	getScriptHash(writeDisciplineNameScriptSource):         writeDisciplineNameScriptSource,
+	getScriptHash(updateDisciplineSearchIndexScriptSource): updateDisciplineSearchIndexScriptSource,
+}
+
+// getCmdScriptHash returns SHA1 of script called by EVAL or EVALSHA command
+func getCmdScriptHash(cmd redis.Cmder) string {
+	script := fmt.Sprint(cmd.Args()[1])
+	if cmd.Name() == "eval" {
+		return getScriptHash(script)
+	}
+
+	return script
+}
+
+func getScriptHash(source string) string {
+	hash := sha1.Sum([]byte(source))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"os/signal"
+	"syscall"
+)
+
+// runRenormalize
+/*
+ * Re-applies current discipline name rules (DISCIPLINE_NAME_RULES_FILE or embedded default rules)
+ * to stored disciplines with outdated rules version once and exits.
+ */
+func runRenormalize(out io.Writer, args []string) error {
+	year, err := parseRenormalizeOptions(args, out)
+	if err != nil {
+		return err
+	}
+
+	config, opt, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	redisClient := redis.NewClient(opt)
+	defer redisClient.Close()
+
+	renormalizer := newDisciplineNamesRenormalizer(newLogger(out, config.logLevel, config.logFormat), redisClient)
+	renormalizer.year = year
+	_, err = renormalizer.renormalize(ctx)
+
+	return err
+}
+
+func parseRenormalizeOptions(args []string, out io.Writer) (year int, err error) {
+	flagSet := flag.NewFlagSet(CommandRenormalize, flag.ContinueOnError)
+	flagSet.SetOutput(out)
+	flagSet.IntVar(&year, "year", 0, "renormalize disciplines of education year only")
+
+	err = flagSet.Parse(args)
+	if err == nil && flagSet.NArg() != 0 {
+		err = fmt.Errorf("unexpected arguments: %v", flagSet.Args())
+	}
+
+	return year, err
+}
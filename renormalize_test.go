@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestParseRenormalizeOptions(t *testing.T) {
+	year, err := parseRenormalizeOptions([]string{}, &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, year)
+
+	year, err = parseRenormalizeOptions([]string{"-year", "2030"}, &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2030, year)
+
+	_, err = parseRenormalizeOptions([]string{"2030"}, &bytes.Buffer{})
+	assert.EqualError(t, err, "unexpected arguments: [2030]")
+
+	_, err = parseRenormalizeOptions([]string{"-year", "last"}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, `invalid value "last" for flag -year`)
+}
+
+func TestRunRenormalize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalid options", func(t *testing.T) {
+		err := runCommand(&bytes.Buffer{}, []string{CommandRenormalize, "2030"})
+		assert.EqualError(t, err, "unexpected arguments: [2030]")
+	})
+
+	t.Run("wrong redis driver", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("REDIS_DSN", "//")
+		defer os.Unsetenv("REDIS_DSN")
+
+		err := runCommand(&bytes.Buffer{}, []string{CommandRenormalize})
+		assert.EqualError(t, err, "redis: invalid URL scheme: ")
+	})
+
+	t.Run("renormalize year", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, "2030:discipline:200", "origName", "Фінанси, 5 сем.", "name", "Фінанси, 5 сем.")
+		redisClient.HSet(ctx, "2031:discipline:200", "origName", "Фінанси, 5 сем.", "name", "Фінанси, 5 сем.")
+
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("REDIS_DSN", "redis://"+server.Addr())
+		defer os.Unsetenv("REDIS_DSN")
+
+		err := runCommand(&bytes.Buffer{}, []string{CommandRenormalize, "-year", "2030"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Фінанси", redisClient.HGet(ctx, "2030:discipline:200", "name").Val())
+		assert.Equal(t, []string{"200"}, redisClient.SMembers(ctx, "2030:discipline_search:фінанси").Val())
+		assert.Equal(t, "Фінанси, 5 сем.", redisClient.HGet(ctx, "2031:discipline:200", "name").Val())
+	})
+}
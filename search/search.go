@@ -0,0 +1,99 @@
+// Package search queries discipline name search index, maintained by storage-writer in Redis.
+//
+// Index is a set of discipline ids per token: "{year}:discipline_search:{token}". Tokens are lowercase words
+// of discipline name without apostrophes and all their prefixes, so "маркет" and "Маркетинг" find
+// "Маркетинг в агробізнесі", and "компютерна" finds "Компʼютерна математика".
+package search
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MinTokenLength is min count of characters in indexed token; shorter words of query are ignored
+const MinTokenLength = 2
+
+var apostrophes = strings.NewReplacer("ʼ", "", "'", "", "’", "", "`", "")
+
+// GetIndexKeyPrefix returns prefix of search index keys of education year
+func GetIndexKeyPrefix(year int) string {
+	return fmt.Sprintf("%d:discipline_search:", year)
+}
+
+// Tokenize returns unique lowercase words of text without apostrophes, shorter than MinTokenLength are skipped
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(
+		strings.ToLower(apostrophes.Replace(text)),
+		func(char rune) bool {
+			return !unicode.IsLetter(char) && !unicode.IsDigit(char)
+		},
+	)
+
+	var tokens []string
+	uniqueTokens := make(map[string]bool, len(words))
+	for _, word := range words {
+		if utf8.RuneCountInString(word) >= MinTokenLength && !uniqueTokens[word] {
+			uniqueTokens[word] = true
+			tokens = append(tokens, word)
+		}
+	}
+
+	return tokens
+}
+
+// GetIndexTokens returns unique tokens of discipline name with all their prefixes of at least MinTokenLength
+func GetIndexTokens(name string) []string {
+	var tokens []string
+	uniqueTokens := make(map[string]bool)
+	for _, word := range Tokenize(name) {
+		runes := []rune(word)
+		for length := MinTokenLength; length <= len(runes); length++ {
+			prefix := string(runes[:length])
+			if !uniqueTokens[prefix] {
+				uniqueTokens[prefix] = true
+				tokens = append(tokens, prefix)
+			}
+		}
+	}
+
+	return tokens
+}
+
+// Search returns sorted ids of disciplines of education year, which names contain all words of query
+// as words or word beginnings. Empty result is returned for query without tokens.
+func Search(ctx context.Context, client redis.UniversalClient, year int, query string) ([]uint, error) {
+	tokens := Tokenize(query)
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	keyPrefix := GetIndexKeyPrefix(year)
+	keys := make([]string, len(tokens))
+	for i, token := range tokens {
+		keys[i] = keyPrefix + token
+	}
+
+	members, err := client.SInter(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member, 10, 0)
+		if err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	return ids, nil
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"context"
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"компютерна", "математика", "іт", "бізнес", "1с"},
+		Tokenize("Компʼютерна МАТЕМАТИКА в ІТ-бізнес; комп'ютерна 1С"),
+	)
+	assert.Empty(t, Tokenize(" - в, з "))
+}
+
+func TestGetIndexTokens(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"фі", "фін", "фіна", "фінан", "фінанс", "фінанси", "іт"},
+		GetIndexTokens("Фінанси в ІТ, фінанси"),
+	)
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("found", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectSInter("2030:discipline_search:маркет", "2030:discipline_search:агро").SetVal(
+			[]string{"210", "200", "invalid"},
+		)
+
+		ids, err := Search(ctx, redisClient, 2030, "Маркет агро")
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Equal(t, []uint{200, 210}, ids)
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+
+		ids, err := Search(ctx, redisClient, 2030, " в ")
+
+		assert.NoError(t, err)
+		assert.NoError(t, redisMock.ExpectationsWereMet())
+		assert.Empty(t, ids)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		redisClient, redisMock := redismock.NewClientMock()
+		redisMock.ExpectSInter("2030:discipline_search:маркет").SetErr(assert.AnError)
+
+		_, err := Search(ctx, redisClient, 2030, "маркет")
+
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
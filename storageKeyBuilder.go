@@ -13,3 +13,22 @@ func getLessonKey(lessonId uint) string {
 func getDeletedLessonKey(year int, semester uint8, disciplineId uint, lessonId uint) string {
 	return fmt.Sprintf("%d:%d:deleted-lessons:%d:%d", year, semester, disciplineId, lessonId)
 }
+
+// getLessonStudentsKey returns key of set with "{student}:{lesson part}" of students having lesson score
+func getLessonStudentsKey(year int, semester uint8, disciplineId uint, lessonId uint) string {
+	return fmt.Sprintf("%d:%d:lesson_students:%d:%d", year, semester, disciplineId, lessonId)
+}
+
+// getDisciplineSemesterUpdatedAtKey returns key of "{semester}{unix timestamp}" of the last discipline score change
+func getDisciplineSemesterUpdatedAtKey(year int, disciplineId uint) string {
+	return fmt.Sprintf("%d:discipline_semester_updated_at:%d", year, disciplineId)
+}
+
+func getDisciplineInfoKey(year int, disciplineId uint) string {
+	return fmt.Sprintf("%d:discipline:%d", year, disciplineId)
+}
+
+// getDisciplineNameOverridesKey returns key of hash with manually set discipline names by discipline id
+func getDisciplineNameOverridesKey(year int) string {
+	return fmt.Sprintf("%d:discipline_name_overrides", year)
+}
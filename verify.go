@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"os/signal"
+	"syscall"
+)
+
+const (
+	VerifyCheckAll                = "all"
+	VerifyCheckTotals             = "totals"
+	VerifyCheckStudentDisciplines = "student-disciplines"
+	VerifyCheckLessonStudents     = "lesson-students"
+)
+
+// runVerify
+/*
+ * Verifies discipline totals, students disciplines sets and lesson students index against students scores
+ * once and exits. Returns error when mismatches are found and not repaired.
+ * Lesson students index must be built once after upgrade with "verify -check lesson-students -repair",
+ * as scores written by previous versions are not indexed and would not be deleted with their lesson.
+ */
+func runVerify(out io.Writer, args []string) error {
+	options, err := parseVerifyOptions(args, out)
+	if err != nil {
+		return err
+	}
+
+	config, opt, err := loadAppConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
+
+	redisClient := redis.NewClient(opt)
+	defer redisClient.Close()
+
+	logger := newLogger(out, config.logLevel, config.logFormat)
+
+	var errs []error
+	if options.check == VerifyCheckAll || options.check == VerifyCheckTotals {
+		verifier := &TotalsVerifier{logger: logger, redis: redisClient, options: options}
+		result, err := verifier.verify(ctx)
+		if err == nil && result.mismatches != result.repaired {
+			err = fmt.Errorf("found %d totals mismatches", result.mismatches-result.repaired)
+		}
+		errs = append(errs, err)
+	}
+
+	if ctx.Err() == nil && (options.check == VerifyCheckAll || options.check == VerifyCheckStudentDisciplines) {
+		verifier := &StudentDisciplinesVerifier{logger: logger, redis: redisClient, options: options}
+		result, err := verifier.verify(ctx)
+		if err == nil && result.mismatches != result.repaired {
+			err = fmt.Errorf("found %d student disciplines mismatches", result.mismatches-result.repaired)
+		}
+		errs = append(errs, err)
+	}
+
+	if ctx.Err() == nil && (options.check == VerifyCheckAll || options.check == VerifyCheckLessonStudents) {
+		verifier := &LessonStudentsVerifier{logger: logger, redis: redisClient, options: options}
+		result, err := verifier.verify(ctx)
+		if err == nil && result.mismatches != result.repaired {
+			err = fmt.Errorf("found %d lesson students mismatches", result.mismatches-result.repaired)
+		}
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+func parseVerifyOptions(args []string, out io.Writer) (VerifyOptions, error) {
+	options := VerifyOptions{}
+
+	flagSet := flag.NewFlagSet(CommandVerify, flag.ContinueOnError)
+	flagSet.SetOutput(out)
+	flagSet.IntVar(&options.year, "year", 0, "verify education year only")
+	flagSet.IntVar(&options.semester, "semester", 0, "verify semester only")
+	flagSet.BoolVar(&options.repair, "repair", false, "repair found mismatches")
+	flagSet.IntVar(&options.maxReported, "max-report", DefaultVerifyMaxReported, "max count of logged mismatches")
+	flagSet.StringVar(
+		&options.check, "check", VerifyCheckAll,
+		"verified data: "+VerifyCheckAll+", "+VerifyCheckTotals+", "+VerifyCheckStudentDisciplines+
+			" or "+VerifyCheckLessonStudents,
+	)
+
+	err := flagSet.Parse(args)
+	if err == nil && (options.semester < 0 || options.semester > 2) {
+		err = errors.New("-semester should be 1 or 2")
+	}
+	if err == nil && options.check != VerifyCheckAll && options.check != VerifyCheckTotals &&
+		options.check != VerifyCheckStudentDisciplines && options.check != VerifyCheckLessonStudents {
+		err = fmt.Errorf("unknown check %q", options.check)
+	}
+	if err == nil && flagSet.NArg() != 0 {
+		err = fmt.Errorf("unexpected arguments: %v", flagSet.Args())
+	}
+
+	return options, err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestParseVerifyOptions(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		options, err := parseVerifyOptions([]string{}, &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Equal(t, VerifyOptions{maxReported: DefaultVerifyMaxReported, check: VerifyCheckAll}, options)
+	})
+
+	t.Run("all options", func(t *testing.T) {
+		options, err := parseVerifyOptions(
+			[]string{"-year", "2030", "-semester", "2", "-repair", "-max-report", "5", "-check", VerifyCheckTotals},
+			&bytes.Buffer{},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, VerifyOptions{
+			year: 2030, semester: 2, repair: true, maxReported: 5, check: VerifyCheckTotals,
+		}, options)
+
+		options, err = parseVerifyOptions([]string{"-check", VerifyCheckStudentDisciplines}, &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Equal(t, VerifyCheckStudentDisciplines, options.check)
+
+		options, err = parseVerifyOptions([]string{"-check", VerifyCheckLessonStudents}, &bytes.Buffer{})
+		assert.NoError(t, err)
+		assert.Equal(t, VerifyCheckLessonStudents, options.check)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		invalidArgs := map[string][]string{
+			`unknown check "lessons"`:             {"-check", "lessons"},
+			"-semester should be 1 or 2":          {"-semester", "3"},
+			"unexpected arguments: [extra]":       {"-year", "2030", "extra"},
+			`invalid value "last" for flag -year`: {"-year", "last"},
+		}
+		for expectedError, args := range invalidArgs {
+			_, err := parseVerifyOptions(args, &bytes.Buffer{})
+			assert.ErrorContains(t, err, expectedError)
+		}
+	})
+}
+
+func TestRunVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("invalid options", func(t *testing.T) {
+		err := runCommand(&bytes.Buffer{}, []string{CommandVerify, "-check", "lessons"})
+		assert.EqualError(t, err, `unknown check "lessons"`)
+	})
+
+	t.Run("wrong redis driver", func(t *testing.T) {
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("REDIS_DSN", "//")
+		defer os.Unsetenv("REDIS_DSN")
+
+		err := runCommand(&bytes.Buffer{}, []string{CommandVerify})
+		assert.EqualError(t, err, "redis: invalid URL scheme: ")
+	})
+
+	t.Run("find and repair mismatches", func(t *testing.T) {
+		server, redisClient := newMiniRedisClient(t)
+		redisClient.HSet(ctx, "2030:1:scores:5:15", "150:1", "2.5", "151:1", "1")
+		redisClient.HSet(ctx, "2030:1:scores:6:15", "150:1", "-999999")
+		redisClient.ZAdd(ctx, "2030:1:totals:15", redis.Z{Score: 2.5, Member: "5"})
+		redisClient.SAdd(ctx, "2030:1:student_disciplines:5", "15", "16")
+
+		_ = os.Setenv("KAFKA_HOST", expectedConfig.kafkaHost)
+		_ = os.Setenv("REDIS_DSN", "redis://"+server.Addr())
+		defer os.Unsetenv("REDIS_DSN")
+
+		out := &bytes.Buffer{}
+		err := runCommand(out, []string{CommandVerify, "-year", "2030"})
+		assert.EqualError(
+			t, err,
+			"found 1 totals mismatches\nfound 2 student disciplines mismatches\nfound 2 lesson students mismatches",
+		)
+		assert.Equal(t, []string{"15", "16"}, redisClient.SMembers(ctx, "2030:1:student_disciplines:5").Val())
+
+		err = runCommand(out, []string{CommandVerify, "-check", VerifyCheckTotals, "-repair"})
+		assert.NoError(t, err)
+		assert.Equal(t, float64(3.5), redisClient.ZScore(ctx, "2030:1:totals:15", "5").Val())
+
+		err = runCommand(out, []string{CommandVerify, "-check", VerifyCheckStudentDisciplines, "-repair"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"15"}, redisClient.SMembers(ctx, "2030:1:student_disciplines:5").Val())
+		assert.Equal(t, []string{"15"}, redisClient.SMembers(ctx, "2030:1:student_disciplines:6").Val())
+
+		err = runCommand(out, []string{CommandVerify, "-check", VerifyCheckLessonStudents, "-repair"})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"5:1", "6:1"}, redisClient.SMembers(ctx, "2030:1:lesson_students:15:150").Val())
+		assert.Equal(t, []string{"5:1"}, redisClient.SMembers(ctx, "2030:1:lesson_students:15:151").Val())
+
+		err = runCommand(out, []string{CommandVerify})
+		assert.NoError(t, err)
+	})
+}